@@ -1,8 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -11,6 +14,31 @@ type Config struct {
 	Server        ServerConfig
 	DatabaseMysql DatabaseConfig
 	JWT           JWTConfig
+	Storage       StorageConfig
+	Inventory     InventoryConfig
+}
+
+type InventoryConfig struct {
+	// ValuationMethod is "fifo" or "average" (moving weighted average).
+	ValuationMethod string
+	// AdjustApprovalThreshold is the value, in minor units, above which a
+	// stock adjustment by staff needs a manager's approval.
+	AdjustApprovalThreshold int64
+}
+
+type StorageConfig struct {
+	Driver    string
+	LocalDir  string
+	PublicURL string
+	S3        S3Config
+}
+
+type S3Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
 }
 
 type JWTConfig struct {
@@ -37,7 +65,7 @@ func Load() (*Config, error) {
 		log.Println("No .env file found, using environment variables")
 	}
 
-	return &Config{
+	cfg := &Config{
 		Server: ServerConfig{
 			Port:            os.Getenv("SERVER_PORT"),
 			ReadTimeout:     30,
@@ -54,5 +82,37 @@ func Load() (*Config, error) {
 		JWT: JWTConfig{
 			Secret: os.Getenv("JWT_SECRET"),
 		},
-	}, nil
+		Storage: StorageConfig{
+			Driver:    getEnv("STORAGE_DRIVER", "local"),
+			LocalDir:  getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+			PublicURL: os.Getenv("STORAGE_PUBLIC_URL"),
+			S3: S3Config{
+				Endpoint:  os.Getenv("S3_ENDPOINT"),
+				Region:    os.Getenv("S3_REGION"),
+				Bucket:    os.Getenv("S3_BUCKET"),
+				AccessKey: os.Getenv("S3_ACCESS_KEY"),
+				SecretKey: os.Getenv("S3_SECRET_KEY"),
+			},
+		},
+		Inventory: InventoryConfig{
+			ValuationMethod: strings.ToLower(getEnv("VALUATION_METHOD", "fifo")),
+		},
+	}
+
+	if m := cfg.Inventory.ValuationMethod; m != "fifo" && m != "average" {
+		return nil, fmt.Errorf("VALUATION_METHOD must be fifo or average, got %q", m)
+	}
+	threshold, err := strconv.ParseInt(getEnv("ADJUST_APPROVAL_THRESHOLD", "0"), 10, 64)
+	if err != nil || threshold < 0 {
+		return nil, fmt.Errorf("ADJUST_APPROVAL_THRESHOLD must be a non-negative whole number, got %q", os.Getenv("ADJUST_APPROVAL_THRESHOLD"))
+	}
+	cfg.Inventory.AdjustApprovalThreshold = threshold
+	return cfg, nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
 }
@@ -6,6 +6,7 @@ CREATE TABLE users (
   password VARCHAR(255) NOT NULL,
   date_of_birth DATE,
   gender ENUM('L', 'P'),
+  role ENUM('staff', 'manager', 'admin') NOT NULL DEFAULT 'staff',
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
   updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
@@ -14,35 +15,275 @@ CREATE TABLE categories (
   id INT AUTO_INCREMENT PRIMARY KEY,
   name VARCHAR(100) NOT NULL,
   description TEXT,
+  version INT NOT NULL DEFAULT 1,
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
   updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
 );
 
+CREATE TABLE warehouses (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  code VARCHAR(20) NOT NULL UNIQUE,
+  name VARCHAR(100) NOT NULL,
+  address TEXT,
+  is_default BOOLEAN NOT NULL DEFAULT FALSE,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
+
+-- Stock set directly on products and variants is held at the default
+-- warehouse.
+INSERT INTO warehouses (code, name, is_default) VALUES ('MAIN', 'Main warehouse', TRUE);
+
 CREATE TABLE products (
   id INT AUTO_INCREMENT PRIMARY KEY,
+  sku VARCHAR(64) UNIQUE,
+  barcode VARCHAR(13) UNIQUE,
   name VARCHAR(100) NOT NULL,
   description TEXT,
   image_url TEXT,
   category_id INT NOT NULL,
-  stock INT NOT NULL DEFAULT 0,
+  base_unit VARCHAR(20) NOT NULL DEFAULT 'pcs',
+  quantity_precision TINYINT NOT NULL DEFAULT 0,
+  stock DECIMAL(18,3) NOT NULL DEFAULT 0,
+  currency CHAR(3) NOT NULL DEFAULT 'IDR',
+  archived_at DATETIME,
+  version INT NOT NULL DEFAULT 1,
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
   updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   FOREIGN KEY (category_id) REFERENCES categories(id)
 );
 
+CREATE TABLE product_history (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  revision INT NOT NULL,
+  product_version INT NOT NULL,
+  snapshot JSON NOT NULL,
+  valid_from DATETIME NOT NULL,
+  UNIQUE KEY uq_product_history_revision (product_id, revision),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE product_prices (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  price BIGINT NOT NULL,
+  cost BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  effective_from DATETIME NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  INDEX idx_product_prices_effective (product_id, effective_from),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE product_variants (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  sku VARCHAR(64) UNIQUE,
+  options JSON NOT NULL,
+  option_key VARCHAR(255) NOT NULL,
+  stock DECIMAL(18,3) NOT NULL DEFAULT 0,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_product_variant_options (product_id, option_key),
+  FOREIGN KEY (product_id) REFERENCES products(id)
+);
+
+-- product_stock and variant_stock split products.stock and
+-- product_variants.stock by warehouse. With the stock of transfers in
+-- transit they always add up to them.
+CREATE TABLE product_stock (
+  product_id INT NOT NULL,
+  warehouse_id INT NOT NULL,
+  stock DECIMAL(18,3) NOT NULL DEFAULT 0,
+  PRIMARY KEY (product_id, warehouse_id),
+  CHECK (stock >= 0),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+
+CREATE TABLE variant_stock (
+  variant_id INT NOT NULL,
+  warehouse_id INT NOT NULL,
+  stock DECIMAL(18,3) NOT NULL DEFAULT 0,
+  PRIMARY KEY (variant_id, warehouse_id),
+  CHECK (stock >= 0),
+  FOREIGN KEY (variant_id) REFERENCES product_variants(id) ON DELETE CASCADE,
+  FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+
+-- bins are the shelves inside a warehouse, addressed by zone, aisle, rack
+-- and bin. capacity is in base units of whatever a bin holds; NULL means
+-- unlimited.
+CREATE TABLE bins (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  warehouse_id INT NOT NULL,
+  zone VARCHAR(20) NOT NULL,
+  aisle VARCHAR(20) NOT NULL,
+  rack VARCHAR(20) NOT NULL,
+  bin VARCHAR(20) NOT NULL,
+  capacity DECIMAL(18,3),
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_bins_position (warehouse_id, zone, aisle, rack, bin),
+  CHECK (capacity > 0),
+  FOREIGN KEY (warehouse_id) REFERENCES warehouses(id)
+);
+
+-- bin_stock places part of the stock of an item at a warehouse in bins;
+-- what is in no bin is unbinned. variant_key stands in for a missing
+-- variant so each item has one row per bin.
+CREATE TABLE bin_stock (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  bin_id INT NOT NULL,
+  product_id INT NOT NULL,
+  variant_id INT NULL,
+  variant_key INT AS (COALESCE(variant_id, 0)) STORED,
+  stock DECIMAL(18,3) NOT NULL DEFAULT 0,
+  UNIQUE KEY uq_bin_stock_item (bin_id, product_id, variant_key),
+  CHECK (stock >= 0),
+  FOREIGN KEY (bin_id) REFERENCES bins(id) ON DELETE CASCADE,
+  FOREIGN KEY (product_id) REFERENCES products(id),
+  FOREIGN KEY (variant_id) REFERENCES product_variants(id)
+);
+
+CREATE TABLE product_units (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  name VARCHAR(20) NOT NULL,
+  factor INT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE KEY uq_product_unit_name (product_id, name),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
+CREATE TABLE product_images (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  position INT NOT NULL DEFAULT 0,
+  storage_key VARCHAR(255) NOT NULL,
+  thumbnail_key VARCHAR(255) NOT NULL,
+  content_type VARCHAR(50) NOT NULL,
+  size_bytes INT NOT NULL,
+  width INT NOT NULL,
+  height INT NOT NULL,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE
+);
+
 CREATE TABLE transactions (
   id INT AUTO_INCREMENT PRIMARY KEY,
-  transaction_type ENUM('IN', 'OUT') NOT NULL,
+  transaction_type ENUM('IN', 'OUT', 'ADJUST', 'TRANSFER') NOT NULL,
   user_id INT NOT NULL,
+  warehouse_id INT NOT NULL,
+  destination_warehouse_id INT NULL,
   created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-  FOREIGN KEY (user_id) REFERENCES users(id)
+  reverses_transaction_id INT NULL,
+  reason ENUM('damaged', 'lost', 'found', 'count_correction') NULL,
+  reference_number VARCHAR(64) NULL,
+  counterparty VARCHAR(255) NULL,
+  notes TEXT NULL,
+  status ENUM('posted', 'pending', 'rejected', 'in_transit') NOT NULL DEFAULT 'posted',
+  reviewed_by INT NULL,
+  reviewed_at DATETIME NULL,
+  received_by INT NULL,
+  received_at DATETIME NULL,
+  UNIQUE KEY uq_transactions_reverses (reverses_transaction_id),
+  UNIQUE KEY uq_transactions_reference (transaction_type, reference_number),
+  INDEX idx_transactions_status (status),
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (warehouse_id) REFERENCES warehouses(id),
+  FOREIGN KEY (destination_warehouse_id) REFERENCES warehouses(id),
+  FOREIGN KEY (reviewed_by) REFERENCES users(id),
+  FOREIGN KEY (received_by) REFERENCES users(id),
+  FOREIGN KEY (reverses_transaction_id) REFERENCES transactions(id)
+);
+
+CREATE TABLE transaction_attachments (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  transaction_id INT NOT NULL,
+  storage_key VARCHAR(255) NOT NULL,
+  file_name VARCHAR(255) NOT NULL,
+  content_type VARCHAR(100) NOT NULL,
+  size_bytes INT NOT NULL,
+  uploaded_by INT NOT NULL,
+  created_at DATETIME NOT NULL,
+  FOREIGN KEY (transaction_id) REFERENCES transactions(id),
+  FOREIGN KEY (uploaded_by) REFERENCES users(id)
 );
 
 CREATE TABLE transaction_items (
   id INT AUTO_INCREMENT PRIMARY KEY,
   transaction_id INT NOT NULL,
   product_id INT NOT NULL,
-  quantity INT NOT NULL,
+  variant_id INT,
+  quantity DECIMAL(18,3) NOT NULL,
+  unit VARCHAR(20),
+  unit_quantity DECIMAL(18,3),
+  unit_cost BIGINT,
+  unit_price BIGINT,
+  currency CHAR(3),
+  cost_of_goods BIGINT,
+  stock_before DECIMAL(18,3),
+  stock_after DECIMAL(18,3),
+  counted DECIMAL(18,3),
+  received_quantity DECIMAL(18,3),
   FOREIGN KEY (transaction_id) REFERENCES transactions(id),
-  FOREIGN KEY (product_id) REFERENCES products(id)
+  FOREIGN KEY (product_id) REFERENCES products(id),
+  FOREIGN KEY (variant_id) REFERENCES product_variants(id)
+);
+
+-- transaction_item_bins records the bins an IN item was put away to and an
+-- OUT item was picked from.
+CREATE TABLE transaction_item_bins (
+  transaction_item_id INT NOT NULL,
+  bin_id INT NOT NULL,
+  quantity DECIMAL(18,3) NOT NULL,
+  PRIMARY KEY (transaction_item_id, bin_id),
+  FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id),
+  FOREIGN KEY (bin_id) REFERENCES bins(id)
+);
+
+CREATE TABLE cost_layers (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  transaction_item_id INT NULL,
+  quantity DECIMAL(18,3) NOT NULL,
+  remaining_quantity DECIMAL(18,3) NOT NULL,
+  total_cost BIGINT NOT NULL,
+  remaining_cost BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  created_at DATETIME NOT NULL,
+  INDEX idx_cost_layers_open (product_id, remaining_quantity),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id)
+);
+
+CREATE TABLE stock_ledger (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  product_id INT NOT NULL,
+  transaction_item_id INT NULL,
+  quantity_change DECIMAL(18,3) NOT NULL,
+  quantity_after DECIMAL(18,3) NOT NULL,
+  fifo_value_after BIGINT NOT NULL,
+  average_value_after BIGINT NOT NULL,
+  currency CHAR(3) NOT NULL,
+  created_at DATETIME NOT NULL,
+  INDEX idx_stock_ledger_product (product_id, created_at),
+  FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE,
+  FOREIGN KEY (transaction_item_id) REFERENCES transaction_items(id)
+);
+
+CREATE TABLE idempotency_keys (
+  id INT AUTO_INCREMENT PRIMARY KEY,
+  user_id INT NOT NULL,
+  idempotency_key VARCHAR(255) NOT NULL,
+  request_hash CHAR(64) NOT NULL,
+  transaction_id INT NOT NULL,
+  response_status INT NOT NULL,
+  response_body MEDIUMBLOB NOT NULL,
+  created_at DATETIME NOT NULL,
+  UNIQUE KEY uq_idempotency_keys_user_key (user_id, idempotency_key),
+  FOREIGN KEY (user_id) REFERENCES users(id),
+  FOREIGN KEY (transaction_id) REFERENCES transactions(id)
 );
@@ -0,0 +1,133 @@
+package barcode
+
+import (
+	"reflect"
+	"testing"
+)
+
+// code128Values reads a Code 128 symbol back into its symbol values,
+// start, data, checksum and stop, by matching each group of bars and
+// spaces against the pattern table.
+func code128Values(t *testing.T, s *Symbol) []int {
+	t.Helper()
+	var widths []byte
+	for x := 0; x < s.Width; {
+		dark := s.Dark(x, 0)
+		start := x
+		for x < s.Width && s.Dark(x, 0) == dark {
+			x++
+		}
+		widths = append(widths, byte('0'+x-start))
+	}
+
+	index := make(map[string]int, len(code128Patterns))
+	for v, p := range code128Patterns {
+		index[p] = v
+	}
+	var values []int
+	for len(widths) > 0 {
+		n := 6
+		if len(widths) == 7 {
+			n = 7
+		}
+		v, ok := index[string(widths[:n])]
+		if !ok {
+			t.Fatalf("no symbol value has the pattern %s", widths[:n])
+		}
+		values = append(values, v)
+		widths = widths[n:]
+	}
+	return values
+}
+
+func TestEncodeCode128(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		// Set B throughout; checksum 879 mod 103 = 55.
+		{"PJJ123C", []int{104, 48, 42, 42, 17, 18, 19, 35, 55, 106}},
+		// An even run of digits uses set C from the start; 353 mod 103 = 44.
+		{"123456", []int{105, 12, 34, 56, 44, 106}},
+		// Two digits make up the whole text; 117 mod 103 = 14.
+		{"12", []int{105, 12, 14, 106}},
+		// Three digits are shorter in set B; 214 mod 103 = 8.
+		{"123", []int{104, 17, 18, 19, 8, 106}},
+		// An odd run of five digits: the first stays in set B, the other
+		// four switch to C; 1037 mod 103 = 7.
+		{"AB12345", []int{104, 33, 34, 17, 99, 23, 45, 7, 106}},
+		// Back to set B after the digits; 787 mod 103 = 66.
+		{"1234AB", []int{105, 12, 34, 100, 33, 34, 66, 106}},
+		// A short run of digits between letters stays in set B;
+		// 104+33+34+54+136 = 361, mod 103 = 52.
+		{"A12B", []int{104, 33, 17, 18, 34, 52, 106}},
+		// Space and tilde are the ends of set B; 104+0+94*2 = 292, mod 103 = 86.
+		{" ~", []int{104, 0, 94, 86, 106}},
+	}
+	for _, tt := range tests {
+		s, err := EncodeCode128(tt.text)
+		if err != nil {
+			t.Errorf("EncodeCode128(%q): %v", tt.text, err)
+			continue
+		}
+		if got := code128Values(t, s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EncodeCode128(%q) values = %v, want %v", tt.text, got, tt.want)
+		}
+		// Every symbol value is 11 modules wide, the stop pattern 13.
+		if want := 11*(len(tt.want)-1) + 13; s.Width != want {
+			t.Errorf("EncodeCode128(%q) width = %d, want %d", tt.text, s.Width, want)
+		}
+		if s.Text != tt.text || s.Kind != Code128 {
+			t.Errorf("EncodeCode128(%q) = %+v", tt.text, s)
+		}
+	}
+}
+
+func TestEncodeCode128Modules(t *testing.T) {
+	// "12": start C 211232, 12 is 112232, checksum 14 is 122231, stop 2331112.
+	want := "11010011100" + "10110011100" + "10011001110" + "1100011101011"
+	s, err := EncodeCode128("12")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := modulesString(s); got != want {
+		t.Errorf("modules =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestEncodeCode128Rejects(t *testing.T) {
+	for _, text := range []string{"", "tab\there", "café", "\x7f"} {
+		if _, err := EncodeCode128(text); err == nil {
+			t.Errorf("EncodeCode128(%q) succeeded, want an error", text)
+		}
+	}
+}
+
+func TestCode128Patterns(t *testing.T) {
+	if len(code128Patterns) != 107 {
+		t.Fatalf("%d patterns, want 107", len(code128Patterns))
+	}
+	seen := map[string]int{}
+	for v, p := range code128Patterns {
+		sum := 0
+		for _, c := range p {
+			sum += int(c - '0')
+		}
+		want := 11
+		if v == code128Stop {
+			want = 13
+		}
+		if sum != want {
+			t.Errorf("pattern %d (%s) is %d modules wide, want %d", v, p, sum, want)
+		}
+		if other, dup := seen[p]; dup {
+			t.Errorf("patterns %d and %d are both %s", other, v, p)
+		}
+		seen[p] = v
+	}
+	for v, want := range map[int]string{0: "212222", 99: "113141", 100: "114131", 104: "211214", 105: "211232", 106: "2331112"} {
+		if got := code128Patterns[v]; got != want {
+			t.Errorf("pattern %d = %s, want %s", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package barcode
+
+import (
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+var (
+	eanLCodes = [10]string{
+		"0001101", "0011001", "0010011", "0111101", "0100011",
+		"0110001", "0101111", "0111011", "0110111", "0001011",
+	}
+	eanGCodes = [10]string{
+		"0100111", "0110011", "0011011", "0100001", "0011101",
+		"0111001", "0000101", "0010001", "0001001", "0010111",
+	}
+	eanRCodes = [10]string{
+		"1110010", "1100110", "1101100", "1000010", "1011100",
+		"1001110", "1010000", "1000100", "1001000", "1110100",
+	}
+	// eanParity selects L or G codes for the left half based on the
+	// first digit, which is not encoded directly.
+	eanParity = [10]string{
+		"LLLLLL", "LLGLGG", "LLGGLG", "LLGGGL", "LGLLGG",
+		"LGGLLG", "LGGGLL", "LGLGLL", "LGLGGL", "LGGLGL",
+	}
+)
+
+// EncodeEAN13 encodes a 13-digit EAN code. UPC-A codes are accepted and
+// encoded in their EAN-13 form.
+func EncodeEAN13(code string) (*Symbol, error) {
+	normalized, err := utils.NormalizeGTIN(code)
+	if err != nil {
+		return nil, fmt.Errorf("ean13: %w", err)
+	}
+	if len(normalized) != 13 {
+		return nil, fmt.Errorf("ean13: %q is not a 13-digit code", code)
+	}
+
+	modules := appendBits(nil, "101")
+	parity := eanParity[normalized[0]-'0']
+	for i := 1; i <= 6; i++ {
+		d := normalized[i] - '0'
+		if parity[i-1] == 'L' {
+			modules = appendBits(modules, eanLCodes[d])
+		} else {
+			modules = appendBits(modules, eanGCodes[d])
+		}
+	}
+	modules = appendBits(modules, "01010")
+	for i := 7; i <= 12; i++ {
+		modules = appendBits(modules, eanRCodes[normalized[i]-'0'])
+	}
+	modules = appendBits(modules, "101")
+
+	return newLinear(EAN13, normalized, modules), nil
+}
@@ -0,0 +1,96 @@
+package barcode
+
+import (
+	"strings"
+	"testing"
+)
+
+func modulesString(s *Symbol) string {
+	var b strings.Builder
+	for x := 0; x < s.Width; x++ {
+		if s.Dark(x, 0) {
+			b.WriteByte('1')
+		} else {
+			b.WriteByte('0')
+		}
+	}
+	return b.String()
+}
+
+func TestEncodeEAN13(t *testing.T) {
+	// 4006381333931: the leading 4 selects the parity LGLLGG for 006381.
+	want := "101" +
+		"0001101" + "0100111" + "0101111" + "0111101" + "0001001" + "0110011" +
+		"01010" +
+		"1000010" + "1000010" + "1000010" + "1110100" + "1000010" + "1100110" +
+		"101"
+
+	s, err := EncodeEAN13("4006381333931")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := modulesString(s); got != want {
+		t.Errorf("modules =\n%s\nwant\n%s", got, want)
+	}
+	if s.Width != 95 || s.Text != "4006381333931" || s.Kind != EAN13 || !s.Linear() {
+		t.Errorf("symbol = %+v", s)
+	}
+}
+
+func TestEncodeEAN13CheckDigit(t *testing.T) {
+	tests := []struct {
+		code, text string
+		ok         bool
+	}{
+		{"4006381333931", "4006381333931", true},
+		{"5901234123457", "5901234123457", true},
+		{"9780201379624", "9780201379624", true},
+		// UPC-A codes are encoded with a leading zero.
+		{"036000291452", "0036000291452", true},
+		{"4006381333930", "", false},
+		{"4006381333932", "", false},
+		{"036000291453", "", false},
+		{"400638133393", "", false},
+		{"400638133393A", "", false},
+		// EAN-8 is a valid GTIN but not an EAN-13 symbol.
+		{"96385074", "", false},
+	}
+	for _, tt := range tests {
+		s, err := EncodeEAN13(tt.code)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("EncodeEAN13(%q) succeeded, want an error", tt.code)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("EncodeEAN13(%q): %v", tt.code, err)
+			continue
+		}
+		if s.Text != tt.text {
+			t.Errorf("EncodeEAN13(%q).Text = %q, want %q", tt.code, s.Text, tt.text)
+		}
+	}
+}
+
+// TestEANCodeTables checks the tables against the relations between them:
+// R codes are the complements of L codes and G codes are R codes reversed.
+func TestEANCodeTables(t *testing.T) {
+	for d := 0; d < 10; d++ {
+		l, g, r := eanLCodes[d], eanGCodes[d], eanRCodes[d]
+		var complement, reversed strings.Builder
+		for i := range l {
+			complement.WriteByte('0' + '1' - l[i])
+			reversed.WriteByte(r[len(r)-1-i])
+		}
+		if r != complement.String() {
+			t.Errorf("R code of %d = %s, want %s", d, r, complement.String())
+		}
+		if g != reversed.String() {
+			t.Errorf("G code of %d = %s, want %s", d, g, reversed.String())
+		}
+		if l[0] != '0' || l[6] != '1' {
+			t.Errorf("L code of %d = %s does not start light and end dark", d, l)
+		}
+	}
+}
@@ -0,0 +1,447 @@
+package barcode
+
+import "fmt"
+
+// qrVersion describes the error-correction layout of a QR version at
+// level M, the only level used for labels.
+type qrVersion struct {
+	ecPerBlock int
+	blocks     [2][2]int // {count, data codewords} per block group
+	alignment  []int
+}
+
+var qrVersions = [...]qrVersion{
+	1:  {10, [2][2]int{{1, 16}}, nil},
+	2:  {16, [2][2]int{{1, 28}}, []int{6, 18}},
+	3:  {26, [2][2]int{{1, 44}}, []int{6, 22}},
+	4:  {18, [2][2]int{{2, 32}}, []int{6, 26}},
+	5:  {24, [2][2]int{{2, 43}}, []int{6, 30}},
+	6:  {16, [2][2]int{{4, 27}}, []int{6, 34}},
+	7:  {18, [2][2]int{{4, 31}}, []int{6, 22, 38}},
+	8:  {22, [2][2]int{{2, 38}, {2, 39}}, []int{6, 24, 42}},
+	9:  {22, [2][2]int{{3, 36}, {2, 37}}, []int{6, 26, 46}},
+	10: {26, [2][2]int{{4, 43}, {1, 44}}, []int{6, 28, 50}},
+}
+
+func (v qrVersion) dataCodewords() int {
+	return v.blocks[0][0]*v.blocks[0][1] + v.blocks[1][0]*v.blocks[1][1]
+}
+
+// EncodeQR encodes text in byte mode at error-correction level M, picking
+// the smallest version from 1 to 10 that fits.
+func EncodeQR(text string) (*Symbol, error) {
+	data := []byte(text)
+
+	version := 0
+	for v := 1; v < len(qrVersions); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrVersions[v].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("qr: %d bytes exceed the supported capacity", len(data))
+	}
+
+	q := newQRMatrix(version)
+	q.drawFunctionPatterns()
+	q.drawCodewords(q.addErrorCorrection(q.encodeData(data)))
+
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(best)
+
+	return &Symbol{
+		Kind:      QR,
+		Text:      text,
+		Width:     q.size,
+		Height:    q.size,
+		QuietZone: 4,
+		modules:   q.modules,
+	}, nil
+}
+
+type qrMatrix struct {
+	version    int
+	size       int
+	modules    []bool
+	isFunction []bool
+}
+
+func newQRMatrix(version int) *qrMatrix {
+	size := version*4 + 17
+	return &qrMatrix{
+		version:    version,
+		size:       size,
+		modules:    make([]bool, size*size),
+		isFunction: make([]bool, size*size),
+	}
+}
+
+func (q *qrMatrix) get(x, y int) bool {
+	return q.modules[y*q.size+x]
+}
+
+func (q *qrMatrix) setFunction(x, y int, dark bool) {
+	q.modules[y*q.size+x] = dark
+	q.isFunction[y*q.size+x] = true
+}
+
+func (q *qrMatrix) drawFunctionPatterns() {
+	for i := 0; i < q.size; i++ {
+		q.setFunction(6, i, i%2 == 0)
+		q.setFunction(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	pos := qrVersions[q.version].alignment
+	last := len(pos) - 1
+	for i := range pos {
+		for j := range pos {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			q.drawAlignment(pos[i], pos[j])
+		}
+	}
+
+	// Reserve the format areas; the real bits are drawn once a mask is chosen.
+	q.drawFormatBits(0)
+	q.drawVersion()
+}
+
+func (q *qrMatrix) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= q.size || y < 0 || y >= q.size {
+				continue
+			}
+			dist := max(abs(dx), abs(dy))
+			q.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (q *qrMatrix) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.setFunction(cx+dx, cy+dy, max(abs(dx), abs(dy)) != 1)
+		}
+	}
+}
+
+func (q *qrMatrix) drawFormatBits(mask int) {
+	// Level M is encoded as 00, so only the mask contributes to the data.
+	data := mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+
+	for i := 0; i <= 5; i++ {
+		q.setFunction(8, i, bit(bits, i))
+	}
+	q.setFunction(8, 7, bit(bits, 6))
+	q.setFunction(8, 8, bit(bits, 7))
+	q.setFunction(7, 8, bit(bits, 8))
+	for i := 9; i < 15; i++ {
+		q.setFunction(14-i, 8, bit(bits, i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.setFunction(q.size-1-i, 8, bit(bits, i))
+	}
+	for i := 8; i < 15; i++ {
+		q.setFunction(8, q.size-15+i, bit(bits, i))
+	}
+	q.setFunction(8, q.size-8, true)
+}
+
+func (q *qrMatrix) drawVersion() {
+	if q.version < 7 {
+		return
+	}
+	rem := q.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := q.version<<12 | rem
+
+	for i := 0; i < 18; i++ {
+		a := q.size - 11 + i%3
+		b := i / 3
+		q.setFunction(a, b, bit(bits, i))
+		q.setFunction(b, a, bit(bits, i))
+	}
+}
+
+func (q *qrMatrix) encodeData(data []byte) []byte {
+	capacity := qrVersions[q.version].dataCodewords()
+	countBits := 8
+	if q.version >= 10 {
+		countBits = 16
+	}
+
+	var bb bitBuffer
+	bb.append(0x4, 4)
+	bb.append(len(data), countBits)
+	for _, b := range data {
+		bb.append(int(b), 8)
+	}
+	bb.append(0, min(4, capacity*8-len(bb)))
+	bb.append(0, (8-len(bb)%8)%8)
+	for pad := 0xEC; len(bb) < capacity*8; pad ^= 0xEC ^ 0x11 {
+		bb.append(pad, 8)
+	}
+
+	out := make([]byte, capacity)
+	for i, b := range bb {
+		if b {
+			out[i>>3] |= 1 << (7 - i&7)
+		}
+	}
+	return out
+}
+
+func (q *qrMatrix) addErrorCorrection(data []byte) []byte {
+	v := qrVersions[q.version]
+	divisor := rsDivisor(v.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for _, group := range v.blocks {
+		for i := 0; i < group[0]; i++ {
+			block := data[offset : offset+group[1]]
+			offset += group[1]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, rsRemainder(block, divisor))
+		}
+	}
+
+	var out []byte
+	for i := 0; ; i++ {
+		added := false
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				out = append(out, block[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := 0; i < v.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+func (q *qrMatrix) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if q.isFunction[y*q.size+x] || i >= len(codewords)*8 {
+					continue
+				}
+				q.modules[y*q.size+x] = codewords[i>>3]>>(7-i&7)&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+func (q *qrMatrix) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.isFunction[y*q.size+x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y*q.size+x] = !q.modules[y*q.size+x]
+			}
+		}
+	}
+}
+
+// penalty scores the matrix with the four rules from ISO/IEC 18004 so the
+// least ambiguous mask can be chosen.
+func (q *qrMatrix) penalty() int {
+	score := 0
+	line := make([]bool, q.size)
+
+	for pass := 0; pass < 2; pass++ {
+		for a := 0; a < q.size; a++ {
+			for b := 0; b < q.size; b++ {
+				if pass == 0 {
+					line[b] = q.get(b, a)
+				} else {
+					line[b] = q.get(a, b)
+				}
+			}
+			score += linePenalty(line)
+		}
+	}
+
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			c := q.get(x, y)
+			if c {
+				dark++
+			}
+			if x+1 < q.size && y+1 < q.size && c == q.get(x+1, y) && c == q.get(x, y+1) && c == q.get(x+1, y+1) {
+				score += 3
+			}
+		}
+	}
+
+	total := q.size * q.size
+	deviation := abs(dark*20-total*10) / total
+	score += deviation * 10
+
+	return score
+}
+
+var qrFinderLike = [2][11]bool{
+	{true, false, true, true, true, false, true, false, false, false, false},
+	{false, false, false, false, true, false, true, true, true, false, true},
+}
+
+func linePenalty(line []bool) int {
+	score := 0
+
+	run := 1
+	for i := 1; i <= len(line); i++ {
+		if i < len(line) && line[i] == line[i-1] {
+			run++
+			continue
+		}
+		if run >= 5 {
+			score += 3 + run - 5
+		}
+		run = 1
+	}
+
+	for i := 0; i+11 <= len(line); i++ {
+		for _, pattern := range qrFinderLike {
+			match := true
+			for j, want := range pattern {
+				if line[i+j] != want {
+					match = false
+					break
+				}
+			}
+			if match {
+				score += 40
+			}
+		}
+	}
+
+	return score
+}
+
+type bitBuffer []bool
+
+func (bb *bitBuffer) append(value, length int) {
+	for i := length - 1; i >= 0; i-- {
+		*bb = append(*bb, (value>>i)&1 == 1)
+	}
+}
+
+func rsDivisor(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = gfMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	return result
+}
+
+func rsRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range divisor {
+			result[i] ^= gfMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+// gfMultiply multiplies in GF(2^8) modulo the QR polynomial x^8+x^4+x^3+x^2+1.
+func gfMultiply(x, y byte) byte {
+	z := 0
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func bit(value, i int) bool {
+	return (value>>i)&1 == 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,440 @@
+package barcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// qrBlocks is ISO/IEC 18004 table 9 at level M: the error correction
+// codewords per block and the data codewords of each block.
+var qrBlocks = map[int]struct {
+	ec   int
+	data []int
+}{
+	1:  {10, []int{16}},
+	2:  {16, []int{28}},
+	3:  {26, []int{44}},
+	4:  {18, []int{32, 32}},
+	5:  {24, []int{43, 43}},
+	6:  {16, []int{27, 27, 27, 27}},
+	7:  {18, []int{31, 31, 31, 31}},
+	8:  {22, []int{38, 38, 39, 39}},
+	9:  {22, []int{36, 36, 36, 37, 37}},
+	10: {26, []int{43, 43, 43, 43, 44}},
+}
+
+// qrTotalCodewords and qrRemainderBits are from ISO/IEC 18004 table 1.
+var (
+	qrTotalCodewords = map[int]int{1: 26, 2: 44, 3: 70, 4: 100, 5: 134, 6: 172, 7: 196, 8: 242, 9: 292, 10: 346}
+	qrRemainderBits  = map[int]int{1: 0, 2: 7, 3: 7, 4: 7, 5: 7, 6: 7, 7: 0, 8: 0, 9: 0, 10: 0}
+)
+
+// qrFormatM is the 15-bit format information at level M for masks 0 to 7,
+// from ISO/IEC 18004 annex C.
+var qrFormatM = [8]int{
+	0b101010000010010, 0b101000100100101, 0b101111001111100, 0b101101101001011,
+	0b100010111111001, 0b100000011001110, 0b100111110010111, 0b100101010100000,
+}
+
+// qrVersionInfo is the 18-bit version information from ISO/IEC 18004
+// annex D.
+var qrVersionInfo = map[int]int{7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3}
+
+func TestQRVersionTable(t *testing.T) {
+	for v := 1; v <= 10; v++ {
+		want := qrBlocks[v]
+		got := qrVersions[v]
+		var data []int
+		for _, group := range got.blocks {
+			for i := 0; i < group[0]; i++ {
+				data = append(data, group[1])
+			}
+		}
+		if got.ecPerBlock != want.ec || !equalInts(data, want.data) {
+			t.Errorf("version %d blocks = %d x %v, want %d x %v", v, got.ecPerBlock, data, want.ec, want.data)
+		}
+
+		total := 0
+		for _, n := range want.data {
+			total += n + want.ec
+		}
+		if total != qrTotalCodewords[v] {
+			t.Errorf("version %d has %d codewords, want %d", v, total, qrTotalCodewords[v])
+		}
+
+		// Whatever the function patterns leave free holds exactly the
+		// codewords and remainder bits, which checks the alignment patterns.
+		q := newQRMatrix(v)
+		q.drawFunctionPatterns()
+		free := 0
+		for _, f := range q.isFunction {
+			if !f {
+				free++
+			}
+		}
+		if want := qrTotalCodewords[v]*8 + qrRemainderBits[v]; free != want {
+			t.Errorf("version %d has %d data modules, want %d", v, free, want)
+		}
+	}
+}
+
+func TestQRErrorCorrection(t *testing.T) {
+	// ISO/IEC 18004 annex I: "01234567" at 1-M.
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := []byte{0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55}
+	if got := rsRemainder(data, rsDivisor(10)); !bytes.Equal(got, want) {
+		t.Errorf("error correction = % X, want % X", got, want)
+	}
+}
+
+func TestQRFormatAndVersionBits(t *testing.T) {
+	q := newQRMatrix(1)
+	for mask, want := range qrFormatM {
+		q.drawFormatBits(mask)
+		first, second := readFormatBits(q.size, q.get)
+		if first != want || second != want {
+			t.Errorf("mask %d format bits = %015b and %015b, want %015b", mask, first, second, want)
+		}
+	}
+
+	for v, want := range qrVersionInfo {
+		q := newQRMatrix(v)
+		q.drawVersion()
+		var below, right int
+		for i := 0; i < 18; i++ {
+			a, b := q.size-11+i%3, i/3
+			if q.get(a, b) {
+				right |= 1 << i
+			}
+			if q.get(b, a) {
+				below |= 1 << i
+			}
+		}
+		if right != want || below != want {
+			t.Errorf("version %d bits = %018b and %018b, want %018b", v, right, below, want)
+		}
+	}
+}
+
+func TestQRDataCodewords(t *testing.T) {
+	q := newQRMatrix(1)
+	// Byte mode 0100, count 00000010, "A" 01000001, "B" 01000010, the
+	// terminator 0000, then alternating pad codewords.
+	want := []byte{0x40, 0x24, 0x14, 0x20, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	if got := q.encodeData([]byte("AB")); !bytes.Equal(got, want) {
+		t.Errorf("data codewords = % X, want % X", got, want)
+	}
+
+	// From version 10 the byte count takes 16 bits.
+	q = newQRMatrix(10)
+	got := q.encodeData(bytes.Repeat([]byte("a"), 200))
+	if want := []byte{0x40, 0x0C, 0x86, 0x16}; !bytes.Equal(got[:4], want) {
+		t.Errorf("version 10 data codewords start % X, want % X", got[:4], want)
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	// Byte mode capacities at level M: 14 bytes fit version 1, 180 version
+	// 9 and 213 version 10.
+	tests := []struct{ length, size int }{
+		{1, 21}, {14, 21}, {15, 25}, {26, 25}, {27, 29}, {180, 53}, {181, 57}, {213, 57},
+	}
+	for _, tt := range tests {
+		s, err := EncodeQR(strings.Repeat("x", tt.length))
+		if err != nil {
+			t.Errorf("EncodeQR of %d bytes: %v", tt.length, err)
+			continue
+		}
+		if s.Width != tt.size || s.Height != tt.size {
+			t.Errorf("EncodeQR of %d bytes is %dx%d, want %dx%d", tt.length, s.Width, s.Height, tt.size, tt.size)
+		}
+	}
+	if _, err := EncodeQR(strings.Repeat("x", 214)); err == nil {
+		t.Error("EncodeQR of 214 bytes succeeded, want an error")
+	}
+}
+
+func TestEncodeQRDecodes(t *testing.T) {
+	for _, text := range []string{
+		"4006381333931",
+		"SKU-KOPI-01",
+		"https://example.com/products/42?warehouse=JKT",
+		"Kopi Arabika Gayo 250g — batch #7",
+		strings.Repeat("0123456789", 12),
+		strings.Repeat("qr", 100),
+	} {
+		s, err := EncodeQR(text)
+		if err != nil {
+			t.Errorf("EncodeQR(%q): %v", text, err)
+			continue
+		}
+		if got := decodeQR(t, s); got != text {
+			t.Errorf("EncodeQR(%q) decodes to %q", text, got)
+		}
+	}
+}
+
+// TestEncodeQRMatrix pins the symbol for one input, so a change of the
+// chosen mask or module placement shows up. TestEncodeQRDecodes checks it
+// reads back.
+func TestEncodeQRMatrix(t *testing.T) {
+	want := []string{
+		"#######...#.#.#######",
+		"#.....#....#..#.....#",
+		"#.###.#.##.##.#.###.#",
+		"#.###.#.#.....#.###.#",
+		"#.###.#.###.#.#.###.#",
+		"#.....#.#.##..#.....#",
+		"#######.#.#.#.#######",
+		"........###..........",
+		"#.#####..###..#####..",
+		"..####.##...#...#.###",
+		".#.##.#.###.####..#..",
+		"#.#.##.##.###.....#..",
+		".###.###.##.####.#.#.",
+		"........#.##....#.###",
+		"#######.....####..#..",
+		"#.....#.#..#...#..##.",
+		"#.###.#.#.#.######.##",
+		"#.###.#.####....###..",
+		"#.###.#.#...####.....",
+		"#.....#....##...#.#..",
+		"#######.###..###.#.#.",
+	}
+	s, err := EncodeQR("4006381333931")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, s.Height)
+	for y := range got {
+		var b strings.Builder
+		for x := 0; x < s.Width; x++ {
+			if s.Dark(x, y) {
+				b.WriteByte('#')
+			} else {
+				b.WriteByte('.')
+			}
+		}
+		got[y] = b.String()
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("matrix =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// readFormatBits returns both copies of the format information.
+func readFormatBits(size int, dark func(x, y int) bool) (first, second int) {
+	set := func(bits *int, i int, on bool) {
+		if on {
+			*bits |= 1 << i
+		}
+	}
+	for i := 0; i <= 5; i++ {
+		set(&first, i, dark(8, i))
+	}
+	set(&first, 6, dark(8, 7))
+	set(&first, 7, dark(8, 8))
+	set(&first, 8, dark(7, 8))
+	for i := 9; i < 15; i++ {
+		set(&first, i, dark(14-i, 8))
+	}
+	for i := 0; i < 8; i++ {
+		set(&second, i, dark(size-1-i, 8))
+	}
+	for i := 8; i < 15; i++ {
+		set(&second, i, dark(8, size-15+i))
+	}
+	return first, second
+}
+
+// decodeQR reads a symbol the way a scanner would: it finds the mask from
+// the format information, reads the codewords in placement order, checks
+// every block against its Reed-Solomon codewords and parses the byte mode
+// segment.
+func decodeQR(t *testing.T, s *Symbol) string {
+	t.Helper()
+	size := s.Width
+	version := (size - 17) / 4
+	blocks, ok := qrBlocks[version]
+	if !ok {
+		t.Fatalf("size %d is not a supported version", size)
+	}
+
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		if !s.Dark(c[0], c[1]) || s.Dark(c[0]+2, c[1]) || !s.Dark(c[0]+3, c[1]) {
+			t.Fatalf("no finder pattern centered at %v", c)
+		}
+	}
+	if !s.Dark(8, size-8) {
+		t.Fatal("dark module is light")
+	}
+	for i := 8; i < size-8; i++ {
+		if s.Dark(i, 6) != (i%2 == 0) || s.Dark(6, i) != (i%2 == 0) {
+			t.Fatalf("timing pattern broken at %d", i)
+		}
+	}
+
+	first, second := readFormatBits(size, s.Dark)
+	if first != second {
+		t.Fatalf("format copies differ: %015b and %015b", first, second)
+	}
+	mask := -1
+	for m, bits := range qrFormatM {
+		if bits == first {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		t.Fatalf("format bits %015b are not level M", first)
+	}
+
+	q := newQRMatrix(version)
+	q.drawFunctionPatterns()
+
+	// Codewords run in two-module columns from the bottom right, upwards
+	// and downwards in turn, skipping the vertical timing pattern.
+	total := qrTotalCodewords[version]
+	var (
+		raw    = make([]byte, total)
+		n      int
+		up     = true
+		masked = qrMaskFunc(mask)
+	)
+	for right := size - 1; right >= 1 && n < total*8; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for k := 0; k < size; k++ {
+			i := k
+			if up {
+				i = size - 1 - k
+			}
+			for _, j := range []int{right, right - 1} {
+				if q.isFunction[i*size+j] || n >= total*8 {
+					continue
+				}
+				if s.Dark(j, i) != masked(i, j) {
+					raw[n/8] |= 0x80 >> (n % 8)
+				}
+				n++
+			}
+		}
+		up = !up
+	}
+
+	longest := blocks.data[len(blocks.data)-1]
+	data := make([][]byte, len(blocks.data))
+	ec := make([][]byte, len(blocks.data))
+	pos := 0
+	for i := 0; i < longest; i++ {
+		for b, length := range blocks.data {
+			if i < length {
+				data[b] = append(data[b], raw[pos])
+				pos++
+			}
+		}
+	}
+	for i := 0; i < blocks.ec; i++ {
+		for b := range blocks.data {
+			ec[b] = append(ec[b], raw[pos])
+			pos++
+		}
+	}
+
+	var stream []byte
+	for b := range data {
+		codeword := append(append([]byte{}, data[b]...), ec[b]...)
+		for root := 0; root < blocks.ec; root++ {
+			if syndrome := gfEvaluate(codeword, root); syndrome != 0 {
+				t.Fatalf("block %d fails the Reed-Solomon check at root %d", b, root)
+			}
+		}
+		stream = append(stream, data[b]...)
+	}
+
+	bitAt := 0
+	read := func(length int) int {
+		v := 0
+		for i := 0; i < length; i++ {
+			v = v<<1 | int(stream[bitAt/8]>>(7-bitAt%8)&1)
+			bitAt++
+		}
+		return v
+	}
+	if mode := read(4); mode != 0x4 {
+		t.Fatalf("mode %04b is not byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	text := make([]byte, read(countBits))
+	for i := range text {
+		text[i] = byte(read(8))
+	}
+	if rest := len(stream)*8 - bitAt; rest > 0 && read(min(4, rest)) != 0 {
+		t.Fatal("terminator is not zero")
+	}
+	bitAt = (bitAt + 7) / 8 * 8
+	for pad := byte(0xEC); bitAt < len(stream)*8; pad ^= 0xEC ^ 0x11 {
+		if got := byte(read(8)); got != pad {
+			t.Fatalf("pad codeword %02X, want %02X", got, pad)
+		}
+	}
+	return string(text)
+}
+
+// qrMaskFunc is the data mask condition of ISO/IEC 18004 table 10, for
+// row i and column j.
+func qrMaskFunc(mask int) func(i, j int) bool {
+	return [8]func(i, j int) bool{
+		func(i, j int) bool { return (i+j)%2 == 0 },
+		func(i, j int) bool { return i%2 == 0 },
+		func(i, j int) bool { return j%3 == 0 },
+		func(i, j int) bool { return (i+j)%3 == 0 },
+		func(i, j int) bool { return (i/2+j/3)%2 == 0 },
+		func(i, j int) bool { return i*j%2+i*j%3 == 0 },
+		func(i, j int) bool { return (i*j%2+i*j%3)%2 == 0 },
+		func(i, j int) bool { return (i*j%3+(i+j)%2)%2 == 0 },
+	}[mask]
+}
+
+// gfEvaluate evaluates a codeword, highest degree first, at α^power in
+// GF(256) with the QR field polynomial, using log tables rather than the
+// encoder's multiplication.
+func gfEvaluate(codeword []byte, power int) byte {
+	var exp [510]byte
+	var log [256]int
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i], exp[i+255] = byte(x), byte(x)
+		log[x] = i
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	point := exp[power%255]
+
+	var result byte
+	for _, c := range codeword {
+		if result != 0 {
+			result = exp[log[result]+log[point]]
+		}
+		result ^= c
+	}
+	return result
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
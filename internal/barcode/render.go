@@ -0,0 +1,95 @@
+package barcode
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// PNG renders the symbol with each module drawn as a scale x scale square,
+// surrounded by its quiet zone.
+func PNG(s *Symbol, scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+
+	w := (s.Width + 2*s.QuietZone) * scale
+	h := (s.Height + 2*s.QuietZone) * scale
+	if s.Linear() {
+		h = (s.Height + 2) * scale
+	}
+
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+
+	offY := s.QuietZone
+	if s.Linear() {
+		offY = 1
+	}
+	for y := 0; y < s.Height; y++ {
+		for x := 0; x < s.Width; x++ {
+			if !s.Dark(x, y) {
+				continue
+			}
+			px, py := (x+s.QuietZone)*scale, (y+offY)*scale
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray(px+dx, py+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// SVG renders the symbol as scalable vector graphics. Linear symbols get
+// their human-readable text printed underneath.
+func SVG(s *Symbol, scale int) []byte {
+	if scale < 1 {
+		scale = 1
+	}
+
+	w := s.Width + 2*s.QuietZone
+	h := s.Height + 2*s.QuietZone
+	textHeight := 0
+	if s.Linear() {
+		textHeight = 12
+		h = s.Height + 2 + textHeight
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		w*scale, h*scale, w, h)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/>`, w, h)
+
+	if s.Linear() {
+		for _, bar := range s.Bars() {
+			fmt.Fprintf(&buf, `<rect x="%d" y="1" width="%d" height="%d"/>`, bar[0]+s.QuietZone, bar[1], s.Height)
+		}
+		fmt.Fprintf(&buf, `<text x="%d" y="%d" font-family="monospace" font-size="10" text-anchor="middle">%s</text>`,
+			w/2, s.Height+2+textHeight-2, html.EscapeString(s.Text))
+	} else {
+		buf.WriteString(`<path d="`)
+		for y := 0; y < s.Height; y++ {
+			for x := 0; x < s.Width; x++ {
+				if s.Dark(x, y) {
+					fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+s.QuietZone, y+s.QuietZone)
+				}
+			}
+		}
+		buf.WriteString(`"/>`)
+	}
+
+	buf.WriteString(`</svg>`)
+	return buf.Bytes()
+}
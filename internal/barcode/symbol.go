@@ -0,0 +1,91 @@
+// Package barcode encodes product identifiers as Code 128, EAN-13 and QR
+// symbols and renders them for printing.
+package barcode
+
+const (
+	Code128 = "code128"
+	EAN13   = "ean13"
+	QR      = "qr"
+)
+
+// linearHeight is the bar height, in modules, used when a one-dimensional
+// symbol is drawn as an image.
+const linearHeight = 50
+
+// Symbol is an encoded barcode expressed as a grid of dark and light
+// modules. Linear symbols store a single row that is repeated vertically.
+type Symbol struct {
+	Kind      string
+	Text      string
+	Width     int
+	Height    int
+	QuietZone int
+	linear    bool
+	modules   []bool
+}
+
+func newLinear(kind, text string, modules []bool) *Symbol {
+	return &Symbol{
+		Kind:      kind,
+		Text:      text,
+		Width:     len(modules),
+		Height:    linearHeight,
+		QuietZone: 10,
+		linear:    true,
+		modules:   modules,
+	}
+}
+
+// Dark reports whether the module at column x, row y is dark.
+func (s *Symbol) Dark(x, y int) bool {
+	if x < 0 || x >= s.Width || y < 0 || y >= s.Height {
+		return false
+	}
+	if s.linear {
+		return s.modules[x]
+	}
+	return s.modules[y*s.Width+x]
+}
+
+// Linear reports whether the symbol is a one-dimensional barcode.
+func (s *Symbol) Linear() bool {
+	return s.linear
+}
+
+// Bars returns the dark runs of a linear symbol as (start, width) pairs in
+// modules, which lets vector renderers draw one rectangle per bar.
+func (s *Symbol) Bars() [][2]int {
+	var bars [][2]int
+	for x := 0; x < s.Width; {
+		if !s.Dark(x, 0) {
+			x++
+			continue
+		}
+		start := x
+		for x < s.Width && s.Dark(x, 0) {
+			x++
+		}
+		bars = append(bars, [2]int{start, x - start})
+	}
+	return bars
+}
+
+// appendWidths expands a bar/space width pattern such as "212222" into
+// modules, starting with a dark bar.
+func appendWidths(modules []bool, pattern string) []bool {
+	dark := true
+	for _, c := range pattern {
+		for i := 0; i < int(c-'0'); i++ {
+			modules = append(modules, dark)
+		}
+		dark = !dark
+	}
+	return modules
+}
+
+func appendBits(modules []bool, pattern string) []bool {
+	for _, c := range pattern {
+		modules = append(modules, c == '1')
+	}
+	return modules
+}
@@ -0,0 +1,237 @@
+// Package decimal provides an exact fixed-point number for stock
+// quantities. Values are held as an integer count of thousandths, matching
+// the DECIMAL(18,3) columns they are stored in, so no float rounding occurs.
+package decimal
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Scale is the number of fractional digits a Decimal can hold.
+const Scale = 3
+
+const unit = 1000
+
+type Decimal struct {
+	milli int64
+}
+
+func New(n int64) Decimal {
+	return Decimal{milli: n * unit}
+}
+
+// FromMilli builds a Decimal from a count of thousandths.
+func FromMilli(milli int64) Decimal {
+	return Decimal{milli: milli}
+}
+
+// Parse reads a plain decimal string such as "12", "-0.5" or "3.125".
+// Exponents and more than Scale fractional digits are rejected.
+func Parse(s string) (Decimal, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	body := strings.TrimPrefix(s, "-")
+
+	intPart, fracPart, hasDot := strings.Cut(body, ".")
+	if intPart == "" || (hasDot && fracPart == "") || !digitsOnly(intPart) || !digitsOnly(fracPart) {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", s)
+	}
+	if len(fracPart) > Scale {
+		return Decimal{}, fmt.Errorf("decimal %q has more than %d decimal places", s, Scale)
+	}
+
+	whole, err := strconv.ParseInt(intPart, 10, 64)
+	if err != nil || whole > math.MaxInt64/unit-1 {
+		return Decimal{}, fmt.Errorf("decimal %q is out of range", s)
+	}
+	frac := int64(0)
+	if fracPart != "" {
+		frac, _ = strconv.ParseInt(fracPart+strings.Repeat("0", Scale-len(fracPart)), 10, 64)
+	}
+
+	milli := whole*unit + frac
+	if neg {
+		milli = -milli
+	}
+	return Decimal{milli: milli}, nil
+}
+
+func MustParse(s string) Decimal {
+	d, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func (d Decimal) Milli() int64 { return d.milli }
+
+func (d Decimal) Add(o Decimal) Decimal { return Decimal{milli: d.milli + o.milli} }
+
+func (d Decimal) Sub(o Decimal) Decimal { return Decimal{milli: d.milli - o.milli} }
+
+func (d Decimal) Neg() Decimal { return Decimal{milli: -d.milli} }
+
+// MulInt multiplies by a whole number, e.g. a unit conversion factor. It
+// fails when the result does not fit in a Decimal.
+func (d Decimal) MulInt(n int64) (Decimal, error) {
+	milli := d.milli * n
+	if n != 0 && (milli/n != d.milli || (n == -1 && d.milli == math.MinInt64)) {
+		return Decimal{}, fmt.Errorf("decimal %s times %d is out of range", d, n)
+	}
+	return Decimal{milli: milli}, nil
+}
+
+// QuoRemInt divides by a whole number, returning the whole quotient and
+// the remainder.
+func (d Decimal) QuoRemInt(n int64) (int64, Decimal) {
+	q := d.milli / (n * unit)
+	return q, Decimal{milli: d.milli - q*n*unit}
+}
+
+// MulAmount multiplies a money amount in minor units by d, rounding half
+// away from zero to a whole minor unit.
+func (d Decimal) MulAmount(amount int64) int64 {
+	return Prorate(amount, d, New(1))
+}
+
+// Prorate returns the share of amount that part represents of whole,
+// rounded half away from zero. It is used to split a stock value across the
+// quantity leaving it.
+func Prorate(amount int64, part, whole Decimal) int64 {
+	if whole.IsZero() {
+		return 0
+	}
+	num := new(big.Int).Mul(big.NewInt(amount), big.NewInt(part.milli))
+	den := big.NewInt(whole.milli)
+	if den.Sign() < 0 {
+		num.Neg(num)
+		den.Neg(den)
+	}
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(int64(num.Sign())))
+	}
+	return q.Int64()
+}
+
+func (d Decimal) Cmp(o Decimal) int {
+	switch {
+	case d.milli < o.milli:
+		return -1
+	case d.milli > o.milli:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (d Decimal) IsZero() bool     { return d.milli == 0 }
+func (d Decimal) IsNegative() bool { return d.milli < 0 }
+func (d Decimal) IsPositive() bool { return d.milli > 0 }
+
+// Places reports how many fractional digits are significant, so 1.500
+// has one place and 2 has none.
+func (d Decimal) Places() int {
+	frac := d.milli % unit
+	if frac < 0 {
+		frac = -frac
+	}
+	places := Scale
+	for places > 0 && frac%10 == 0 {
+		frac /= 10
+		places--
+	}
+	return places
+}
+
+// String formats the value without trailing zeros.
+func (d Decimal) String() string {
+	s := d.fixed()
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+func (d Decimal) fixed() string {
+	milli := d.milli
+	sign := ""
+	if milli < 0 {
+		sign = "-"
+		milli = -milli
+	}
+	return fmt.Sprintf("%s%d.%03d", sign, milli/unit, milli%unit)
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a quoted decimal string.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	s = strings.Trim(s, `"`)
+
+	parsed, err := Parse(s)
+	if err != nil {
+		return &json.UnmarshalTypeError{Value: "number " + s, Type: reflect.TypeOf(Decimal{})}
+	}
+	*d = parsed
+	return nil
+}
+
+func (d *Decimal) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Decimal{}
+		return nil
+	case int64:
+		*d = New(v)
+		return nil
+	case []byte:
+		return d.scanString(string(v))
+	case string:
+		return d.scanString(v)
+	default:
+		return fmt.Errorf("cannot scan %T into decimal", src)
+	}
+}
+
+func (d *Decimal) scanString(s string) error {
+	// Aggregates such as SUM may come back with more digits than Scale;
+	// they only ever add trailing zeros for DECIMAL(18,3) inputs.
+	if intPart, frac, ok := strings.Cut(s, "."); ok && len(frac) > Scale {
+		s = intPart + "." + frac[:Scale]
+	}
+	parsed, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) Value() (driver.Value, error) {
+	return d.fixed(), nil
+}
+
+func digitsOnly(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
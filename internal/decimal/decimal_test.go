@@ -0,0 +1,147 @@
+package decimal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		milli   int64
+		wantErr bool
+	}{
+		{in: "12", milli: 12000},
+		{in: "-0.5", milli: -500},
+		{in: "3.125", milli: 3125},
+		{in: " 7.1 ", milli: 7100},
+		{in: "0.001", milli: 1},
+		{in: "-0", milli: 0},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "5.", wantErr: true},
+		{in: "1e3", wantErr: true},
+		{in: "+1", wantErr: true},
+		{in: "1.2345", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) returned error: %v", tt.in, err)
+			continue
+		}
+		if got.Milli() != tt.milli {
+			t.Errorf("Parse(%q) = %d thousandths, want %d", tt.in, got.Milli(), tt.milli)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		milli int64
+		want  string
+	}{
+		{0, "0"},
+		{12000, "12"},
+		{1500, "1.5"},
+		{1050, "1.05"},
+		{3125, "3.125"},
+		{1, "0.001"},
+		{-500, "-0.5"},
+		{-12000, "-12"},
+	}
+	for _, tt := range tests {
+		if got := FromMilli(tt.milli).String(); got != tt.want {
+			t.Errorf("FromMilli(%d).String() = %q, want %q", tt.milli, got, tt.want)
+		}
+	}
+}
+
+func TestMulInt(t *testing.T) {
+	tests := []struct {
+		d       Decimal
+		n       int64
+		want    Decimal
+		wantErr bool
+	}{
+		{d: MustParse("1.5"), n: 12, want: MustParse("18")},
+		{d: MustParse("-0.25"), n: 4, want: MustParse("-1")},
+		{d: MustParse("7"), n: 0, want: Decimal{}},
+		{d: FromMilli(math.MaxInt64 / 2), n: 2, want: FromMilli(math.MaxInt64 - 1)},
+		{d: FromMilli(math.MaxInt64/2 + 1), n: 2, wantErr: true},
+		{d: FromMilli(math.MinInt64), n: -1, wantErr: true},
+		{d: FromMilli(-1), n: math.MinInt64, wantErr: true},
+		{d: MustParse("1000000"), n: math.MaxInt64, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.d.MulInt(tt.n)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%v.MulInt(%d) = %v, want an error", tt.d, tt.n, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v.MulInt(%d) returned error: %v", tt.d, tt.n, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%v.MulInt(%d) = %v, want %v", tt.d, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestMulAmount(t *testing.T) {
+	tests := []struct {
+		d      string
+		amount int64
+		want   int64
+	}{
+		{"2", 1500, 3000},
+		{"0.5", 3, 2},
+		{"0.5", -3, -2},
+		{"-0.5", 3, -2},
+		{"0.001", 499, 0},
+		{"0.001", 500, 1},
+		{"0.001", -500, -1},
+		{"1.333", 100, 133},
+		{"0", 1000, 0},
+	}
+	for _, tt := range tests {
+		if got := MustParse(tt.d).MulAmount(tt.amount); got != tt.want {
+			t.Errorf("%s.MulAmount(%d) = %d, want %d", tt.d, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestProrate(t *testing.T) {
+	tests := []struct {
+		amount      int64
+		part, whole string
+		want        int64
+	}{
+		{1000, "1", "3", 333},
+		{1000, "2", "3", 667},
+		{1000, "3", "3", 1000},
+		{1, "1", "2", 1},
+		{-1, "1", "2", -1},
+		{5, "1", "-2", -3},
+		{-5, "-1", "-2", -3},
+		{1000, "1", "0", 0},
+		{math.MaxInt64, "1.5", "3", math.MaxInt64/2 + 1},
+	}
+	for _, tt := range tests {
+		if got := Prorate(tt.amount, MustParse(tt.part), MustParse(tt.whole)); got != tt.want {
+			t.Errorf("Prorate(%d, %s, %s) = %d, want %d", tt.amount, tt.part, tt.whole, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,13 @@
+package dto
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
+type BinRequest struct {
+	Zone  string `json:"zone" validate:"required,max=20"`
+	Aisle string `json:"aisle" validate:"required,max=20"`
+	Rack  string `json:"rack" validate:"required,max=20"`
+	Bin   string `json:"bin" validate:"required,max=20"`
+	// Capacity is in base units of whatever the bin holds; omit it for no
+	// limit.
+	Capacity *decimal.Decimal `json:"capacity" validate:"omitempty,gt=0"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type LabelRequest struct {
+	Symbology string `json:"symbology" validate:"omitempty,oneof=code128 ean13 qr"`
+	Format    string `json:"format" validate:"omitempty,oneof=png svg"`
+	Scale     int    `json:"scale" validate:"omitempty,gte=1,lte=20"`
+}
+
+type LabelSheetRequest struct {
+	ProductIDs []int64 `json:"product_ids" validate:"required,min=1,max=500,dive,gt=0"`
+	Symbology  string  `json:"symbology" validate:"omitempty,oneof=code128 ean13 qr"`
+	Format     string  `json:"format" validate:"omitempty,oneof=pdf zpl"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type ReorderProductImagesRequest struct {
+	ImageIDs []int64 `json:"image_ids" validate:"required,min=1,dive,gt=0"`
+}
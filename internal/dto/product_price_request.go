@@ -0,0 +1,12 @@
+package dto
+
+import "time"
+
+// ProductPriceRequest schedules a price change. Amounts are in minor units;
+// effective_from defaults to now and may lie in the past or the future.
+type ProductPriceRequest struct {
+	Price         int64      `json:"price" validate:"gte=0"`
+	Cost          int64      `json:"cost" validate:"gte=0"`
+	Currency      string     `json:"currency" validate:"omitempty,iso4217"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+}
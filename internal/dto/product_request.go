@@ -1,17 +1,33 @@
 package dto
 
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
 type CreateProductRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	ImageURL    string `json:"image_url" validate:"required"`
-	CategoryID  int    `json:"category_id" validate:"required"`
-	Stock       string `json:"stock" validate:"required"`
+	SKU         string          `json:"sku" validate:"omitempty,max=64"`
+	Barcode     string          `json:"barcode" validate:"omitempty,gtin"`
+	Name        string          `json:"name" validate:"required"`
+	Description string          `json:"description" validate:"required"`
+	ImageURL    string          `json:"image_url" validate:"omitempty,url"`
+	CategoryID  int             `json:"category_id" validate:"required,gt=0"`
+	BaseUnit    string          `json:"base_unit" validate:"omitempty,max=20"`
+	Precision   int             `json:"quantity_precision" validate:"gte=0,lte=3"`
+	Stock       decimal.Decimal `json:"stock" validate:"gte=0"`
+	Price       int64           `json:"price" validate:"gte=0"`
+	Cost        int64           `json:"cost" validate:"gte=0"`
+	Currency    string          `json:"currency" validate:"omitempty,iso4217"`
 }
 
 type UpdateProductRequest struct {
-	Name        string `json:"name" validate:"required"`
-	Description string `json:"description" validate:"required"`
-	ImageURL    string `json:"image_url" validate:"required"`
-	CategoryID  int    `json:"category_id" validate:"required"`
-	Stock       string `json:"stock" validate:"required"`
+	SKU         string          `json:"sku" validate:"omitempty,max=64"`
+	Barcode     string          `json:"barcode" validate:"omitempty,gtin"`
+	Name        string          `json:"name" validate:"required"`
+	Description string          `json:"description" validate:"required"`
+	ImageURL    string          `json:"image_url" validate:"omitempty,url"`
+	CategoryID  int             `json:"category_id" validate:"required,gt=0"`
+	BaseUnit    string          `json:"base_unit" validate:"omitempty,max=20"`
+	Precision   int             `json:"quantity_precision" validate:"gte=0,lte=3"`
+	Stock       decimal.Decimal `json:"stock" validate:"gte=0"`
+	Price       int64           `json:"price" validate:"gte=0"`
+	Cost        int64           `json:"cost" validate:"gte=0"`
+	Currency    string          `json:"currency" validate:"omitempty,iso4217"`
 }
@@ -0,0 +1,6 @@
+package dto
+
+type CreateProductUnitRequest struct {
+	Name   string `json:"name" validate:"required,max=20"`
+	Factor int64  `json:"factor" validate:"required,gt=1"`
+}
@@ -0,0 +1,9 @@
+package dto
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
+type ProductVariantRequest struct {
+	SKU     string            `json:"sku" validate:"omitempty,max=64"`
+	Options map[string]string `json:"options" validate:"required,min=1,dive,keys,required,max=50,endkeys,required,max=100"`
+	Stock   decimal.Decimal   `json:"stock" validate:"gte=0"`
+}
@@ -1,12 +1,57 @@
 package dto
 
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
 type TransactionItemRequest struct {
-	ProductID int64 `json:"product_id" validate:"required"`
-	Quantity  int64 `json:"quantity" validate:"required,gt=0"`
+	ProductID int64           `json:"product_id" validate:"required_without_all=SKU Barcode"`
+	VariantID int64           `json:"variant_id" validate:"omitempty,gt=0"`
+	SKU       string          `json:"sku" validate:"omitempty,max=64"`
+	Barcode   string          `json:"barcode" validate:"omitempty,gtin"`
+	Quantity  decimal.Decimal `json:"quantity" validate:"required_without=Counted,omitempty,gt=0"`
+	Unit      string          `json:"unit" validate:"omitempty,max=20"`
+	// Counted sets the stock of a count_correction ADJUST item instead of
+	// moving it by Quantity.
+	Counted *decimal.Decimal `json:"counted" validate:"omitempty,gte=0"`
+	// UnitCost (IN) and UnitPrice (OUT) are per unit of the item, in minor
+	// units of the product's currency. They default to the product's
+	// current cost or price.
+	UnitCost  *int64 `json:"unit_cost" validate:"omitempty,gte=0"`
+	UnitPrice *int64 `json:"unit_price" validate:"omitempty,gte=0"`
+	// BinID is the bin to put an IN item away to or pick an OUT item from.
+	// Without it an IN item is put away to a bin with room and an OUT item
+	// is picked along the suggested pick list.
+	BinID int64 `json:"bin_id" validate:"omitempty,gt=0"`
 }
 
 type CreateTransactionRequest struct {
-	TransactionType string                   `json:"transaction_type" validate:"required,oneof=IN OUT"`
+	TransactionType string `json:"transaction_type" validate:"required,oneof=IN OUT ADJUST TRANSFER"`
+	// WarehouseID is where the stock of every item moves, or for TRANSFER
+	// where it moves from.
+	WarehouseID int64 `json:"warehouse_id" validate:"required,gt=0"`
+	// DestinationWarehouseID is where a TRANSFER moves stock to. With
+	// InTransit the stock only arrives there once the transfer is received.
+	DestinationWarehouseID int64 `json:"destination_warehouse_id" validate:"omitempty,gt=0"`
+	InTransit              bool  `json:"in_transit"`
+	// Reason applies to ADJUST, which requires it.
+	Reason string `json:"reason" validate:"omitempty,oneof=damaged lost found count_correction"`
+	// ReferenceNumber, such as a delivery note or order number, is unique
+	// per transaction type.
+	ReferenceNumber string                   `json:"reference_number" validate:"omitempty,max=64"`
+	Counterparty    string                   `json:"counterparty" validate:"omitempty,max=255"`
+	Notes           string                   `json:"notes" validate:"omitempty,max=1000"`
 	UserID          int64                    `json:"-"`
+	Role            string                   `json:"-"`
 	Items           []TransactionItemRequest `json:"items" validate:"required,dive"`
 }
+
+// ReceiveTransferRequest lists what arrived of a transfer in transit. Items
+// left out arrived as dispatched.
+type ReceiveTransferRequest struct {
+	Items []ReceivedItemRequest `json:"items" validate:"dive"`
+}
+
+type ReceivedItemRequest struct {
+	ItemID int64 `json:"item_id" validate:"required,gt=0"`
+	// ReceivedQuantity is in the product's base unit.
+	ReceivedQuantity decimal.Decimal `json:"received_quantity" validate:"gte=0"`
+}
@@ -0,0 +1,8 @@
+package dto
+
+type WarehouseRequest struct {
+	Code      string `json:"code" validate:"required,max=20"`
+	Name      string `json:"name" validate:"required,max=100"`
+	Address   string `json:"address" validate:"omitempty,max=1000"`
+	IsDefault bool   `json:"is_default"`
+}
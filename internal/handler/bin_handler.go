@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type BinHandler struct {
+	binService *service.BinService
+}
+
+func NewBinHandler(s *service.BinService) *BinHandler {
+	return &BinHandler{binService: s}
+}
+
+func (h *BinHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	var req dto.BinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return
+	}
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	bin, err := h.binService.Create(r.Context(), &model.Bin{
+		WarehouseID: warehouseID,
+		Zone:        req.Zone,
+		Aisle:       req.Aisle,
+		Rack:        req.Rack,
+		Bin:         req.Bin,
+		Capacity:    req.Capacity,
+	})
+	if err != nil {
+		h.writeError(w, err, "Warehouse not found", "Failed to create bin")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{ResponseCode: "00", Message: "Bin created successfully", Data: bin})
+}
+
+// HandleGetAll lists the bins of a warehouse in the order a picker walks
+// them, with how much each holds.
+func (h *BinHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	bins, err := h.binService.List(r.Context(), warehouseID)
+	if err != nil {
+		h.writeError(w, err, "Warehouse not found", "Failed to get bins")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: bins})
+}
+
+// HandleGetByID returns a bin with the products and variants it holds.
+func (h *BinHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	id, ok := parseIDParam(w, r, "binId", "Invalid bin ID")
+	if !ok {
+		return
+	}
+
+	bin, err := h.binService.GetByID(r.Context(), warehouseID, id)
+	if err != nil {
+		h.writeError(w, err, "Bin not found", "Failed to get bin")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: bin})
+}
+
+// HandlePickList suggests the bins to pick ?quantity= of ?product_id= and
+// optionally ?variant_id= from at a warehouse.
+func (h *BinHandler) HandlePickList(w http.ResponseWriter, r *http.Request) {
+	warehouseID, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	errs := map[string]string{}
+	var productID, variantID int64
+	for name, dst := range map[string]*int64{"product_id": &productID, "variant_id": &variantID} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			errs[name] = name + " must be a positive integer"
+		}
+		*dst = id
+	}
+	if productID == 0 && errs["product_id"] == "" {
+		errs["product_id"] = "product_id is required"
+	}
+	quantity, err := decimal.Parse(q.Get("quantity"))
+	if err != nil || !quantity.IsPositive() {
+		errs["quantity"] = "quantity must be a positive number"
+	}
+	if len(errs) > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Validation failed", Errors: errs})
+		return
+	}
+
+	picks, err := h.binService.PickList(r.Context(), warehouseID, productID, variantID, quantity)
+	if err != nil {
+		h.writeError(w, err, "Warehouse not found", "Failed to get pick list")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: picks})
+}
+
+func (h *BinHandler) writeError(w http.ResponseWriter, err error, notFound, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: notFound})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
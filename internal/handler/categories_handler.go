@@ -2,6 +2,8 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 
@@ -131,6 +133,7 @@ func (h *CategoriesHandler) HandleGetByID(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	setETag(w, category.Version)
 	utils.WriteJSON(w, http.StatusOK, model.Response{
 		ResponseCode: "00",
 		Message:      "Success",
@@ -177,26 +180,92 @@ func (h *CategoriesHandler) HandleUpdate(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	category := &model.Categories{
 		ID:          id,
 		Name:        req.Name,
 		Description: req.Description,
+		Version:     version,
 	}
 
 	if err := h.categoriesService.UpdateCategory(r.Context(), category); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
-			ResponseCode: "01",
-			Message:      "Failed to update category",
-		})
+		h.writeWriteError(w, err, "Failed to update category")
 		return
 	}
 
+	setETag(w, category.Version)
 	utils.WriteJSON(w, http.StatusOK, model.Response{
 		ResponseCode: "00",
 		Message:      "Category updated successfully",
 	})
 }
 
+// HandlePatch applies an RFC 7396 merge patch to a category.
+func (h *CategoriesHandler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid category ID")
+	if !ok {
+		return
+	}
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.categoriesService.GetByID(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get category"})
+		return
+	}
+	if existing == nil {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Category not found"})
+		return
+	}
+
+	req := dto.UpdateCategoryRequest{Name: existing.Name, Description: existing.Description}
+	if !applyMergePatch(w, patch, &req) {
+		return
+	}
+
+	// Without If-Match the patch still only applies to the category it was
+	// merged into, so a concurrent change is refused rather than reverted.
+	if version == 0 {
+		version = existing.Version
+	}
+	category := &model.Categories{
+		ID:          id,
+		Name:        req.Name,
+		Description: req.Description,
+		Version:     version,
+	}
+	if err := h.categoriesService.PatchCategory(r.Context(), category); err != nil {
+		h.writeWriteError(w, err, "Failed to update category")
+		return
+	}
+
+	updated, err := h.categoriesService.GetByID(r.Context(), id)
+	if err != nil || updated == nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get category"})
+		return
+	}
+
+	setETag(w, updated.Version)
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "Category updated successfully",
+		Data:         updated,
+	})
+}
+
 func (h *CategoriesHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		utils.WriteJSON(w, http.StatusMethodNotAllowed, model.Response{
@@ -217,11 +286,13 @@ func (h *CategoriesHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.categoriesService.DeleteCategory(r.Context(), id); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
-			ResponseCode: "01",
-			Message:      "Failed to delete category",
-		})
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.categoriesService.DeleteCategory(r.Context(), id, version); err != nil {
+		h.writeWriteError(w, err, "Failed to delete category")
 		return
 	}
 
@@ -230,3 +301,16 @@ func (h *CategoriesHandler) HandleDelete(w http.ResponseWriter, r *http.Request)
 		Message:      "Category deleted successfully",
 	})
 }
+
+// writeWriteError maps errors from versioned writes to responses.
+func (h *CategoriesHandler) writeWriteError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Category not found"})
+	case errors.Is(err, service.ErrVersionMismatch):
+		writePreconditionFailed(w)
+	default:
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+// writeValidationError responds with 400 and the offending fields when err
+// is a service.ValidationError. It reports whether a response was written.
+func writeValidationError(w http.ResponseWriter, err error) bool {
+	var vErr *service.ValidationError
+	if !errors.As(err, &vErr) {
+		return false
+	}
+
+	utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+		ResponseCode: "01",
+		Message:      "Validation failed",
+		Errors:       vErr.Fields,
+	})
+	return true
+}
+
+// parseIDParam reads a positive integer route variable, writing a 400 with
+// message when it is missing or malformed.
+func parseIDParam(w http.ResponseWriter, r *http.Request, name, message string) (int64, bool) {
+	id, err := strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	if err != nil || id <= 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      message,
+		})
+		return 0, false
+	}
+	return id, true
+}
+
+// parseAsOf reads a point in time given as RFC 3339 or as a date, which
+// means the end of that day in UTC.
+func parseAsOf(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	day, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return day.Add(24*time.Hour - time.Second), nil
+}
+
+// parseFrom reads the start of a time range given as RFC 3339 or as a
+// date, which means the start of that day in UTC.
+func parseFrom(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// setETag exposes a resource version as a strong entity tag.
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", `"`+strconv.Itoa(version)+`"`)
+}
+
+// parseIfMatch returns the version named by the If-Match header, or 0 when
+// the header is absent or "*". A tag that cannot be a version never
+// matches, so it gets a 412 straight away.
+func parseIfMatch(w http.ResponseWriter, r *http.Request) (int, bool) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return 0, true
+	}
+
+	tag := strings.Trim(strings.TrimPrefix(value, "W/"), `"`)
+	version, err := strconv.Atoi(tag)
+	if err != nil || version <= 0 {
+		writePreconditionFailed(w)
+		return 0, false
+	}
+	return version, true
+}
+
+func writePreconditionFailed(w http.ResponseWriter) {
+	utils.WriteJSON(w, http.StatusPreconditionFailed, model.Response{
+		ResponseCode: "01",
+		Message:      "Resource was modified by someone else; fetch it again and retry",
+	})
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type LabelHandler struct {
+	labelService *service.LabelService
+}
+
+func NewLabelHandler(s *service.LabelService) *LabelHandler {
+	return &LabelHandler{labelService: s}
+}
+
+func (h *LabelHandler) HandleProductLabel(w http.ResponseWriter, r *http.Request) {
+	idParam := mux.Vars(r)["id"]
+	id, err := strconv.ParseInt(idParam, 10, 64)
+	if err != nil || id <= 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Invalid product ID"})
+		return
+	}
+
+	query := r.URL.Query()
+	req := dto.LabelRequest{
+		Symbology: query.Get("symbology"),
+		Format:    query.Get("format"),
+	}
+	if scale := query.Get("scale"); scale != "" {
+		req.Scale, err = strconv.Atoi(scale)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+				ResponseCode: "01",
+				Message:      "Validation failed",
+				Errors:       map[string]string{"scale": "scale must be a number"},
+			})
+			return
+		}
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	body, contentType, err := h.labelService.RenderLabel(r.Context(), id, req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (h *LabelHandler) HandleLabelSheet(w http.ResponseWriter, r *http.Request) {
+	var req dto.LabelSheetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	body, contentType, err := h.labelService.RenderSheet(r.Context(), req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	filename := "labels.pdf"
+	if req.Format == "zpl" {
+		filename = "labels.zpl"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+func (h *LabelHandler) writeError(w http.ResponseWriter, err error) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to render label"})
+}
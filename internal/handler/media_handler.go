@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/storage"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+// MediaHandler serves stored blobs at /media/{key} from any BlobStore, so
+// the bucket behind an S3 store never has to be public.
+type MediaHandler struct {
+	store storage.BlobStore
+}
+
+func NewMediaHandler(store storage.BlobStore) *MediaHandler {
+	return &MediaHandler{store: store}
+}
+
+func (h *MediaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/media/")
+	blob, err := h.store.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotExist) || errors.Is(err, storage.ErrInvalidKey) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "File not found"})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get file"})
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if rs, ok := blob.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, key, time.Time{}, rs)
+		return
+	}
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Println(err)
+	}
+}
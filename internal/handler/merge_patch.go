@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const maxPatchBytes = 1 << 20
+
+// readMergePatch reads an RFC 7396 merge patch from the request body. Both
+// application/merge-patch+json and plain application/json are accepted.
+func readMergePatch(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		mediaType, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+			utils.WriteJSON(w, http.StatusUnsupportedMediaType, model.Response{
+				ResponseCode: "01",
+				Message:      "Content-Type must be application/merge-patch+json",
+			})
+			return nil, false
+		}
+	}
+
+	patch, err := io.ReadAll(io.LimitReader(r.Body, maxPatchBytes+1))
+	if err != nil || len(patch) > maxPatchBytes {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+		})
+		return nil, false
+	}
+	return patch, true
+}
+
+// applyMergePatch merges patch into the request struct dst points to and
+// validates only the fields the patch touched. It writes a 400 and returns
+// false when either step fails.
+func applyMergePatch(w http.ResponseWriter, patch []byte, dst any) bool {
+	fields, err := utils.ApplyMergePatch(dst, patch)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       patchErrors(err),
+		})
+		return false
+	}
+
+	if len(fields) == 0 {
+		return true
+	}
+	if err := utils.NewValidator().StructPartial(dst, fields...); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return false
+	}
+	return true
+}
+
+// patchErrors reports why a merge patch could not be applied, by field
+// where possible.
+func patchErrors(err error) map[string]string {
+	if errs := utils.FormatDecodeError(err); errs != nil {
+		return errs
+	}
+	return map[string]string{"reason": err.Error()}
+}
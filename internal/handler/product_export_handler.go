@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/spreadsheet"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const formatNDJSON = "ndjson"
+
+var exportColumns = []string{
+	"id", "sku", "barcode", "name", "description", "category_id", "category_name", "base_unit",
+	"quantity_precision", "stock", "price", "cost", "currency", "image_url", "archived_at", "version",
+}
+
+// exportNumericColumns are the indexes in exportColumns stored as numbers
+// in XLSX.
+var exportNumericColumns = []int{0, 5, 8, 9, 10, 11, 15}
+
+var exportContentTypes = map[string]string{
+	spreadsheet.FormatCSV:  "text/csv; charset=utf-8",
+	spreadsheet.FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	formatNDJSON:           "application/x-ndjson",
+}
+
+// HandleExport streams the catalog as ?format=csv (default), xlsx or
+// ndjson. It takes the same filters as the product listing. Rows are
+// written as they are read, so an error part way through can only cut the
+// file short; it is logged.
+func (h *ProductHandler) HandleExport(w http.ResponseWriter, r *http.Request) {
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = spreadsheet.FormatCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"format": "format must be csv, xlsx or ndjson"},
+		})
+		return
+	}
+
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+
+	filename := "products-" + time.Now().UTC().Format("20060102") + "." + format
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := bufio.NewWriter(w)
+	var err error
+	if format == formatNDJSON {
+		enc := json.NewEncoder(out)
+		err = h.productService.Export(r.Context(), includeArchived, func(p *model.ProductExport) error {
+			return enc.Encode(p)
+		})
+	} else {
+		err = h.exportSheet(r, out, format, includeArchived)
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (h *ProductHandler) exportSheet(r *http.Request, out *bufio.Writer, format string, includeArchived bool) error {
+	sheet, err := spreadsheet.NewWriter(format, out, exportNumericColumns...)
+	if err != nil {
+		return err
+	}
+	if err := sheet.Write(exportColumns); err != nil {
+		return err
+	}
+
+	err = h.productService.Export(r.Context(), includeArchived, func(p *model.ProductExport) error {
+		return sheet.Write(exportRecord(p))
+	})
+	if err != nil {
+		return err
+	}
+	return sheet.Close()
+}
+
+// exportRecord lays out p in the order of exportColumns.
+func exportRecord(p *model.ProductExport) []string {
+	archivedAt := ""
+	if p.ArchivedAt != nil {
+		archivedAt = p.ArchivedAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		strconv.FormatInt(p.ID, 10),
+		p.SKU,
+		p.Barcode,
+		p.Name,
+		p.Description,
+		strconv.Itoa(p.CategoryID),
+		p.CategoryName,
+		p.BaseUnit,
+		strconv.Itoa(p.Precision),
+		p.Stock.String(),
+		strconv.FormatInt(p.Price, 10),
+		strconv.FormatInt(p.Cost, 10),
+		p.Currency,
+		p.ImageURL,
+		archivedAt,
+		strconv.Itoa(p.Version),
+	}
+}
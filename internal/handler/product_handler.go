@@ -2,11 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
 
-	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
@@ -29,28 +29,39 @@ func (h *ProductHandler) HandleInsert(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
 		})
 		return
 	}
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := utils.NewValidator().Struct(req); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Validation failed",
-			Errors:       utils.FormatValidationErrors(err),
+			Errors:       utils.FormatFieldErrors(err),
 		})
 		return
 	}
 
 	p := &model.Product{
+		SKU:         req.SKU,
+		Barcode:     req.Barcode,
 		Name:        req.Name,
 		Description: req.Description,
 		ImageURL:    req.ImageURL,
 		CategoryID:  req.CategoryID,
+		BaseUnit:    req.BaseUnit,
+		Precision:   req.Precision,
 		Stock:       req.Stock,
+		Price:       req.Price,
+		Cost:        req.Cost,
+		Currency:    req.Currency,
 	}
 
 	if err := h.productService.Insert(r.Context(), p); err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
 		log.Println(err)
 		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
 			ResponseCode: "01",
@@ -71,7 +82,9 @@ func (h *ProductHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data, err := h.productService.GetAll(r.Context())
+	includeArchived, _ := strconv.ParseBool(r.URL.Query().Get("include_archived"))
+
+	data, err := h.productService.GetAll(r.Context(), includeArchived)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
 			ResponseCode: "01",
@@ -93,6 +106,11 @@ func (h *ProductHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		h.writeRevisionAsOf(w, r, id, v)
+		return
+	}
+
 	product, err := h.productService.GetByID(r.Context(), id)
 	if err != nil {
 		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get product"})
@@ -103,6 +121,73 @@ func (h *ProductHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	setETag(w, product.Version)
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: product})
+}
+
+// writeRevisionAsOf responds with the revision of a product that was in
+// effect at the given time.
+func (h *ProductHandler) writeRevisionAsOf(w http.ResponseWriter, r *http.Request, id int64, value string) {
+	asOf, err := parseAsOf(value)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"as_of": "as_of must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"},
+		})
+		return
+	}
+
+	revision, err := h.productService.GetAsOf(r.Context(), id, asOf)
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get product"})
+		return
+	}
+	if revision == nil {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found at that time"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: revision})
+}
+
+func (h *ProductHandler) HandleHistory(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	revisions, err := h.productService.History(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get product history"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: revisions})
+}
+
+func (h *ProductHandler) HandleGetByBarcode(w http.ResponseWriter, r *http.Request) {
+	code := mux.Vars(r)["code"]
+
+	product, err := h.productService.GetByBarcode(r.Context(), code)
+	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get product"})
+		return
+	}
+	if product == nil {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: product})
 }
 
@@ -138,42 +223,110 @@ func (h *ProductHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
 		})
 		return
 	}
 
-	if err := validator.New().Struct(req); err != nil {
+	if err := utils.NewValidator().Struct(req); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Validation failed",
-			Errors:       utils.FormatValidationErrors(err),
+			Errors:       utils.FormatFieldErrors(err),
 		})
 		return
 	}
 
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
 	p := &model.Product{
 		ID:          id,
+		Version:     version,
+		SKU:         req.SKU,
+		Barcode:     req.Barcode,
 		Name:        req.Name,
 		Description: req.Description,
 		ImageURL:    req.ImageURL,
 		CategoryID:  req.CategoryID,
+		BaseUnit:    req.BaseUnit,
+		Precision:   req.Precision,
 		Stock:       req.Stock,
+		Price:       req.Price,
+		Cost:        req.Cost,
+		Currency:    req.Currency,
 	}
 
 	if err := h.productService.Update(r.Context(), p); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
-			ResponseCode: "01",
-			Message:      "Failed to update product",
-		})
+		h.writeWriteError(w, err, "Failed to update product")
 		return
 	}
 
+	setETag(w, p.Version)
 	utils.WriteJSON(w, http.StatusOK, model.Response{
 		ResponseCode: "00",
 		Message:      "Product updated successfully",
 	})
 }
 
+// HandlePatch applies an RFC 7396 merge patch, so only the fields present
+// in the body change and null clears an optional field.
+func (h *ProductHandler) HandlePatch(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.productService.GetByID(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to check product"})
+		return
+	}
+	if existing == nil {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+		return
+	}
+
+	req := productRequest(existing)
+	if !applyMergePatch(w, patch, &req) {
+		return
+	}
+
+	// Without If-Match the patch still only applies to the product it was
+	// merged into, so a concurrent change is refused rather than reverted.
+	if version == 0 {
+		version = existing.Version
+	}
+	p := productFromRequest(req)
+	p.ID = id
+	p.Version = version
+	if err := h.productService.Patch(r.Context(), p); err != nil {
+		h.writeWriteError(w, err, "Failed to update product")
+		return
+	}
+
+	product, err := h.productService.GetByID(r.Context(), id)
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get product"})
+		return
+	}
+
+	setETag(w, product.Version)
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Product updated successfully", Data: product})
+}
+
 func (h *ProductHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 	idParam := mux.Vars(r)["id"]
 	id, err := strconv.ParseInt(idParam, 10, 64)
@@ -201,16 +354,106 @@ func (h *ProductHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.productService.Delete(r.Context(), id); err != nil {
-		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
-			ResponseCode: "01",
-			Message:      "Failed to delete product",
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	// Products are archived unless a permanent delete is asked for, which
+	// only succeeds for products without transaction history.
+	if permanent, _ := strconv.ParseBool(r.URL.Query().Get("permanent")); permanent {
+		if err := h.productService.Delete(r.Context(), id, version); err != nil {
+			h.writeWriteError(w, err, "Failed to delete product")
+			return
+		}
+
+		utils.WriteJSON(w, http.StatusOK, model.Response{
+			ResponseCode: "00",
+			Message:      "Product deleted successfully",
 		})
 		return
 	}
 
+	if err := h.productService.Archive(r.Context(), id, version); err != nil {
+		h.writeWriteError(w, err, "Failed to archive product")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "Product archived successfully",
+	})
+}
+
+func (h *ProductHandler) HandleUnarchive(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	version, ok := parseIfMatch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.productService.Unarchive(r.Context(), id, version); err != nil {
+		h.writeWriteError(w, err, "Failed to unarchive product")
+		return
+	}
+
 	utils.WriteJSON(w, http.StatusOK, model.Response{
 		ResponseCode: "00",
-		Message:      "Product deleted successfully",
+		Message:      "Product unarchived successfully",
 	})
 }
+
+// writeWriteError maps errors from versioned writes to responses.
+func (h *ProductHandler) writeWriteError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+	case errors.Is(err, service.ErrVersionMismatch):
+		writePreconditionFailed(w)
+	default:
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+	}
+}
+
+// productRequest returns the request that would recreate p as it is.
+func productRequest(p *model.Product) dto.UpdateProductRequest {
+	return dto.UpdateProductRequest{
+		SKU:         p.SKU,
+		Barcode:     p.Barcode,
+		Name:        p.Name,
+		Description: p.Description,
+		ImageURL:    p.ImageURL,
+		CategoryID:  p.CategoryID,
+		BaseUnit:    p.BaseUnit,
+		Precision:   p.Precision,
+		Stock:       p.Stock,
+		Price:       p.Price,
+		Cost:        p.Cost,
+		Currency:    p.Currency,
+	}
+}
+
+func productFromRequest(req dto.UpdateProductRequest) *model.Product {
+	return &model.Product{
+		SKU:         req.SKU,
+		Barcode:     req.Barcode,
+		Name:        req.Name,
+		Description: req.Description,
+		ImageURL:    req.ImageURL,
+		CategoryID:  req.CategoryID,
+		BaseUnit:    req.BaseUnit,
+		Precision:   req.Precision,
+		Stock:       req.Stock,
+		Price:       req.Price,
+		Cost:        req.Cost,
+		Currency:    req.Currency,
+	}
+}
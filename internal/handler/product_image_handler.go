@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const (
+	maxImagesPerUpload = 10
+	maxUploadBytes     = maxImagesPerUpload*service.MaxImageBytes + 1<<20
+)
+
+type ProductImageHandler struct {
+	imageService *service.ProductImageService
+}
+
+func NewProductImageHandler(s *service.ProductImageService) *ProductImageHandler {
+	return &ProductImageHandler{imageService: s}
+}
+
+// HandleUpload accepts one or more files in the "image" form field.
+func (h *ProductImageHandler) HandleUpload(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid multipart body or upload too large",
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["image"]
+	if len(files) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"image": "image is required"},
+		})
+		return
+	}
+	if len(files) > maxImagesPerUpload {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"image": "at most " + strconv.Itoa(maxImagesPerUpload) + " images per upload"},
+		})
+		return
+	}
+
+	var uploaded []*model.ProductImage
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Failed to read uploaded file"})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(f, service.MaxImageBytes+1))
+		f.Close()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Failed to read uploaded file"})
+			return
+		}
+
+		img, err := h.imageService.Upload(r.Context(), productID, data)
+		if err != nil {
+			h.writeError(w, err, "Failed to upload image")
+			return
+		}
+		uploaded = append(uploaded, img)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{
+		ResponseCode: "00",
+		Message:      "Images uploaded successfully",
+		Data:         uploaded,
+	})
+}
+
+func (h *ProductImageHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	images, err := h.imageService.List(r.Context(), productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to get images")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: images})
+}
+
+func (h *ProductImageHandler) HandleReorder(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req dto.ReorderProductImagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	images, err := h.imageService.Reorder(r.Context(), productID, req.ImageIDs)
+	if err != nil {
+		h.writeError(w, err, "Failed to reorder images")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Images reordered successfully", Data: images})
+}
+
+func (h *ProductImageHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	imageID, ok := parseIDParam(w, r, "imageId", "Invalid image ID")
+	if !ok {
+		return
+	}
+
+	if err := h.imageService.Delete(r.Context(), productID, imageID); err != nil {
+		h.writeError(w, err, "Failed to delete image")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Image deleted successfully"})
+}
+
+func (h *ProductImageHandler) writeError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
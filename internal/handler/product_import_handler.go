@@ -0,0 +1,312 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/spreadsheet"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const (
+	maxImportBytes        = 50 << 20
+	maxInlineImportErrors = 100
+)
+
+type ProductImportHandler struct {
+	productService *service.ProductService
+	importService  *service.ProductImportService
+}
+
+func NewProductImportHandler(productService *service.ProductService, importService *service.ProductImportService) *ProductImportHandler {
+	return &ProductImportHandler{productService: productService, importService: importService}
+}
+
+// HandleImport creates or updates products, matched by SKU, from the CSV
+// or XLSX file in the "file" form field. The first row holds column names;
+// "mapping" maps product fields to them as a JSON object, otherwise columns
+// named like the fields are used. Empty cells leave a field unchanged. With
+// dry_run=true every row is validated but nothing is saved.
+func (h *ProductImportHandler) HandleImport(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid multipart body or upload too large",
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.writeInvalid(w, "file", "file is required")
+		return
+	}
+	defer file.Close()
+
+	format := strings.ToLower(r.FormValue("format"))
+	if format == "" {
+		format = spreadsheet.FormatFromName(header.Filename)
+	}
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		h.writeInvalid(w, "format", "format must be csv or xlsx")
+		return
+	}
+
+	dryRun, err := parseOptionalBool(r.FormValue("dry_run"))
+	if err != nil {
+		h.writeInvalid(w, "dry_run", "dry_run must be true or false")
+		return
+	}
+
+	mapping := map[string]string{}
+	if v := r.FormValue("mapping"); v != "" {
+		if err := json.Unmarshal([]byte(v), &mapping); err != nil {
+			h.writeInvalid(w, "mapping", "mapping must be a JSON object of field names to column names")
+			return
+		}
+	}
+
+	rows, err := spreadsheet.NewReader(format, file, header.Size)
+	if err != nil {
+		h.writeInvalid(w, "file", err.Error())
+		return
+	}
+	headerRow, err := rows.Read()
+	if err != nil {
+		message := "file has no header row"
+		if err != io.EOF {
+			message = err.Error()
+		}
+		h.writeInvalid(w, "file", message)
+		return
+	}
+	columns, errs := importColumns(headerRow, mapping)
+	if errs != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Validation failed", Errors: errs})
+		return
+	}
+
+	result := &model.ImportResult{DryRun: dryRun, Errors: []model.ImportRowError{}}
+	seen := map[string]int{}
+	seenBarcodes := map[string]int{}
+	for {
+		record, err := rows.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			h.writeInvalid(w, "file", err.Error())
+			return
+		}
+
+		result.Rows++
+		row := rows.Row()
+		values := rowValues(columns, record)
+		sku := values["sku"]
+
+		var (
+			action  string
+			rowErrs map[string]string
+		)
+		barcode := importBarcode(values["barcode"])
+		firstBarcode, dupBarcode := seenBarcodes[barcode]
+		switch first, dup := seen[sku]; {
+		case sku == "":
+			rowErrs = map[string]string{"sku": "sku is required"}
+		case dup:
+			rowErrs = map[string]string{"sku": fmt.Sprintf("sku %s already appears in row %d", sku, first)}
+		case barcode != "" && dupBarcode:
+			rowErrs = map[string]string{"barcode": fmt.Sprintf("barcode %s already appears in row %d", barcode, firstBarcode)}
+		default:
+			seen[sku] = row
+			if barcode != "" {
+				seenBarcodes[barcode] = row
+			}
+			action, rowErrs, err = h.importRow(r.Context(), values, dryRun)
+		}
+		if err != nil {
+			log.Println(err)
+			utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+				ResponseCode: "01",
+				Message:      fmt.Sprintf("Import stopped at row %d; earlier rows were saved", row),
+				Data:         result,
+			})
+			return
+		}
+
+		switch {
+		case rowErrs != nil:
+			result.Failed++
+			result.Errors = append(result.Errors, model.ImportRowError{Row: row, SKU: sku, Errors: rowErrs})
+		case action == model.ImportCreated:
+			result.Created++
+		case action == model.ImportUpdated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		url, err := h.importService.SaveErrorReport(r.Context(), result.Errors)
+		if err != nil {
+			log.Println(err)
+		}
+		result.ErrorReportURL = url
+		if len(result.Errors) > maxInlineImportErrors {
+			result.Errors = result.Errors[:maxInlineImportErrors]
+		}
+	}
+
+	message := "Import completed"
+	if dryRun {
+		message = "Dry run completed, nothing was saved"
+	}
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: message, Data: result})
+}
+
+// importRow validates one row with the same rules as the create and patch
+// endpoints and saves it. Rejections come back as field errors; err is
+// only set when the import cannot go on.
+func (h *ProductImportHandler) importRow(ctx context.Context, values map[string]string, dryRun bool) (string, map[string]string, error) {
+	existing, err := h.productService.GetBySKU(ctx, values["sku"])
+	if err != nil {
+		return "", nil, err
+	}
+
+	var req dto.UpdateProductRequest
+	patch, err := utils.TextMergePatch(&req, values)
+	if err != nil {
+		return "", nil, err
+	}
+
+	validate := utils.NewValidator()
+	if existing == nil {
+		var create dto.CreateProductRequest
+		if _, err := utils.ApplyMergePatch(&create, patch); err != nil {
+			return "", patchErrors(err), nil
+		}
+		if err := validate.Struct(create); err != nil {
+			return "", utils.FormatFieldErrors(err), nil
+		}
+		req = dto.UpdateProductRequest(create)
+	} else {
+		req = productRequest(existing)
+		fields, err := utils.ApplyMergePatch(&req, patch)
+		if err != nil {
+			return "", patchErrors(err), nil
+		}
+		if err := validate.StructPartial(req, fields...); err != nil {
+			return "", utils.FormatFieldErrors(err), nil
+		}
+	}
+
+	p := productFromRequest(req)
+	if existing != nil {
+		p.ID = existing.ID
+	}
+	action, err := h.productService.Upsert(ctx, p, dryRun)
+	var validationErr *service.ValidationError
+	if errors.As(err, &validationErr) {
+		return "", validationErr.Fields, nil
+	}
+	return action, nil, err
+}
+
+// importBarcode is the form of a barcode cell compared between rows, the
+// normalized GTIN when it is one. Invalid barcodes fail validation anyway.
+func importBarcode(v string) string {
+	if normalized, err := utils.NormalizeGTIN(v); err == nil {
+		return normalized
+	}
+	return v
+}
+
+func (h *ProductImportHandler) writeInvalid(w http.ResponseWriter, field, message string) {
+	utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+		ResponseCode: "01",
+		Message:      "Validation failed",
+		Errors:       map[string]string{field: message},
+	})
+}
+
+// importColumns finds the column of each product field in the header row.
+// Fields named in mapping must be found; other fields are matched by their
+// own name, ignoring case. A column for sku is required.
+func importColumns(header []string, mapping map[string]string) (map[string]int, map[string]string) {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, ok := index[key]; !ok {
+			index[key] = i
+		}
+	}
+
+	fields := utils.JSONFieldNames(&dto.CreateProductRequest{})
+	known := make(map[string]bool, len(fields))
+	for _, field := range fields {
+		known[field] = true
+	}
+
+	errs := map[string]string{}
+	for field := range mapping {
+		if !known[field] {
+			errs["mapping."+field] = "unknown product field"
+		}
+	}
+
+	columns := map[string]int{}
+	for _, field := range fields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		col, ok := index[strings.ToLower(strings.TrimSpace(name))]
+		switch {
+		case ok:
+			columns[field] = col
+		case mapped:
+			errs["mapping."+field] = fmt.Sprintf("column %q not found", name)
+		}
+	}
+	if _, ok := columns["sku"]; !ok && errs["mapping.sku"] == "" {
+		errs["mapping.sku"] = "a column for sku is required"
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return columns, nil
+}
+
+func rowValues(columns map[string]int, record []string) map[string]string {
+	values := make(map[string]string, len(columns))
+	for field, col := range columns {
+		if col >= len(record) {
+			continue
+		}
+		if v := strings.TrimSpace(record[col]); v != "" {
+			values[field] = v
+		}
+	}
+	return values
+}
+
+func parseOptionalBool(v string) (bool, error) {
+	if v == "" {
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type ProductPriceHandler struct {
+	priceService *service.ProductPriceService
+}
+
+func NewProductPriceHandler(s *service.ProductPriceService) *ProductPriceHandler {
+	return &ProductPriceHandler{priceService: s}
+}
+
+func (h *ProductPriceHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req dto.ProductPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	price := &model.ProductPrice{
+		ProductID: productID,
+		Price:     req.Price,
+		Cost:      req.Cost,
+		Currency:  req.Currency,
+	}
+	if req.EffectiveFrom != nil {
+		price.EffectiveFrom = *req.EffectiveFrom
+	}
+
+	created, err := h.priceService.Create(r.Context(), price)
+	if err != nil {
+		h.writeError(w, err, "Failed to create price")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{ResponseCode: "00", Message: "Price created successfully", Data: created})
+}
+
+func (h *ProductPriceHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	prices, err := h.priceService.List(r.Context(), productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to get prices")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: prices})
+}
+
+func (h *ProductPriceHandler) writeError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Product not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type ProductUnitHandler struct {
+	unitService *service.ProductUnitService
+}
+
+func NewProductUnitHandler(s *service.ProductUnitService) *ProductUnitHandler {
+	return &ProductUnitHandler{unitService: s}
+}
+
+func (h *ProductUnitHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	var req dto.CreateProductUnitRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	unit, err := h.unitService.Create(r.Context(), &model.ProductUnit{
+		ProductID: productID,
+		Name:      req.Name,
+		Factor:    req.Factor,
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to create unit")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{ResponseCode: "00", Message: "Unit created successfully", Data: unit})
+}
+
+func (h *ProductUnitHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	units, err := h.unitService.List(r.Context(), productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to get units")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: units})
+}
+
+func (h *ProductUnitHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	unitID, ok := parseIDParam(w, r, "unitId", "Invalid unit ID")
+	if !ok {
+		return
+	}
+
+	if err := h.unitService.Delete(r.Context(), productID, unitID); err != nil {
+		h.writeError(w, err, "Failed to delete unit")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Unit deleted successfully"})
+}
+
+// HandleStock reports stock in the unit given by ?unit=, defaulting to the
+// product's base unit.
+func (h *ProductUnitHandler) HandleStock(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	stock, err := h.unitService.Stock(r.Context(), productID, r.URL.Query().Get("unit"))
+	if err != nil {
+		h.writeError(w, err, "Failed to get stock")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: stock})
+}
+
+func (h *ProductUnitHandler) writeError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
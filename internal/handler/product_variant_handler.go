@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type ProductVariantHandler struct {
+	variantService *service.ProductVariantService
+}
+
+func NewProductVariantHandler(s *service.ProductVariantService) *ProductVariantHandler {
+	return &ProductVariantHandler{variantService: s}
+}
+
+func (h *ProductVariantHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	req, ok := decodeVariantRequest(w, r)
+	if !ok {
+		return
+	}
+
+	variant, err := h.variantService.Create(r.Context(), &model.ProductVariant{
+		ProductID: productID,
+		SKU:       req.SKU,
+		Options:   req.Options,
+		Stock:     req.Stock,
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to create variant")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{ResponseCode: "00", Message: "Variant created successfully", Data: variant})
+}
+
+func (h *ProductVariantHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+
+	variants, err := h.variantService.List(r.Context(), productID)
+	if err != nil {
+		h.writeError(w, err, "Failed to get variants")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: variants})
+}
+
+func (h *ProductVariantHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	variantID, ok := parseIDParam(w, r, "variantId", "Invalid variant ID")
+	if !ok {
+		return
+	}
+
+	req, ok := decodeVariantRequest(w, r)
+	if !ok {
+		return
+	}
+
+	variant, err := h.variantService.Update(r.Context(), &model.ProductVariant{
+		ID:        variantID,
+		ProductID: productID,
+		SKU:       req.SKU,
+		Options:   req.Options,
+		Stock:     req.Stock,
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to update variant")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Variant updated successfully", Data: variant})
+}
+
+func (h *ProductVariantHandler) HandleDelete(w http.ResponseWriter, r *http.Request) {
+	productID, ok := parseIDParam(w, r, "id", "Invalid product ID")
+	if !ok {
+		return
+	}
+	variantID, ok := parseIDParam(w, r, "variantId", "Invalid variant ID")
+	if !ok {
+		return
+	}
+
+	if err := h.variantService.Delete(r.Context(), productID, variantID); err != nil {
+		h.writeError(w, err, "Failed to delete variant")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Variant deleted successfully"})
+}
+
+func decodeVariantRequest(w http.ResponseWriter, r *http.Request) (*dto.ProductVariantRequest, bool) {
+	var req dto.ProductVariantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return nil, false
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return nil, false
+	}
+	return &req, true
+}
+
+func (h *ProductVariantHandler) writeError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type ReportHandler struct {
+	valuationService *service.ValuationService
+}
+
+func NewReportHandler(s *service.ValuationService) *ReportHandler {
+	return &ReportHandler{valuationService: s}
+}
+
+// HandleValuation values stock on hand as of ?as_of=, given as RFC 3339 or
+// as a date meaning the end of that day (UTC). ?method= overrides the
+// configured costing method.
+func (h *ReportHandler) HandleValuation(w http.ResponseWriter, r *http.Request) {
+	asOf := time.Now().UTC()
+	if v := r.URL.Query().Get("as_of"); v != "" {
+		parsed, err := parseAsOf(v)
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+				ResponseCode: "01",
+				Message:      "Validation failed",
+				Errors:       map[string]string{"as_of": "as_of must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"},
+			})
+			return
+		}
+		asOf = parsed
+	}
+
+	method := strings.ToLower(r.URL.Query().Get("method"))
+	report, err := h.valuationService.Report(r.Context(), asOf, method)
+	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: "Failed to get valuation"})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: report})
+}
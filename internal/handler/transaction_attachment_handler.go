@@ -0,0 +1,179 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/middleware"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const (
+	maxAttachmentsPerUpload = 5
+	maxAttachmentUpload     = maxAttachmentsPerUpload*service.MaxAttachmentBytes + 1<<20
+)
+
+// HandleUploadAttachments accepts one or more files in the "file" form
+// field. Staff may only attach files to their own transactions.
+func (h *TransactionHandler) HandleUploadAttachments(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+	userID, owner, ok := transactionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxAttachmentUpload)
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid multipart body or upload too large",
+		})
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	files := r.MultipartForm.File["file"]
+	if len(files) == 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"file": "file is required"},
+		})
+		return
+	}
+	if len(files) > maxAttachmentsPerUpload {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"file": "at most " + strconv.Itoa(maxAttachmentsPerUpload) + " files per upload"},
+		})
+		return
+	}
+
+	var uploaded []*model.TransactionAttachment
+	for _, fh := range files {
+		f, err := fh.Open()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Failed to read uploaded file"})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(f, service.MaxAttachmentBytes+1))
+		f.Close()
+		if err != nil {
+			utils.WriteJSON(w, http.StatusBadRequest, model.Response{ResponseCode: "01", Message: "Failed to read uploaded file"})
+			return
+		}
+
+		a, err := h.attachmentService.Upload(r.Context(), id, owner, userID, fh.Filename, data)
+		if err != nil {
+			writeAttachmentError(w, err, "Failed to upload attachment")
+			return
+		}
+		uploaded = append(uploaded, a)
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{
+		ResponseCode: "00",
+		Message:      "Attachments uploaded successfully",
+		Data:         uploaded,
+	})
+}
+
+func (h *TransactionHandler) HandleListAttachments(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+	_, owner, ok := transactionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachmentService.List(r.Context(), id, owner)
+	if err != nil {
+		writeAttachmentError(w, err, "Failed to get attachments")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: attachments})
+}
+
+func (h *TransactionHandler) HandleDeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+	attachmentID, ok := parseIDParam(w, r, "attachmentId", "Invalid attachment ID")
+	if !ok {
+		return
+	}
+	_, owner, ok := transactionCaller(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.attachmentService.Delete(r.Context(), id, attachmentID, owner); err != nil {
+		writeAttachmentError(w, err, "Failed to delete attachment")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Attachment deleted successfully"})
+}
+
+// ServeAttachmentFile serves the stored files of a transaction's
+// attachments through files to callers who may see the transaction.
+func (h *TransactionHandler) ServeAttachmentFile(files http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+		if !ok {
+			return
+		}
+		_, owner, ok := transactionCaller(w, r)
+		if !ok {
+			return
+		}
+		if err := h.attachmentService.Authorize(r.Context(), id, owner); err != nil {
+			writeAttachmentError(w, err, "Failed to get attachment")
+			return
+		}
+		files.ServeHTTP(w, r)
+	}
+}
+
+// transactionCaller returns the calling user and the owner whose
+// transactions they may touch: themselves, or anyone (0) for managers and
+// admins. It writes a 401 when there is no user.
+func transactionCaller(w http.ResponseWriter, r *http.Request) (int64, int64, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return 0, 0, false
+	}
+	if canViewAllTransactions(r) {
+		return userID, 0, true
+	}
+	return userID, userID, true
+}
+
+func writeAttachmentError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
@@ -1,28 +1,56 @@
 package handler
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/middleware"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/spreadsheet"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
 )
 
 type TransactionHandler struct {
 	transactionService *service.TransactionService
+	attachmentService  *service.TransactionAttachmentService
 }
 
-func NewTransactionHandler(s *service.TransactionService) *TransactionHandler {
-	return &TransactionHandler{transactionService: s}
+func NewTransactionHandler(s *service.TransactionService, attachments *service.TransactionAttachmentService) *TransactionHandler {
+	return &TransactionHandler{transactionService: s, attachmentService: attachments}
 }
 
+const (
+	maxTransactionBodyBytes = 1 << 20
+	maxIdempotencyKeyLength = 255
+)
+
+// HandleCreate records a transaction. With an Idempotency-Key header a
+// retried request gets the original response back, marked with
+// Idempotent-Replayed, instead of recording the transaction twice.
 func (h *TransactionHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTransactionBodyBytes))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+		})
+		return
+	}
+
 	var req dto.CreateTransactionRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Invalid request body",
@@ -30,12 +58,11 @@ func (h *TransactionHandler) HandleCreate(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
+	if err := utils.NewValidator().Struct(req); err != nil {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
 			Message:      "Validation failed",
-			Errors:       utils.FormatValidationErrors(err),
+			Errors:       utils.FormatFieldErrors(err),
 		})
 		return
 	}
@@ -48,22 +75,340 @@ func (h *TransactionHandler) HandleCreate(w http.ResponseWriter, r *http.Request
 		})
 		return
 	}
-
 	req.UserID = userID
-	if err := h.transactionService.Create(r.Context(), &req); err != nil {
+	req.Role = middleware.GetRoleFromContext(r.Context())
+
+	if key := r.Header.Get("Idempotency-Key"); key != "" {
+		h.createIdempotent(w, r, &req, key, body)
+		return
+	}
+
+	transaction, err := h.transactionService.Create(r.Context(), &req)
+	if err != nil {
+		h.writeCreateError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, createdResponse(transaction))
+}
+
+func (h *TransactionHandler) createIdempotent(w http.ResponseWriter, r *http.Request, req *dto.CreateTransactionRequest, key string, body []byte) {
+	if len(key) > maxIdempotencyKeyLength {
 		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
 			ResponseCode: "01",
-			Message:      err.Error(),
+			Message:      "Validation failed",
+			Errors:       map[string]string{"Idempotency-Key": "Idempotency-Key must be at most 255 characters"},
+		})
+		return
+	}
+
+	// Formatting differences in the body do not make it a different request.
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		compact.Reset()
+		compact.Write(body)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+
+	rec, replayed, err := h.transactionService.CreateIdempotent(r.Context(), req, key, hex.EncodeToString(sum[:]),
+		func(t *model.Transaction) (int, []byte, error) {
+			data, err := json.Marshal(createdResponse(t))
+			return http.StatusCreated, data, err
+		})
+	if errors.Is(err, service.ErrIdempotencyMismatch) {
+		utils.WriteJSON(w, http.StatusUnprocessableEntity, model.Response{
+			ResponseCode: "01",
+			Message:      "Idempotency-Key was already used with a different request body",
+		})
+		return
+	}
+	if err != nil {
+		h.writeCreateError(w, err)
+		return
+	}
+
+	if replayed {
+		w.Header().Set("Idempotent-Replayed", "true")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(rec.ResponseStatus)
+	_, _ = w.Write(rec.ResponseBody)
+}
+
+func createdResponse(t *model.Transaction) model.Response {
+	message := "Transaction created successfully"
+	switch t.Status {
+	case model.TransactionPending:
+		message = "Adjustment recorded and waiting for manager approval"
+	case model.TransactionInTransit:
+		message = "Transfer dispatched and in transit"
+	}
+	return model.Response{
+		ResponseCode: "00",
+		Message:      message,
+		Data:         t,
+	}
+}
+
+func (h *TransactionHandler) writeCreateError(w http.ResponseWriter, err error) {
+	if writeValidationError(w, err) {
+		return
+	}
+	utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+		ResponseCode: "01",
+		Message:      err.Error(),
+	})
+}
+
+// HandleGetByID returns a transaction with who recorded it, its
+// attachments and, per item, the product and its stock before and after.
+// Staff only see their own.
+func (h *TransactionHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return
+	}
+
+	// Managers and admins may look at anyone's transactions.
+	owner := userID
+	if canViewAllTransactions(r) {
+		owner = 0
+	}
+
+	transaction, err := h.transactionService.GetByID(r.Context(), id, owner)
+	if err == nil {
+		transaction.Attachments, err = h.attachmentService.List(r.Context(), id, owner)
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction not found",
+		})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to fetch transaction",
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "Success",
+		Data:         transaction,
+	})
+}
+
+// HandleReverse undoes a transaction with a linked transaction of the
+// opposite type. Staff may only reverse their own transactions.
+func (h *TransactionHandler) HandleReverse(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return
+	}
+
+	owner := userID
+	if canViewAllTransactions(r) {
+		owner = 0
+	}
+
+	reversal, err := h.transactionService.Reverse(r.Context(), id, owner, userID)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction not found",
+		})
+		return
+	case errors.Is(err, service.ErrAlreadyReversed):
+		utils.WriteJSON(w, http.StatusConflict, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction is already reversed or is itself a reversal",
+		})
+		return
+	case errors.Is(err, service.ErrNotPosted):
+		utils.WriteJSON(w, http.StatusConflict, model.Response{
+			ResponseCode: "01",
+			Message:      "Only posted transactions can be reversed",
+		})
+		return
+	case err != nil:
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to reverse transaction",
 		})
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, model.Response{
 		ResponseCode: "00",
-		Message:      "Transaction created successfully",
+		Message:      "Transaction reversed successfully",
+		Data:         reversal,
 	})
 }
 
+// HandleApprove posts a pending adjustment, moving its stock.
+func (h *TransactionHandler) HandleApprove(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, true)
+}
+
+// HandleReject closes a pending adjustment without moving stock.
+func (h *TransactionHandler) HandleReject(w http.ResponseWriter, r *http.Request) {
+	h.review(w, r, false)
+}
+
+func (h *TransactionHandler) review(w http.ResponseWriter, r *http.Request, approve bool) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return
+	}
+
+	transaction, err := h.transactionService.Review(r.Context(), id, userID, approve)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction not found",
+		})
+		return
+	case errors.Is(err, service.ErrNotPending):
+		utils.WriteJSON(w, http.StatusConflict, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction is not waiting for approval",
+		})
+		return
+	case err != nil:
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to review transaction",
+		})
+		return
+	}
+
+	message := "Adjustment approved"
+	if !approve {
+		message = "Adjustment rejected"
+	}
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      message,
+		Data:         transaction,
+	})
+}
+
+// HandleReceive records the arrival of a transfer in transit at its
+// destination. The body may list what arrived of some items; an empty body
+// means everything arrived as dispatched.
+func (h *TransactionHandler) HandleReceive(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid transaction ID")
+	if !ok {
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxTransactionBodyBytes))
+	var req dto.ReceiveTransferRequest
+	if err == nil && len(bytes.TrimSpace(body)) > 0 {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+		})
+		return
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return
+	}
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return
+	}
+
+	transaction, err := h.transactionService.Receive(r.Context(), id, userID, req.Items)
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction not found",
+		})
+		return
+	case errors.Is(err, service.ErrNotInTransit):
+		utils.WriteJSON(w, http.StatusConflict, model.Response{
+			ResponseCode: "01",
+			Message:      "Transaction is not a transfer in transit",
+		})
+		return
+	case err != nil:
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to receive transfer",
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "Transfer received",
+		Data:         transaction,
+	})
+}
+
+// HandleGetUserTransactions lists the caller's transactions newest first,
+// a page at a time. See parseTransactionFilter for the query parameters.
 func (h *TransactionHandler) HandleGetUserTransactions(w http.ResponseWriter, r *http.Request) {
 	userID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok || userID == 0 {
@@ -74,8 +419,61 @@ func (h *TransactionHandler) HandleGetUserTransactions(w http.ResponseWriter, r
 		return
 	}
 
-	transactions, err := h.transactionService.GetByUserID(r.Context(), userID)
+	filter, ok := parseTransactionFilter(w, r)
+	if !ok {
+		return
+	}
+	filter.UserID = userID
+
+	page, err := h.transactionService.List(r.Context(), filter, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to fetch transactions",
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "Success",
+		Data:         page,
+	})
+}
+
+// HandleList lists the transactions of all users for managers and admins,
+// a page at a time with totals over every match, or with ?format=csv as a
+// CSV file of all matching items.
+func (h *TransactionHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseTransactionFilter(w, r)
+	if !ok {
+		return
+	}
+
+	switch strings.ToLower(r.URL.Query().Get("format")) {
+	case "":
+	case "csv":
+		h.exportCSV(w, r, filter)
+		return
+	default:
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       map[string]string{"format": "format must be csv"},
+		})
+		return
+	}
+
+	page, err := h.transactionService.ListWithTotals(r.Context(), filter, r.URL.Query().Get("cursor"))
 	if err != nil {
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
 		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
 			ResponseCode: "01",
 			Message:      "Failed to fetch transactions",
@@ -86,6 +484,174 @@ func (h *TransactionHandler) HandleGetUserTransactions(w http.ResponseWriter, r
 	utils.WriteJSON(w, http.StatusOK, model.Response{
 		ResponseCode: "00",
 		Message:      "Success",
-		Data:         transactions,
+		Data:         page,
 	})
 }
+
+var transactionExportColumns = []string{
+	"transaction_id", "created_at", "transaction_type", "warehouse_id", "destination_warehouse_id", "status", "reason", "reference_number", "counterparty", "notes",
+	"user_id", "user_name", "user_email",
+	"reverses_transaction_id", "reversed_by_transaction_id",
+	"item_id", "product_id", "product_sku", "product_name", "variant_id", "quantity", "unit", "unit_quantity",
+	"unit_cost", "unit_price", "currency", "cost_of_goods", "stock_before", "stock_after", "counted", "received_quantity",
+}
+
+// exportCSV streams one line per matching item. The response starts with
+// the first line, so filter errors can still be reported as JSON.
+func (h *TransactionHandler) exportCSV(w http.ResponseWriter, r *http.Request, filter model.TransactionFilter) {
+	var sheet spreadsheet.Writer
+	start := func() error {
+		if sheet != nil {
+			return nil
+		}
+		filename := "transactions-" + time.Now().UTC().Format("20060102") + ".csv"
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+		w.WriteHeader(http.StatusOK)
+		sheet, _ = spreadsheet.NewWriter(spreadsheet.FormatCSV, w)
+		return sheet.Write(transactionExportColumns)
+	}
+
+	err := h.transactionService.Export(r.Context(), filter, func(t *model.TransactionWithItems, item *model.TransactionItem) error {
+		if err := start(); err != nil {
+			return err
+		}
+		return sheet.Write(transactionExportRecord(t, item))
+	})
+	if err != nil && sheet == nil {
+		if writeValidationError(w, err) {
+			return
+		}
+		log.Println(err)
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to export transactions",
+		})
+		return
+	}
+	if err == nil {
+		err = start()
+	}
+	if err == nil {
+		err = sheet.Close()
+	}
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func transactionExportRecord(t *model.TransactionWithItems, item *model.TransactionItem) []string {
+	optionalInt := func(v *int64) string {
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatInt(*v, 10)
+	}
+	optionalDecimal := func(v *decimal.Decimal) string {
+		if v == nil {
+			return ""
+		}
+		return v.String()
+	}
+	variantID := ""
+	if item.VariantID != 0 {
+		variantID = strconv.FormatInt(item.VariantID, 10)
+	}
+
+	return []string{
+		strconv.FormatInt(t.ID, 10),
+		t.CreatedAt.UTC().Format(time.RFC3339),
+		t.TransactionType,
+		strconv.FormatInt(t.WarehouseID, 10),
+		optionalInt(t.DestinationWarehouseID),
+		t.Status,
+		t.Reason,
+		t.ReferenceNumber,
+		t.Counterparty,
+		t.Notes,
+		strconv.FormatInt(t.UserID, 10),
+		strings.TrimSpace(t.CreatedBy.FirstName + " " + t.CreatedBy.LastName),
+		t.CreatedBy.Email,
+		optionalInt(t.ReversesID),
+		optionalInt(t.ReversedByID),
+		strconv.FormatInt(item.ID, 10),
+		strconv.FormatInt(item.ProductID, 10),
+		item.ProductSKU,
+		item.ProductName,
+		variantID,
+		item.Quantity.String(),
+		item.Unit,
+		optionalDecimal(item.UnitQuantity),
+		optionalInt(item.UnitCost),
+		optionalInt(item.UnitPrice),
+		item.Currency,
+		optionalInt(item.CostOfGoods),
+		optionalDecimal(item.StockBefore),
+		optionalDecimal(item.StockAfter),
+		optionalDecimal(item.Counted),
+		optionalDecimal(item.ReceivedQuantity),
+	}
+}
+
+func canViewAllTransactions(r *http.Request) bool {
+	role := middleware.GetRoleFromContext(r.Context())
+	return role == model.RoleManager || role == model.RoleAdmin
+}
+
+// parseTransactionFilter reads the listing filters ?type=, ?status=,
+// ?reference= (exact), ?q= (text in the reference number, counterparty or
+// notes), ?user_id=, ?warehouse_id=, ?product_id=, ?category_id=, ?from=
+// and ?to= (RFC 3339 or dates, inclusive) and the page size ?limit=.
+func parseTransactionFilter(w http.ResponseWriter, r *http.Request) (model.TransactionFilter, bool) {
+	q := r.URL.Query()
+	f := model.TransactionFilter{
+		Type:      strings.ToUpper(q.Get("type")),
+		Status:    strings.ToLower(q.Get("status")),
+		Reference: strings.TrimSpace(q.Get("reference")),
+		Search:    strings.TrimSpace(q.Get("q")),
+	}
+	errs := map[string]string{}
+
+	for name, dst := range map[string]*int64{"user_id": &f.UserID, "warehouse_id": &f.WarehouseID, "product_id": &f.ProductID, "category_id": &f.CategoryID} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			errs[name] = name + " must be a positive integer"
+		}
+		*dst = id
+	}
+	if v := q.Get("from"); v != "" {
+		from, err := parseFrom(v)
+		if err != nil {
+			errs["from"] = "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+		}
+		f.From = &from
+	}
+	if v := q.Get("to"); v != "" {
+		to, err := parseAsOf(v)
+		if err != nil {
+			errs["to"] = "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"
+		}
+		f.To = &to
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			errs["limit"] = "limit must be a positive integer"
+		}
+		f.Limit = limit
+	}
+
+	if len(errs) > 0 {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       errs,
+		})
+		return f, false
+	}
+	return f, true
+}
@@ -132,3 +132,79 @@ func (h *UserHandler) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
 		Message:      "User updated successfully",
 	})
 }
+
+// HandlePatchUser applies an RFC 7396 merge patch to the current user, so
+// the password only needs to be sent when it changes.
+func (h *UserHandler) HandlePatchUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok || userID == 0 {
+		utils.WriteJSON(w, http.StatusUnauthorized, model.Response{
+			ResponseCode: "01",
+			Message:      "Unauthorized",
+		})
+		return
+	}
+
+	patch, ok := readMergePatch(w, r)
+	if !ok {
+		return
+	}
+
+	existing, err := h.userService.GetByID(r.Context(), int64(userID))
+	if err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to get user",
+		})
+		return
+	}
+	if existing == nil {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{
+			ResponseCode: "01",
+			Message:      "User not found",
+		})
+		return
+	}
+
+	req := dto.UpdateUserRequest{
+		FirstName:   existing.FirstName,
+		LastName:    existing.LastName,
+		Email:       existing.Email,
+		DateOfBirth: existing.DateOfBirth.Format("2006-01-02"),
+		Gender:      existing.Gender,
+	}
+	if !applyMergePatch(w, patch, &req) {
+		return
+	}
+
+	birthDate, err := utils.ParseDate(req.DateOfBirth)
+	if err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid birth_date format",
+		})
+		return
+	}
+
+	user := &model.Users{
+		ID:          userID,
+		FirstName:   req.FirstName,
+		LastName:    req.LastName,
+		Email:       req.Email,
+		Password:    req.Password,
+		DateOfBirth: birthDate,
+		Gender:      req.Gender,
+	}
+	if err := h.userService.Patch(r.Context(), user); err != nil {
+		utils.WriteJSON(w, http.StatusInternalServerError, model.Response{
+			ResponseCode: "01",
+			Message:      "Failed to update user",
+		})
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{
+		ResponseCode: "00",
+		Message:      "User updated successfully",
+	})
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+type WarehouseHandler struct {
+	warehouseService *service.WarehouseService
+}
+
+func NewWarehouseHandler(s *service.WarehouseService) *WarehouseHandler {
+	return &WarehouseHandler{warehouseService: s}
+}
+
+func (h *WarehouseHandler) HandleCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeWarehouseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.warehouseService.Create(r.Context(), &model.Warehouse{
+		Code:      req.Code,
+		Name:      req.Name,
+		Address:   req.Address,
+		IsDefault: req.IsDefault,
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to create warehouse")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, model.Response{ResponseCode: "00", Message: "Warehouse created successfully", Data: warehouse})
+}
+
+func (h *WarehouseHandler) HandleGetAll(w http.ResponseWriter, r *http.Request) {
+	warehouses, err := h.warehouseService.List(r.Context())
+	if err != nil {
+		h.writeError(w, err, "Failed to get warehouses")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: warehouses})
+}
+
+func (h *WarehouseHandler) HandleGetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.warehouseService.GetByID(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to get warehouse")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Success", Data: warehouse})
+}
+
+func (h *WarehouseHandler) HandleUpdate(w http.ResponseWriter, r *http.Request) {
+	id, ok := parseIDParam(w, r, "id", "Invalid warehouse ID")
+	if !ok {
+		return
+	}
+	req, ok := decodeWarehouseRequest(w, r)
+	if !ok {
+		return
+	}
+
+	warehouse, err := h.warehouseService.Update(r.Context(), &model.Warehouse{
+		ID:        id,
+		Code:      req.Code,
+		Name:      req.Name,
+		Address:   req.Address,
+		IsDefault: req.IsDefault,
+	})
+	if err != nil {
+		h.writeError(w, err, "Failed to update warehouse")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, model.Response{ResponseCode: "00", Message: "Warehouse updated successfully", Data: warehouse})
+}
+
+func decodeWarehouseRequest(w http.ResponseWriter, r *http.Request) (*dto.WarehouseRequest, bool) {
+	var req dto.WarehouseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Invalid request body",
+			Errors:       utils.FormatDecodeError(err),
+		})
+		return nil, false
+	}
+
+	if err := utils.NewValidator().Struct(req); err != nil {
+		utils.WriteJSON(w, http.StatusBadRequest, model.Response{
+			ResponseCode: "01",
+			Message:      "Validation failed",
+			Errors:       utils.FormatFieldErrors(err),
+		})
+		return nil, false
+	}
+	return &req, true
+}
+
+func (h *WarehouseHandler) writeError(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	if errors.Is(err, service.ErrNotFound) {
+		utils.WriteJSON(w, http.StatusNotFound, model.Response{ResponseCode: "01", Message: "Warehouse not found"})
+		return
+	}
+	log.Println(err)
+	utils.WriteJSON(w, http.StatusInternalServerError, model.Response{ResponseCode: "01", Message: message})
+}
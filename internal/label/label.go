@@ -0,0 +1,11 @@
+// Package label lays out product barcodes for printing, either as an A4
+// PDF sheet for office printers or as ZPL for thermal label printers.
+package label
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/barcode"
+
+// Item is a single label: a caption printed above an encoded symbol.
+type Item struct {
+	Title  string
+	Symbol *barcode.Symbol
+}
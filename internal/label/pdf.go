@@ -0,0 +1,144 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 sheet of 3 x 8 labels, in PDF points.
+const (
+	pageWidth   = 595.0
+	pageHeight  = 842.0
+	sheetCols   = 3
+	sheetRows   = 8
+	labelWidth  = pageWidth / sheetCols
+	labelHeight = pageHeight / sheetRows
+	labelMargin = 8.0
+	titleSize   = 8.0
+	captionSize = 7.0
+)
+
+// PDF renders items onto as many A4 sheets as needed.
+func PDF(items []Item) []byte {
+	perPage := sheetCols * sheetRows
+	pages := (len(items) + perPage - 1) / perPage
+	if pages == 0 {
+		pages = 1
+	}
+
+	var (
+		buf     bytes.Buffer
+		offsets []int
+	)
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, pages)
+	for i := range kids {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+
+	for p := 0; p < pages; p++ {
+		end := min((p+1)*perPage, len(items))
+		content := pageContent(items[p*perPage : end])
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %g %g] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 5+2*p))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+func pageContent(items []Item) string {
+	var b strings.Builder
+	for i, item := range items {
+		col, row := i%sheetCols, i/sheetCols
+		x := float64(col)*labelWidth + labelMargin
+		top := pageHeight - float64(row)*labelHeight - labelMargin
+		drawLabel(&b, item, x, top)
+	}
+	return b.String()
+}
+
+func drawLabel(b *strings.Builder, item Item, x, top float64) {
+	availW := labelWidth - 2*labelMargin
+	availH := labelHeight - 2*labelMargin - titleSize - 4
+
+	fmt.Fprintf(b, "BT /F1 %g Tf %.2f %.2f Td (%s) Tj ET\n", titleSize, x, top-titleSize, pdfText(truncate(item.Title, 40)))
+
+	s := item.Symbol
+	if s == nil {
+		return
+	}
+	symbolTop := top - titleSize - 4
+
+	if s.Linear() {
+		barH := availH - captionSize - 2
+		module := availW / float64(s.Width+2*s.QuietZone)
+		originX := x + float64(s.QuietZone)*module
+		for _, bar := range s.Bars() {
+			fmt.Fprintf(b, "%.2f %.2f %.2f %.2f re\n", originX+float64(bar[0])*module, symbolTop-barH, float64(bar[1])*module, barH)
+		}
+		b.WriteString("f\n")
+		fmt.Fprintf(b, "BT /F1 %g Tf %.2f %.2f Td (%s) Tj ET\n", captionSize, originX, symbolTop-barH-captionSize-1, pdfText(s.Text))
+		return
+	}
+
+	module := min(availW, availH) / float64(s.Width)
+	for y := 0; y < s.Height; y++ {
+		for xm := 0; xm < s.Width; {
+			if !s.Dark(xm, y) {
+				xm++
+				continue
+			}
+			start := xm
+			for xm < s.Width && s.Dark(xm, y) {
+				xm++
+			}
+			fmt.Fprintf(b, "%.2f %.2f %.2f %.2f re\n", x+float64(start)*module, symbolTop-float64(y+1)*module, float64(xm-start)*module, module)
+		}
+	}
+	b.WriteString("f\n")
+}
+
+// pdfText escapes a string for a PDF literal, replacing characters outside
+// the base font's single-byte range.
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
@@ -0,0 +1,63 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/barcode"
+)
+
+func TestPDFCrossReference(t *testing.T) {
+	symbol, err := barcode.EncodeCode128("SKU-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := make([]Item, 25)
+	for i := range items {
+		items[i] = Item{Title: fmt.Sprintf("Item (%d)", i), Symbol: symbol}
+	}
+	doc := PDF(items)
+
+	// 25 labels take two pages: catalog, pages, font and a page and
+	// content stream per page.
+	m := regexp.MustCompile(`xref\n0 (\d+)\n0000000000 65535 f \n((?:\d{10} 00000 n \n)*)trailer`).FindSubmatch(doc)
+	if m == nil {
+		t.Fatal("no cross-reference table")
+	}
+	if string(m[1]) != "8" {
+		t.Errorf("cross-reference has %s entries, want 8", m[1])
+	}
+	for i, line := range bytes.Split(bytes.TrimSuffix(m[2], []byte("\n")), []byte("\n")) {
+		off, _ := strconv.Atoi(string(line[:10]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(doc[off:], []byte(want)) {
+			t.Errorf("object %d offset %d points at %q", i+1, off, doc[off:off+10])
+		}
+	}
+
+	start := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(doc)
+	if start == nil {
+		t.Fatal("no startxref")
+	}
+	if off, _ := strconv.Atoi(string(start[1])); !bytes.HasPrefix(doc[off:], []byte("xref\n")) {
+		t.Errorf("startxref %d does not point at the cross-reference table", off)
+	}
+	if !bytes.Contains(doc, []byte(`(Item \(24\))`)) {
+		t.Error("parentheses in titles are not escaped")
+	}
+}
+
+func TestPDFText(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"Kopi (250g)", `Kopi \(250g\)`},
+		{`a\b`, `a\\b`},
+		{"Café\n", "Caf??"},
+	}
+	for _, tt := range tests {
+		if got := pdfText(tt.in); got != tt.want {
+			t.Errorf("pdfText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package label
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/barcode"
+)
+
+// ZPL renders one ^XA..^XZ format per item for 203 dpi thermal printers.
+// The printer draws the symbols itself, so only the data is sent.
+func ZPL(items []Item) []byte {
+	var b strings.Builder
+	for _, item := range items {
+		b.WriteString("^XA\n^CI28\n")
+		fmt.Fprintf(&b, "^FO20,20^A0N,28,28^FH_^FD%s^FS\n", zplEscape(truncate(item.Title, 40)))
+
+		s := item.Symbol
+		switch {
+		case s == nil:
+		case s.Kind == barcode.EAN13:
+			fmt.Fprintf(&b, "^FO20,60^BY2^BEN,100,Y,N^FD%s^FS\n", s.Text[:12])
+		case s.Kind == barcode.QR:
+			fmt.Fprintf(&b, "^FO20,60^BQN,2,5^FH_^FDMA,%s^FS\n", zplEscape(s.Text))
+		default:
+			fmt.Fprintf(&b, "^FO20,60^BY2^BCN,100,Y,N,N^FH_^FD%s^FS\n", zplEscape(s.Text))
+		}
+
+		b.WriteString("^XZ\n")
+	}
+	return []byte(b.String())
+}
+
+// zplEscape hex-encodes the characters ZPL treats as control prefixes;
+// it pairs with ^FH_ on the field.
+func zplEscape(s string) string {
+	r := strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+	return r.Replace(s)
+}
@@ -0,0 +1,66 @@
+package label
+
+import (
+	"testing"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/barcode"
+)
+
+func TestZPL(t *testing.T) {
+	code128, err := barcode.EncodeCode128("SKU_1^~")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ean, err := barcode.EncodeEAN13("4006381333931")
+	if err != nil {
+		t.Fatal(err)
+	}
+	qr, err := barcode.EncodeQR("https://example.com/p?a=1_b^c")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items := []Item{
+		{Title: "Kopi_Arabika ^Gayo~", Symbol: code128},
+		// The printer adds the EAN-13 check digit itself.
+		{Title: "Gula", Symbol: ean},
+		{Title: "Teh Melati Premium dengan Kemasan Kaleng 500g", Symbol: qr},
+		{Title: "No symbol"},
+	}
+	want := "^XA\n^CI28\n" +
+		"^FO20,20^A0N,28,28^FH_^FDKopi_5FArabika _5EGayo_7E^FS\n" +
+		"^FO20,60^BY2^BCN,100,Y,N,N^FH_^FDSKU_5F1_5E_7E^FS\n" +
+		"^XZ\n" +
+		"^XA\n^CI28\n" +
+		"^FO20,20^A0N,28,28^FH_^FDGula^FS\n" +
+		"^FO20,60^BY2^BEN,100,Y,N^FD400638133393^FS\n" +
+		"^XZ\n" +
+		"^XA\n^CI28\n" +
+		"^FO20,20^A0N,28,28^FH_^FDTeh Melati Premium dengan Kemasan Kal...^FS\n" +
+		"^FO20,60^BQN,2,5^FH_^FDMA,https://example.com/p?a=1_5Fb_5Ec^FS\n" +
+		"^XZ\n" +
+		"^XA\n^CI28\n" +
+		"^FO20,20^A0N,28,28^FH_^FDNo symbol^FS\n" +
+		"^XZ\n"
+
+	if got := string(ZPL(items)); got != want {
+		t.Errorf("ZPL =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestZPLEscape(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"plain text", "plain text"},
+		{"_", "_5F"},
+		{"^XZ", "_5EXZ"},
+		{"~JR", "_7EJR"},
+		// An escaped underscore must not be read as the start of another.
+		{"_5E", "_5F5E"},
+		{"Kopi ☕", "Kopi ☕"},
+	}
+	for _, tt := range tests {
+		if got := zplEscape(tt.in); got != tt.want {
+			t.Errorf("zplEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
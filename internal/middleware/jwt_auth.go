@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
@@ -10,7 +11,10 @@ import (
 
 type contextKey string
 
-const UserIDKey contextKey = "user_id"
+const (
+	UserIDKey contextKey = "user_id"
+	RoleKey   contextKey = "role"
+)
 
 func JWTMiddleware(secret string, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +30,7 @@ func JWTMiddleware(secret string, next http.HandlerFunc) http.HandlerFunc {
 
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		userID, err := utils.ParseJWT(tokenStr, secret)
+		userID, role, err := utils.ParseJWT(tokenStr, secret)
 		if err != nil {
 			utils.WriteJSON(w, http.StatusUnauthorized, map[string]string{
 				"responseCode": "01",
@@ -36,6 +40,7 @@ func JWTMiddleware(secret string, next http.HandlerFunc) http.HandlerFunc {
 		}
 
 		ctx := context.WithValue(r.Context(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, RoleKey, role)
 
 		next(w, r.WithContext(ctx))
 	}
@@ -46,10 +51,15 @@ func GetUserIDFromContext(ctx context.Context) (int64, bool) {
 	return userID, ok
 }
 
+// GetRoleFromContext returns the role from the caller's token.
+func GetRoleFromContext(ctx context.Context) string {
+	role, _ := ctx.Value(RoleKey).(string)
+	return role
+}
+
 func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		role, ok := r.Context().Value("role").(string)
-		if !ok || role != "admin" {
+		if GetRoleFromContext(r.Context()) != "admin" {
 			utils.WriteJSON(w, http.StatusForbidden, map[string]string{
 				"responseCode": "03",
 				"message":      "Forbidden: Admins only",
@@ -60,3 +70,19 @@ func RequireAdmin(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// RequireRole lets through only callers with one of the given roles. It
+// must run inside JWTMiddleware.
+func RequireRole(next http.HandlerFunc, roles ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !slices.Contains(roles, GetRoleFromContext(r.Context())) {
+			utils.WriteJSON(w, http.StatusForbidden, map[string]string{
+				"responseCode": "03",
+				"message":      "Forbidden: requires role " + strings.Join(roles, " or "),
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package model
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
+// Bin is a shelf inside a warehouse, addressed by zone, aisle, rack and
+// bin. Code joins the four for pick lists and labels. Capacity is in base
+// units of whatever the bin holds; nil means unlimited.
+type Bin struct {
+	ID          int64            `json:"id"`
+	WarehouseID int64            `json:"warehouse_id"`
+	Zone        string           `json:"zone"`
+	Aisle       string           `json:"aisle"`
+	Rack        string           `json:"rack"`
+	Bin         string           `json:"bin"`
+	Code        string           `json:"code"`
+	Capacity    *decimal.Decimal `json:"capacity,omitempty"`
+	Used        decimal.Decimal  `json:"used"`
+	Items       []*BinItem       `json:"items,omitempty"`
+}
+
+// BinItem is the stock of a product or variant in a bin, in base units.
+type BinItem struct {
+	ProductID   int64           `json:"product_id"`
+	ProductSKU  string          `json:"product_sku,omitempty"`
+	ProductName string          `json:"product_name"`
+	VariantID   int64           `json:"variant_id,omitempty"`
+	VariantSKU  string          `json:"variant_sku,omitempty"`
+	Stock       decimal.Decimal `json:"stock"`
+}
+
+// BinQuantity is a quantity of an item in a bin: what a transaction item
+// put away there or picked from there, or what a pick list suggests
+// picking.
+type BinQuantity struct {
+	BinID    int64           `json:"bin_id"`
+	BinCode  string          `json:"bin_code"`
+	Quantity decimal.Decimal `json:"quantity"`
+}
+
+// PickList suggests the bins to pick a quantity of an item from, in the
+// order a picker walks them. Unbinned is what is left to take from stock
+// not in any bin.
+type PickList struct {
+	Bins     []BinQuantity   `json:"bins"`
+	Unbinned decimal.Decimal `json:"unbinned"`
+}
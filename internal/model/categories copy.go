@@ -8,6 +8,8 @@ type Categories struct {
 	ID          int64
 	Name        string
 	Description string
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	// Version increases on every update and backs the ETag.
+	Version   int
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
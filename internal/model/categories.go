@@ -1,10 +1,37 @@
 package model
 
+import (
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
 type Product struct {
 	ID          int64
+	SKU         string
+	Barcode     string
 	Name        string
 	Description string
 	ImageURL    string
 	CategoryID  int
-	Stock       string
+	BaseUnit    string
+	// Precision is the number of decimal places allowed in stock and
+	// movement quantities, e.g. 0 for pieces or 3 for kilograms.
+	Precision int
+	// Stock is the total over all warehouses; StockByWarehouse lists the
+	// warehouses holding any of it.
+	Stock            decimal.Decimal
+	StockByWarehouse []*WarehouseStock `json:",omitempty"`
+	// Price and Cost are the currently effective selling price and purchase
+	// cost per base unit, in minor units of Currency (e.g. cents).
+	Price    int64
+	Cost     int64
+	Currency string
+	// ArchivedAt is set once the product is archived; archived products are
+	// hidden from listings and cannot be issued.
+	ArchivedAt *time.Time `json:",omitempty"`
+	// Version increases on every edit and backs the ETag used for
+	// optimistic concurrency.
+	Version  int
+	Variants []*ProductVariant `json:",omitempty"`
 }
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// IdempotencyRecord is the stored outcome of a request sent with an
+// Idempotency-Key header. A retry with the same key and body gets the same
+// response instead of repeating the request.
+type IdempotencyRecord struct {
+	UserID         int64
+	Key            string
+	RequestHash    string
+	TransactionID  int64
+	ResponseStatus int
+	ResponseBody   []byte
+	CreatedAt      time.Time
+}
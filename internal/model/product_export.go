@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
+// ProductExport is one row of the catalog export. Fields are named like
+// the import columns, so an exported file can be edited and imported back.
+type ProductExport struct {
+	ID           int64           `json:"id"`
+	SKU          string          `json:"sku"`
+	Barcode      string          `json:"barcode"`
+	Name         string          `json:"name"`
+	Description  string          `json:"description"`
+	CategoryID   int             `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	BaseUnit     string          `json:"base_unit"`
+	Precision    int             `json:"quantity_precision"`
+	Stock        decimal.Decimal `json:"stock"`
+	Price        int64           `json:"price"`
+	Cost         int64           `json:"cost"`
+	Currency     string          `json:"currency"`
+	ImageURL     string          `json:"image_url"`
+	ArchivedAt   *time.Time      `json:"archived_at"`
+	Version      int             `json:"version"`
+}
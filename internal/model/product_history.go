@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// ProductSnapshot holds the descriptive attributes of a product that are
+// versioned. Stock and prices have their own histories.
+type ProductSnapshot struct {
+	SKU         string `json:"sku,omitempty"`
+	Barcode     string `json:"barcode,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ImageURL    string `json:"image_url"`
+	CategoryID  int    `json:"category_id"`
+	BaseUnit    string `json:"base_unit"`
+	Precision   int    `json:"quantity_precision"`
+	Currency    string `json:"currency"`
+}
+
+// ProductRevision is the state of a product from ValidFrom until the next
+// revision. Changes lists what differs from the previous revision.
+// ProductVersion is the product's version, as sent in its ETag, when the
+// revision was recorded; stock and price changes also bump the version but
+// don't add a revision.
+type ProductRevision struct {
+	ProductID      int64           `json:"product_id"`
+	Revision       int             `json:"revision"`
+	ProductVersion int             `json:"product_version"`
+	ValidFrom      time.Time       `json:"valid_from"`
+	Product        ProductSnapshot `json:"product"`
+	Changes        []FieldChange   `json:"changes,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from"`
+	To    any    `json:"to"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+type ProductImage struct {
+	ID           int64     `json:"id"`
+	ProductID    int64     `json:"product_id"`
+	Position     int       `json:"position"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
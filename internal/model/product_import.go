@@ -0,0 +1,29 @@
+package model
+
+// Outcomes of importing one row.
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+)
+
+// ImportRowError lists why a row of an import file was rejected. Row is
+// the row number as shown in the file, counting the header as row 1.
+type ImportRowError struct {
+	Row    int               `json:"row"`
+	SKU    string            `json:"sku"`
+	Errors map[string]string `json:"errors"`
+}
+
+// ImportResult summarises a product import. Errors may be truncated; the
+// report at ErrorReportURL always lists every rejected row.
+type ImportResult struct {
+	DryRun         bool             `json:"dry_run"`
+	Rows           int              `json:"rows"`
+	Created        int              `json:"created"`
+	Updated        int              `json:"updated"`
+	Unchanged      int              `json:"unchanged"`
+	Failed         int              `json:"failed"`
+	Errors         []ImportRowError `json:"errors"`
+	ErrorReportURL string           `json:"error_report_url,omitempty"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// DefaultCurrency is used for products created without a currency.
+const DefaultCurrency = "IDR"
+
+// ProductPrice is one entry of a product's price history. Amounts are in
+// minor units of Currency and apply from EffectiveFrom until the next entry.
+type ProductPrice struct {
+	ID            int64     `json:"id"`
+	ProductID     int64     `json:"product_id"`
+	Price         int64     `json:"price"`
+	Cost          int64     `json:"cost"`
+	Currency      string    `json:"currency"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	CreatedAt     time.Time `json:"created_at"`
+}
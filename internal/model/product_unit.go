@@ -0,0 +1,26 @@
+package model
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
+// DefaultBaseUnit is used for products created without a base unit.
+const DefaultBaseUnit = "pcs"
+
+// ProductUnit is an alternative unit of measure for a product, such as a
+// box or pallet, expressed as a multiple of the product's base unit.
+type ProductUnit struct {
+	ID        int64  `json:"id"`
+	ProductID int64  `json:"product_id"`
+	Name      string `json:"name"`
+	Factor    int64  `json:"factor"`
+}
+
+// StockInUnit reports a product's stock converted to one of its units.
+type StockInUnit struct {
+	ProductID int64           `json:"product_id"`
+	BaseUnit  string          `json:"base_unit"`
+	BaseStock decimal.Decimal `json:"base_stock"`
+	Unit      string          `json:"unit"`
+	Factor    int64           `json:"factor"`
+	Quantity  int64           `json:"quantity"`
+	Remainder decimal.Decimal `json:"remainder"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
+type ProductVariant struct {
+	ID        int64             `json:"id"`
+	ProductID int64             `json:"product_id"`
+	SKU       string            `json:"sku,omitempty"`
+	Options   map[string]string `json:"options"`
+	Stock     decimal.Decimal   `json:"stock"`
+	// StockByWarehouse lists the warehouses holding any of Stock.
+	StockByWarehouse []*WarehouseStock `json:"stock_by_warehouse,omitempty"`
+}
+
+// OptionKey is a canonical form of Options, e.g. "color=red;size=M", used
+// to keep option combinations unique per product.
+func (v *ProductVariant) OptionKey() string {
+	names := make([]string, 0, len(v.Options))
+	for name := range v.Options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = strings.ToLower(strings.TrimSpace(name)) + "=" + strings.TrimSpace(v.Options[name])
+	}
+	return strings.Join(parts, ";")
+}
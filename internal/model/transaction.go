@@ -1,21 +1,164 @@
 package model
 
+import (
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
+// Transaction statuses. Only posted transactions have moved stock; an
+// adjustment waiting for a manager is pending until approved or rejected.
+// A transfer in transit has left its source warehouse but not yet reached
+// its destination.
+const (
+	TransactionPosted    = "posted"
+	TransactionPending   = "pending"
+	TransactionRejected  = "rejected"
+	TransactionInTransit = "in_transit"
+)
+
 type Transaction struct {
 	ID              int64  `json:"id"`
 	TransactionType string `json:"transaction_type"`
 	UserID          int64  `json:"user_id"`
+	WarehouseID     int64  `json:"warehouse_id"`
+	// DestinationWarehouseID is set on TRANSFER transactions, which move
+	// stock from WarehouseID to it.
+	DestinationWarehouseID *int64    `json:"destination_warehouse_id,omitempty"`
+	CreatedAt              time.Time `json:"created_at"`
+	Status                 string    `json:"status"`
+	// Reason is set on ADJUST transactions.
+	Reason          string `json:"reason,omitempty"`
+	ReferenceNumber string `json:"reference_number,omitempty"`
+	Counterparty    string `json:"counterparty,omitempty"`
+	Notes           string `json:"notes,omitempty"`
+	// ReversesID is set on a reversal to the transaction it undoes.
+	ReversesID *int64 `json:"reverses_transaction_id,omitempty"`
 }
 
 type TransactionItem struct {
-	ID            int64 `json:"id"`
-	TransactionID int64 `json:"transaction_id"`
-	ProductID     int64 `json:"product_id"`
-	Quantity      int64 `json:"quantity"`
+	ID            int64            `json:"id"`
+	TransactionID int64            `json:"transaction_id"`
+	ProductID     int64            `json:"product_id"`
+	VariantID     int64            `json:"variant_id,omitempty"`
+	Quantity      decimal.Decimal  `json:"quantity"`
+	Unit          string           `json:"unit,omitempty"`
+	UnitQuantity  *decimal.Decimal `json:"unit_quantity,omitempty"`
+	// UnitCost is set on IN and ADJUST items and UnitPrice on OUT items,
+	// per unit the item was entered in and in minor units of Currency.
+	UnitCost  *int64 `json:"unit_cost,omitempty"`
+	UnitPrice *int64 `json:"unit_price,omitempty"`
+	Currency  string `json:"currency,omitempty"`
+	// CostOfGoods is the total cost of an OUT item, or of the stock an
+	// ADJUST item wrote off, under the configured valuation method.
+	CostOfGoods *int64 `json:"cost_of_goods,omitempty"`
+	ProductName string `json:"product_name,omitempty"`
+	ProductSKU  string `json:"product_sku,omitempty"`
+	// StockBefore and StockAfter are the product's stock in base units,
+	// over all warehouses, around this item. They are unknown for items recorded before they
+	// were tracked.
+	StockBefore *decimal.Decimal `json:"stock_before,omitempty"`
+	StockAfter  *decimal.Decimal `json:"stock_after,omitempty"`
+	// Counted is the stock in base units a count_correction item sets.
+	// Quantity of an ADJUST item is signed: negative when stock went down.
+	Counted *decimal.Decimal `json:"counted,omitempty"`
+	// ReceivedQuantity is how much of a TRANSFER item arrived, in base
+	// units, once received. Discrepancy is ReceivedQuantity less Quantity
+	// when they differ.
+	ReceivedQuantity *decimal.Decimal `json:"received_quantity,omitempty"`
+	Discrepancy      *decimal.Decimal `json:"discrepancy,omitempty"`
+	// Bins are where an IN item was put away and an OUT or TRANSFER item
+	// was picked from; on a reversal, the bins it took the stock back from
+	// or returned it to. Stock outside them was unbinned.
+	Bins []BinQuantity `json:"bins,omitempty"`
+}
+
+// TransactionUser is the user who recorded a transaction.
+type TransactionUser struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
 }
 
 type TransactionWithItems struct {
-	ID              int64             `json:"id"`
-	UserID          int64             `json:"user_id"`
-	TransactionType string            `json:"transaction_type"`
-	Items           []TransactionItem `json:"items"`
+	ID                     int64            `json:"id"`
+	UserID                 int64            `json:"user_id"`
+	TransactionType        string           `json:"transaction_type"`
+	WarehouseID            int64            `json:"warehouse_id"`
+	DestinationWarehouseID *int64           `json:"destination_warehouse_id,omitempty"`
+	CreatedAt              time.Time        `json:"created_at"`
+	CreatedBy              *TransactionUser `json:"created_by,omitempty"`
+	Status                 string           `json:"status"`
+	Reason                 string           `json:"reason,omitempty"`
+	ReferenceNumber        string           `json:"reference_number,omitempty"`
+	Counterparty           string           `json:"counterparty,omitempty"`
+	Notes                  string           `json:"notes,omitempty"`
+	// ReviewedBy and ReviewedAt record who approved or rejected a pending
+	// adjustment, and when.
+	ReviewedBy *int64     `json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	// ReceivedBy and ReceivedAt record who received a transfer that was
+	// in transit, and when.
+	ReceivedBy *int64     `json:"received_by,omitempty"`
+	ReceivedAt *time.Time `json:"received_at,omitempty"`
+	// ReversesID is set on a reversal; Reversed and ReversedByID on the
+	// transaction it undoes.
+	ReversesID   *int64            `json:"reverses_transaction_id,omitempty"`
+	Reversed     bool              `json:"reversed"`
+	ReversedByID *int64            `json:"reversed_by_transaction_id,omitempty"`
+	Items        []TransactionItem `json:"items"`
+	// Attachments are only loaded for a single transaction.
+	Attachments []*TransactionAttachment `json:"attachments,omitempty"`
+}
+
+// TransactionFilter selects transactions for history listings, newest
+// first. Zero fields do not filter.
+type TransactionFilter struct {
+	UserID int64
+	// WarehouseID matches the source or destination of a transaction.
+	WarehouseID int64
+	Type        string
+	Status      string
+	// Reference matches the reference number exactly; Search looks for
+	// text in the reference number, counterparty and notes.
+	Reference  string
+	Search     string
+	ProductID  int64
+	CategoryID int64
+	From       *time.Time
+	To         *time.Time
+	// BeforeID continues a listing after the transaction with that ID.
+	BeforeID int64
+	Limit    int
+}
+
+// TransactionPage is one page of a transaction listing. NextCursor is set
+// when there are more transactions to fetch.
+type TransactionPage struct {
+	Transactions []TransactionWithItems `json:"transactions"`
+	NextCursor   string                 `json:"next_cursor,omitempty"`
+	Totals       *TransactionTotals     `json:"totals,omitempty"`
+}
+
+// TransactionTotals sums up every transaction matching a filter, not just
+// one page. With a product or category filter only the matching items are
+// summed.
+type TransactionTotals struct {
+	Transactions int                `json:"transactions"`
+	ByType       []TransactionTotal `json:"by_type"`
+}
+
+// TransactionTotal sums the items of one transaction type in one currency.
+// Value is the cost of IN and TRANSFER items, the revenue of OUT items and
+// the signed cost of ADJUST items, in minor units of Currency; Quantity is
+// in base units.
+type TransactionTotal struct {
+	TransactionType string          `json:"transaction_type"`
+	Currency        string          `json:"currency"`
+	Transactions    int             `json:"transactions"`
+	Items           int             `json:"items"`
+	Quantity        decimal.Decimal `json:"quantity"`
+	Value           int64           `json:"value"`
+	CostOfGoods     int64           `json:"cost_of_goods"`
 }
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// TransactionAttachment is a file kept with a transaction, such as a
+// scanned delivery note.
+type TransactionAttachment struct {
+	ID            int64     `json:"id"`
+	TransactionID int64     `json:"transaction_id"`
+	FileName      string    `json:"file_name"`
+	ContentType   string    `json:"content_type"`
+	SizeBytes     int64     `json:"size_bytes"`
+	StorageKey    string    `json:"-"`
+	URL           string    `json:"url"`
+	UploadedBy    int64     `json:"uploaded_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -4,6 +4,14 @@ import (
 	"time"
 )
 
+// Roles a user can have. Managers and admins see the transactions of every
+// user; new users are staff.
+const (
+	RoleStaff   = "staff"
+	RoleManager = "manager"
+	RoleAdmin   = "admin"
+)
+
 type Users struct {
 	ID          int64
 	FirstName   string
@@ -12,6 +20,7 @@ type Users struct {
 	Password    string
 	DateOfBirth time.Time
 	Gender      string
+	Role        string
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 }
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
+// CostLayer is a quantity received by one IN item together with its cost,
+// consumed oldest first under FIFO. Costs are in minor units of Currency.
+// Stock set directly on a product gets a layer without a transaction item.
+type CostLayer struct {
+	ID                int64
+	ProductID         int64
+	TransactionItemID int64
+	Quantity          decimal.Decimal
+	RemainingQuantity decimal.Decimal
+	TotalCost         int64
+	RemainingCost     int64
+	Currency          string
+}
+
+// StockLedgerEntry records the valued quantity of a product and its value
+// under each costing method after a movement.
+type StockLedgerEntry struct {
+	ProductID         int64
+	TransactionItemID int64
+	QuantityChange    decimal.Decimal
+	QuantityAfter     decimal.Decimal
+	FIFOValueAfter    int64
+	AverageValueAfter int64
+	Currency          string
+}
+
+type ValuationLine struct {
+	ProductID int64           `json:"product_id"`
+	SKU       string          `json:"sku,omitempty"`
+	Name      string          `json:"name"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Value     int64           `json:"value"`
+	Currency  string          `json:"currency"`
+}
+
+type ValuationReport struct {
+	AsOf   time.Time       `json:"as_of"`
+	Method string          `json:"method"`
+	Items  []ValuationLine `json:"items"`
+	// Totals sums the value of all items per currency.
+	Totals map[string]int64 `json:"totals"`
+}
@@ -0,0 +1,23 @@
+package model
+
+import "github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+
+// Warehouse is a location holding stock. Exactly one warehouse is the
+// default, which holds stock set directly on products and variants rather
+// than moved by transactions.
+type Warehouse struct {
+	ID        int64  `json:"id"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Address   string `json:"address,omitempty"`
+	IsDefault bool   `json:"is_default"`
+}
+
+// WarehouseStock is the stock of a product or variant at one warehouse, in
+// base units.
+type WarehouseStock struct {
+	WarehouseID   int64           `json:"warehouse_id"`
+	WarehouseCode string          `json:"warehouse_code"`
+	WarehouseName string          `json:"warehouse_name"`
+	Stock         decimal.Decimal `json:"stock"`
+}
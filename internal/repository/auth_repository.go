@@ -46,7 +46,7 @@ func (r *AuthRepository) RegisterConsumer(ctx context.Context, c *model.Users) (
 
 func (r *AuthRepository) FindByEmail(ctx context.Context, email string) (*model.Users, error) {
 	query := `
-		SELECT id, first_name, last_name, email, password, date_of_birth, gender
+		SELECT id, first_name, last_name, email, password, date_of_birth, gender, role
 		FROM users WHERE email = ? LIMIT 1
 	`
 
@@ -59,6 +59,7 @@ func (r *AuthRepository) FindByEmail(ctx context.Context, email string) (*model.
 		&c.Password,
 		&c.DateOfBirth,
 		&c.Gender,
+		&c.Role,
 	)
 
 	if err != nil {
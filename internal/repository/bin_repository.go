@@ -0,0 +1,310 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type BinRepository struct {
+	db *sql.DB
+}
+
+func NewBinRepository(db *sql.DB) *BinRepository {
+	return &BinRepository{db: db}
+}
+
+// binCode joins the position of bins b into its code.
+const binCode = `CONCAT_WS('-', b.zone, b.aisle, b.rack, b.bin)`
+
+// binOrder is the order a picker walks bins b in.
+const binOrder = `b.zone, b.aisle, b.rack, b.bin`
+
+// binColumns are read by scanBin. They need bins b joined with bin_stock bs
+// and grouped by b.id.
+const binColumns = `b.id, b.warehouse_id, b.zone, b.aisle, b.rack, b.bin, ` + binCode + `, b.capacity, COALESCE(SUM(bs.stock), 0)`
+
+func (r *BinRepository) Insert(ctx context.Context, b *model.Bin) (int64, error) {
+	query := `INSERT INTO bins (warehouse_id, zone, aisle, rack, bin, capacity) VALUES (?, ?, ?, ?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, b.WarehouseID, b.Zone, b.Aisle, b.Rack, b.Bin, b.Capacity)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert bin: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return id, nil
+}
+
+// GetByWarehouse returns the bins of a warehouse in walking order, with
+// how much each holds.
+func (r *BinRepository) GetByWarehouse(ctx context.Context, warehouseID int64) ([]*model.Bin, error) {
+	query := `
+		SELECT ` + binColumns + `
+		FROM bins b
+		LEFT JOIN bin_stock bs ON bs.bin_id = b.id
+		WHERE b.warehouse_id = ?
+		GROUP BY b.id
+		ORDER BY ` + binOrder
+	rows, err := r.db.QueryContext(ctx, query, warehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bins: %w", err)
+	}
+	defer rows.Close()
+
+	bins := []*model.Bin{}
+	for rows.Next() {
+		b, err := scanBin(rows)
+		if err != nil {
+			return nil, err
+		}
+		bins = append(bins, b)
+	}
+	return bins, rows.Err()
+}
+
+// GetByID returns a bin of a warehouse, or nil if there is none.
+func (r *BinRepository) GetByID(ctx context.Context, warehouseID, id int64) (*model.Bin, error) {
+	query := `
+		SELECT ` + binColumns + `
+		FROM bins b
+		LEFT JOIN bin_stock bs ON bs.bin_id = b.id
+		WHERE b.warehouse_id = ? AND b.id = ?
+		GROUP BY b.id
+	`
+	b, err := scanBin(r.db.QueryRowContext(ctx, query, warehouseID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+// GetIDByPosition returns the ID of the bin at a position in a warehouse,
+// or 0 if there is none.
+func (r *BinRepository) GetIDByPosition(ctx context.Context, b *model.Bin) (int64, error) {
+	var id int64
+	query := `SELECT id FROM bins WHERE warehouse_id = ? AND zone = ? AND aisle = ? AND rack = ? AND bin = ?`
+	err := r.db.QueryRowContext(ctx, query, b.WarehouseID, b.Zone, b.Aisle, b.Rack, b.Bin).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetContents returns what a bin holds, by product name.
+func (r *BinRepository) GetContents(ctx context.Context, binID int64) ([]*model.BinItem, error) {
+	query := `
+		SELECT bs.product_id, p.sku, p.name, bs.variant_id, v.sku, bs.stock
+		FROM bin_stock bs
+		JOIN products p ON p.id = bs.product_id
+		LEFT JOIN product_variants v ON v.id = bs.variant_id
+		WHERE bs.bin_id = ? AND bs.stock > 0
+		ORDER BY p.name, bs.variant_key
+	`
+	rows, err := r.db.QueryContext(ctx, query, binID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bin contents: %w", err)
+	}
+	defer rows.Close()
+
+	items := []*model.BinItem{}
+	for rows.Next() {
+		var (
+			item                   model.BinItem
+			productSKU, variantSKU sql.NullString
+			variantID              sql.NullInt64
+		)
+		if err := rows.Scan(&item.ProductID, &productSKU, &item.ProductName, &variantID, &variantSKU, &item.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan bin contents: %w", err)
+		}
+		item.ProductSKU = productSKU.String
+		item.VariantID = variantID.Int64
+		item.VariantSKU = variantSKU.String
+		items = append(items, &item)
+	}
+	return items, rows.Err()
+}
+
+// GetItemBins returns the bins of a warehouse holding a product, or a
+// variant of it, in walking order with what each holds of it.
+func (r *BinRepository) GetItemBins(ctx context.Context, warehouseID, productID, variantID int64) ([]model.BinQuantity, error) {
+	return itemBins(ctx, r.db, "", warehouseID, productID, variantID)
+}
+
+// itemBins is GetItemBins through q, with suffix appended to the query.
+func itemBins(ctx context.Context, q queryer, suffix string, warehouseID, productID, variantID int64) ([]model.BinQuantity, error) {
+	query := `
+		SELECT b.id, ` + binCode + `, bs.stock
+		FROM bin_stock bs
+		JOIN bins b ON b.id = bs.bin_id
+		WHERE b.warehouse_id = ? AND bs.product_id = ? AND bs.variant_key = ? AND bs.stock > 0
+		ORDER BY ` + binOrder + suffix
+	rows, err := q.QueryContext(ctx, query, warehouseID, productID, variantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query item bins: %w", err)
+	}
+	defer rows.Close()
+
+	var bins []model.BinQuantity
+	for rows.Next() {
+		var b model.BinQuantity
+		if err := rows.Scan(&b.BinID, &b.BinCode, &b.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan item bins: %w", err)
+		}
+		bins = append(bins, b)
+	}
+	return bins, rows.Err()
+}
+
+// fitDefaultBins takes stock of a product, or of a variant of it, out of the
+// bins of the default warehouse in walking order until they hold no more
+// than the warehouse does. It keeps bins right after settleDefaultStock or
+// settleDefaultVariantStock lowered that stock.
+func fitDefaultBins(ctx context.Context, tx *sql.Tx, productID, variantID int64) error {
+	var (
+		warehouseID int64
+		stock       decimal.Decimal
+	)
+	query := `
+		SELECT w.id, COALESCE(s.stock, 0)
+		FROM warehouses w
+		LEFT JOIN product_stock s ON s.warehouse_id = w.id AND s.product_id = ?
+		WHERE w.is_default
+	`
+	ownerID := productID
+	if variantID != 0 {
+		query = `
+			SELECT w.id, COALESCE(s.stock, 0)
+			FROM warehouses w
+			LEFT JOIN variant_stock s ON s.warehouse_id = w.id AND s.variant_id = ?
+			WHERE w.is_default
+		`
+		ownerID = variantID
+	}
+	err := tx.QueryRowContext(ctx, query, ownerID).Scan(&warehouseID, &stock)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get default warehouse stock: %w", err)
+	}
+
+	bins, err := itemBins(ctx, tx, ` FOR UPDATE OF bs`, warehouseID, productID, variantID)
+	if err != nil {
+		return err
+	}
+	excess := stock.Neg()
+	for _, b := range bins {
+		excess = excess.Add(b.Quantity)
+	}
+	for _, b := range bins {
+		if !excess.IsPositive() {
+			break
+		}
+		if b.Quantity.Cmp(excess) > 0 {
+			b.Quantity = excess
+		}
+		if err := takeBinStock(ctx, tx, b.BinID, productID, variantID, b.Quantity); err != nil {
+			return fmt.Errorf("failed to update bin stock: %w", err)
+		}
+		excess = excess.Sub(b.Quantity)
+	}
+	return nil
+}
+
+func scanBin(row rowScanner) (*model.Bin, error) {
+	var b model.Bin
+	if err := row.Scan(&b.ID, &b.WarehouseID, &b.Zone, &b.Aisle, &b.Rack, &b.Bin, &b.Code, &b.Capacity, &b.Used); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// LockedBin is the part of a bin a putaway needs.
+type LockedBin struct {
+	WarehouseID int64
+	Code        string
+	Capacity    *decimal.Decimal
+	Used        decimal.Decimal
+}
+
+// GetBinForUpdate locks a bin for the rest of tx and returns it with how
+// much it holds. Putaways lock the bin first, so they check its capacity
+// one at a time.
+func (r *TransactionRepository) GetBinForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*LockedBin, error) {
+	var b LockedBin
+	query := `SELECT b.warehouse_id, ` + binCode + `, b.capacity FROM bins b WHERE b.id = ? FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&b.WarehouseID, &b.Code, &b.Capacity); err != nil {
+		return nil, err
+	}
+	query = `SELECT COALESCE(SUM(stock), 0) FROM bin_stock WHERE bin_id = ?`
+	if err := tx.QueryRowContext(ctx, query, id).Scan(&b.Used); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// FindPutawayBin returns a bin of a warehouse with room for quantity of
+// an item, or 0 if there is none. Bins already holding the item come
+// first, then empty bins, each in walking order.
+func (r *TransactionRepository) FindPutawayBin(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64, quantity decimal.Decimal) (int64, error) {
+	var (
+		id         int64
+		used, held decimal.Decimal
+	)
+	query := `
+		SELECT b.id, COALESCE(SUM(bs.stock), 0) AS used,
+			COALESCE(SUM(CASE WHEN bs.product_id = ? AND bs.variant_key = ? THEN bs.stock ELSE 0 END), 0) AS held
+		FROM bins b
+		LEFT JOIN bin_stock bs ON bs.bin_id = b.id
+		WHERE b.warehouse_id = ?
+		GROUP BY b.id
+		HAVING (b.capacity IS NULL OR b.capacity - used >= ?) AND (held > 0 OR used = 0)
+		ORDER BY held > 0 DESC, ` + binOrder + `
+		LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, productID, variantID, warehouseID, quantity).Scan(&id, &used, &held)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// GetItemBinsForUpdate is BinRepository.GetItemBins inside tx, locking the
+// item's stock in those bins.
+func (r *TransactionRepository) GetItemBinsForUpdate(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64) ([]model.BinQuantity, error) {
+	return itemBins(ctx, tx, ` FOR UPDATE OF bs`, warehouseID, productID, variantID)
+}
+
+// AddBinStock puts quantity of an item into a bin.
+func (r *TransactionRepository) AddBinStock(ctx context.Context, tx *sql.Tx, binID, productID, variantID int64, quantity decimal.Decimal) error {
+	query := `
+		INSERT INTO bin_stock (bin_id, product_id, variant_id, stock) VALUES (?, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE stock = stock + VALUES(stock)
+	`
+	_, err := tx.ExecContext(ctx, query, binID, productID, nullInt64(variantID), quantity)
+	return err
+}
+
+// TakeBinStock takes quantity of an item out of a bin.
+func (r *TransactionRepository) TakeBinStock(ctx context.Context, tx *sql.Tx, binID, productID, variantID int64, quantity decimal.Decimal) error {
+	return takeBinStock(ctx, tx, binID, productID, variantID, quantity)
+}
+
+func takeBinStock(ctx context.Context, tx *sql.Tx, binID, productID, variantID int64, quantity decimal.Decimal) error {
+	query := `UPDATE bin_stock SET stock = stock - ? WHERE bin_id = ? AND product_id = ? AND variant_key = ?`
+	_, err := tx.ExecContext(ctx, query, quantity, binID, productID, variantID)
+	return err
+}
+
+// InsertItemBin records that a transaction item put away or picked
+// quantity in a bin.
+func (r *TransactionRepository) InsertItemBin(ctx context.Context, tx *sql.Tx, itemID int64, b model.BinQuantity) error {
+	query := `INSERT INTO transaction_item_bins (transaction_item_id, bin_id, quantity) VALUES (?, ?, ?)`
+	_, err := tx.ExecContext(ctx, query, itemID, b.BinID, b.Quantity)
+	return err
+}
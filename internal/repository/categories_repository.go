@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 )
@@ -30,7 +31,7 @@ func (r *CategoriesRepository) InsertCategories(ctx context.Context, tx *model.C
 
 func (r *CategoriesRepository) GetAllCategories(ctx context.Context) ([]*model.Categories, error) {
 	query := `
-		SELECT id, name, description
+		SELECT id, name, description, version
 		FROM categories
 		ORDER BY created_at DESC
 	`
@@ -44,7 +45,7 @@ func (r *CategoriesRepository) GetAllCategories(ctx context.Context) ([]*model.C
 	var results []*model.Categories
 	for rows.Next() {
 		var t model.Categories
-		err := rows.Scan(&t.ID, &t.Name, &t.Description)
+		err := rows.Scan(&t.ID, &t.Name, &t.Description, &t.Version)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
 		}
@@ -56,13 +57,13 @@ func (r *CategoriesRepository) GetAllCategories(ctx context.Context) ([]*model.C
 
 func (r *CategoriesRepository) GetCategoryByID(ctx context.Context, id int64) (*model.Categories, error) {
 	query := `
-		SELECT id, name, description
+		SELECT id, name, description, version
 		FROM categories
 		WHERE id = ?
 	`
 
 	var c model.Categories
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.Description)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&c.ID, &c.Name, &c.Description, &c.Version)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -72,25 +73,72 @@ func (r *CategoriesRepository) GetCategoryByID(ctx context.Context, id int64) (*
 	return &c, nil
 }
 
-func (r *CategoriesRepository) UpdateCategory(ctx context.Context, category *model.Categories) error {
+// UpdateCategory applies the change when category.Version is current, or
+// unconditionally when it is 0, and reports whether a row was updated.
+// category.Version is set to the new version.
+func (r *CategoriesRepository) UpdateCategory(ctx context.Context, category *model.Categories) (bool, error) {
 	query := `
 		UPDATE categories
-		SET name = ?, description = ?, updated_at = CURRENT_TIMESTAMP
-		WHERE id = ?
+		SET name = ?, description = ?, version = version + 1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = ? AND (? = 0 OR version = ?)
 	`
-	_, err := r.db.ExecContext(ctx, query, category.Name, category.Description, category.ID)
+	res, err := r.db.ExecContext(ctx, query, category.Name, category.Description, category.ID, category.Version, category.Version)
 	if err != nil {
-		return fmt.Errorf("failed to update category: %w", err)
+		return false, fmt.Errorf("failed to update category: %w", err)
 	}
-	return nil
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := r.db.QueryRowContext(ctx, `SELECT version FROM categories WHERE id = ?`, category.ID).Scan(&category.Version); err != nil {
+		return false, fmt.Errorf("failed to get category version: %w", err)
+	}
+	return true, nil
+}
+
+// PatchCategory writes only the given fields, named as in the API, with the
+// same version rule as UpdateCategory.
+func (r *CategoriesRepository) PatchCategory(ctx context.Context, category *model.Categories, fields []string) (bool, error) {
+	values := map[string]any{
+		"name":        category.Name,
+		"description": category.Description,
+	}
+
+	var (
+		set  []string
+		args []any
+	)
+	for _, field := range fields {
+		if value, ok := values[field]; ok {
+			set = append(set, field+" = ?")
+			args = append(args, value)
+		}
+	}
+	set = append(set, "version = version + 1", "updated_at = CURRENT_TIMESTAMP")
+	args = append(args, category.ID, category.Version, category.Version)
+
+	query := `UPDATE categories SET ` + strings.Join(set, ", ") + ` WHERE id = ? AND (? = 0 OR version = ?)`
+	res, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to patch category: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := r.db.QueryRowContext(ctx, `SELECT version FROM categories WHERE id = ?`, category.ID).Scan(&category.Version); err != nil {
+		return false, fmt.Errorf("failed to get category version: %w", err)
+	}
+	return true, nil
 }
 
-func (r *CategoriesRepository) DeleteCategory(ctx context.Context, id int64) error {
-	query := `DELETE FROM categories WHERE id = ?`
+func (r *CategoriesRepository) DeleteCategory(ctx context.Context, id int64, version int) (bool, error) {
+	query := `DELETE FROM categories WHERE id = ? AND (? = 0 OR version = ?)`
 
-	_, err := r.db.ExecContext(ctx, query, id)
+	res, err := r.db.ExecContext(ctx, query, id, version, version)
 	if err != nil {
-		return fmt.Errorf("failed to delete category: %w", err)
+		return false, fmt.Errorf("failed to delete category: %w", err)
 	}
-	return nil
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
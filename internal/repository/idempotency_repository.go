@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+// ErrDuplicateKey is returned by Insert when the key was stored by a
+// concurrent request first.
+var ErrDuplicateKey = errors.New("duplicate idempotency key")
+
+type IdempotencyRepository struct {
+	db *sql.DB
+}
+
+func NewIdempotencyRepository(db *sql.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Get returns the record for a user's key stored after since, or nil.
+// Older records are deleted so the key can be used again.
+func (r *IdempotencyRepository) Get(ctx context.Context, userID int64, key string, since time.Time) (*model.IdempotencyRecord, error) {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE user_id = ? AND idempotency_key = ? AND created_at < ?`, userID, key, since)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT user_id, idempotency_key, request_hash, transaction_id, response_status, response_body, created_at
+		FROM idempotency_keys
+		WHERE user_id = ? AND idempotency_key = ?
+	`
+	var rec model.IdempotencyRecord
+	err = r.db.QueryRowContext(ctx, query, userID, key).Scan(&rec.UserID, &rec.Key, &rec.RequestHash, &rec.TransactionID,
+		&rec.ResponseStatus, &rec.ResponseBody, &rec.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Insert stores a record within tx, so it commits together with the
+// transaction it describes.
+func (r *IdempotencyRepository) Insert(ctx context.Context, tx *sql.Tx, rec *model.IdempotencyRecord) error {
+	query := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, transaction_id, response_status, response_body, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := tx.ExecContext(ctx, query, rec.UserID, rec.Key, rec.RequestHash, rec.TransactionID, rec.ResponseStatus, rec.ResponseBody, rec.CreatedAt)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 {
+		return ErrDuplicateKey
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type ProductHistoryRepository struct {
+	db *sql.DB
+}
+
+func NewProductHistoryRepository(db *sql.DB) *ProductHistoryRepository {
+	return &ProductHistoryRepository{db: db}
+}
+
+// GetByProductID returns every revision of a product, oldest first.
+func (r *ProductHistoryRepository) GetByProductID(ctx context.Context, productID int64) ([]*model.ProductRevision, error) {
+	query := `
+		SELECT product_id, revision, product_version, snapshot, valid_from
+		FROM product_history
+		WHERE product_id = ?
+		ORDER BY revision
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product history: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []*model.ProductRevision
+	for rows.Next() {
+		v, err := scanProductRevision(rows)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, v)
+	}
+	return revisions, rows.Err()
+}
+
+// GetAsOf returns the revision in effect at the given time, or nil if the
+// product did not exist yet.
+func (r *ProductHistoryRepository) GetAsOf(ctx context.Context, productID int64, asOf time.Time) (*model.ProductRevision, error) {
+	query := `
+		SELECT product_id, revision, product_version, snapshot, valid_from
+		FROM product_history
+		WHERE product_id = ? AND valid_from <= ?
+		ORDER BY revision DESC
+		LIMIT 1
+	`
+	v, err := scanProductRevision(r.db.QueryRowContext(ctx, query, productID, asOf.UTC()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+// recordProductRevision snapshots the product row as a new revision unless
+// it matches the latest one, noting the product version it was taken at. It
+// runs inside the transaction that changed the product so the history never
+// misses a change.
+func recordProductRevision(ctx context.Context, tx *sql.Tx, productID int64) error {
+	var (
+		s              model.ProductSnapshot
+		sku            sql.NullString
+		barcode        sql.NullString
+		desc           sql.NullString
+		image          sql.NullString
+		productVersion int
+	)
+	query := `
+		SELECT sku, barcode, name, description, image_url, category_id, base_unit, quantity_precision, currency, version
+		FROM products WHERE id = ? FOR UPDATE
+	`
+	err := tx.QueryRowContext(ctx, query, productID).Scan(&sku, &barcode, &s.Name, &desc, &image, &s.CategoryID, &s.BaseUnit, &s.Precision, &s.Currency,
+		&productVersion)
+	if err != nil {
+		return fmt.Errorf("failed to read product for history: %w", err)
+	}
+	s.SKU, s.Barcode, s.Description, s.ImageURL = sku.String, barcode.String, desc.String, image.String
+
+	snapshot, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("failed to encode product snapshot: %w", err)
+	}
+
+	var (
+		revision int
+		last     []byte
+	)
+	err = tx.QueryRowContext(ctx, `SELECT revision, snapshot FROM product_history WHERE product_id = ? ORDER BY revision DESC LIMIT 1`, productID).Scan(&revision, &last)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get latest product revision: %w", err)
+	}
+	if err == nil {
+		var previous model.ProductSnapshot
+		if err := json.Unmarshal(last, &previous); err == nil && previous == s {
+			return nil
+		}
+	}
+
+	insert := `INSERT INTO product_history (product_id, revision, product_version, snapshot, valid_from) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, insert, productID, revision+1, productVersion, snapshot, time.Now().UTC().Truncate(time.Second)); err != nil {
+		return fmt.Errorf("failed to record product revision: %w", err)
+	}
+	return nil
+}
+
+func scanProductRevision(row rowScanner) (*model.ProductRevision, error) {
+	var (
+		v        model.ProductRevision
+		snapshot []byte
+	)
+	if err := row.Scan(&v.ProductID, &v.Revision, &v.ProductVersion, &snapshot, &v.ValidFrom); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(snapshot, &v.Product); err != nil {
+		return nil, fmt.Errorf("failed to decode product snapshot: %w", err)
+	}
+	return &v, nil
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type ProductImageRepository struct {
+	db *sql.DB
+}
+
+func NewProductImageRepository(db *sql.DB) *ProductImageRepository {
+	return &ProductImageRepository{db: db}
+}
+
+func (r *ProductImageRepository) Insert(ctx context.Context, img *model.ProductImage) (int64, error) {
+	query := `
+		INSERT INTO product_images (
+			product_id, position, storage_key, thumbnail_key, content_type, size_bytes, width, height
+		)
+		SELECT ?, COALESCE(MAX(position) + 1, 0), ?, ?, ?, ?, ?, ?
+		FROM product_images WHERE product_id = ?
+	`
+	res, err := r.db.ExecContext(ctx, query,
+		img.ProductID,
+		img.StorageKey,
+		img.ThumbnailKey,
+		img.ContentType,
+		img.SizeBytes,
+		img.Width,
+		img.Height,
+		img.ProductID,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert product image: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *ProductImageRepository) GetByProductID(ctx context.Context, productID int64) ([]*model.ProductImage, error) {
+	query := `
+		SELECT id, product_id, position, storage_key, thumbnail_key, content_type, size_bytes, width, height, created_at
+		FROM product_images
+		WHERE product_id = ?
+		ORDER BY position, id
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []*model.ProductImage
+	for rows.Next() {
+		var img model.ProductImage
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.Position, &img.StorageKey, &img.ThumbnailKey,
+			&img.ContentType, &img.SizeBytes, &img.Width, &img.Height, &img.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan product image: %w", err)
+		}
+		images = append(images, &img)
+	}
+	return images, rows.Err()
+}
+
+func (r *ProductImageRepository) Delete(ctx context.Context, productID, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM product_images WHERE id = ? AND product_id = ?`, id, productID)
+	if err != nil {
+		return fmt.Errorf("failed to delete product image: %w", err)
+	}
+	return nil
+}
+
+// Reorder assigns positions following the order of ids.
+func (r *ProductImageRepository) Reorder(ctx context.Context, productID int64, ids []int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for pos, id := range ids {
+		_, err := tx.ExecContext(ctx, `UPDATE product_images SET position = ? WHERE id = ? AND product_id = ?`, pos, id, productID)
+		if err != nil {
+			return fmt.Errorf("failed to reorder product images: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SetPrimaryImageURL keeps products.image_url pointing at the first image.
+func (r *ProductImageRepository) SetPrimaryImageURL(ctx context.Context, productID int64, url string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `UPDATE products SET image_url = ?, version = version + 1 WHERE id = ?`, url, productID); err != nil {
+		return fmt.Errorf("failed to update product image url: %w", err)
+	}
+	if err := recordProductRevision(ctx, tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
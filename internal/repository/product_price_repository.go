@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+// effectivePriceJoin attaches the price entry in effect right now to the
+// products table aliased as p. Times are stored in UTC, matching the driver.
+const effectivePriceJoin = `
+	LEFT JOIN product_prices pp ON pp.id = (
+		SELECT id FROM product_prices
+		WHERE product_id = p.id AND effective_from <= UTC_TIMESTAMP()
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	)`
+
+type ProductPriceRepository struct {
+	db *sql.DB
+}
+
+func NewProductPriceRepository(db *sql.DB) *ProductPriceRepository {
+	return &ProductPriceRepository{db: db}
+}
+
+func (r *ProductPriceRepository) Insert(ctx context.Context, price *model.ProductPrice) (int64, error) {
+	query := `INSERT INTO product_prices (product_id, price, cost, currency, effective_from) VALUES (?, ?, ?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, price.ProductID, price.Price, price.Cost, price.Currency, price.EffectiveFrom.UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert product price: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+// GetByProductID returns the price history, latest effective date first.
+func (r *ProductPriceRepository) GetByProductID(ctx context.Context, productID int64) ([]*model.ProductPrice, error) {
+	query := `
+		SELECT id, product_id, price, cost, currency, effective_from, created_at
+		FROM product_prices
+		WHERE product_id = ?
+		ORDER BY effective_from DESC, id DESC
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product prices: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []*model.ProductPrice
+	for rows.Next() {
+		var p model.ProductPrice
+		if err := rows.Scan(&p.ID, &p.ProductID, &p.Price, &p.Cost, &p.Currency, &p.EffectiveFrom, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		prices = append(prices, &p)
+	}
+	return prices, rows.Err()
+}
+
+// recordPriceChange adds a history entry effective now when the product's
+// price, cost or currency differs from the entry currently in effect.
+func recordPriceChange(ctx context.Context, tx *sql.Tx, p *model.Product) error {
+	var (
+		price, cost int64
+		currency    string
+	)
+	query := `
+		SELECT price, cost, currency FROM product_prices
+		WHERE product_id = ? AND effective_from <= UTC_TIMESTAMP()
+		ORDER BY effective_from DESC, id DESC
+		LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, p.ID).Scan(&price, &cost, &currency)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get current price: %w", err)
+	}
+	if err == nil && price == p.Price && cost == p.Cost && currency == p.Currency {
+		return nil
+	}
+
+	insert := `INSERT INTO product_prices (product_id, price, cost, currency, effective_from) VALUES (?, ?, ?, ?, ?)`
+	if _, err := tx.ExecContext(ctx, insert, p.ID, p.Price, p.Cost, p.Currency, time.Now().UTC().Truncate(time.Second)); err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
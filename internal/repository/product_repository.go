@@ -3,10 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/go-sql-driver/mysql"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 )
 
+const productColumns = `p.id, p.sku, p.barcode, p.name, p.description, p.image_url, p.category_id, p.base_unit, p.quantity_precision, p.stock,
+	COALESCE(pp.price, 0), COALESCE(pp.cost, 0), COALESCE(pp.currency, p.currency), p.archived_at, p.version`
+
+const productFrom = ` FROM products p` + effectivePriceJoin
+
 type ProductRepository struct {
 	db *sql.DB
 }
@@ -15,14 +25,74 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (r *ProductRepository) Insert(ctx context.Context, p *model.Product) error {
-	query := `INSERT INTO products (name, description, image_url, category_id, stock) VALUES (?, ?, ?, ?, ?)`
-	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.ImageURL, p.CategoryID, p.Stock)
+// DuplicateProductError is returned by writes that would give a product the
+// sku or barcode of another one, e.g. when two requests race past the
+// service's check.
+type DuplicateProductError struct {
+	Field string
+	Value string
+}
+
+func (e *DuplicateProductError) Error() string {
+	return fmt.Sprintf("%s %s is already used by another product", e.Field, e.Value)
+}
+
+// duplicateProduct turns a unique key violation on the sku or barcode of p
+// into a *DuplicateProductError and returns any other error as is.
+func duplicateProduct(err error, p *model.Product) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) || mysqlErr.Number != 1062 {
+		return err
+	}
+	switch {
+	case strings.Contains(mysqlErr.Message, "barcode'"):
+		return &DuplicateProductError{Field: "barcode", Value: p.Barcode}
+	case strings.Contains(mysqlErr.Message, "sku'"):
+		return &DuplicateProductError{Field: "sku", Value: p.SKU}
+	}
 	return err
 }
 
-func (r *ProductRepository) GetAll(ctx context.Context) ([]*model.Product, error) {
-	rows, err := r.db.QueryContext(ctx, `SELECT id, name, description, image_url, category_id, stock FROM products`)
+func (r *ProductRepository) Insert(ctx context.Context, p *model.Product) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO products (sku, barcode, name, description, image_url, category_id, base_unit, quantity_precision, stock, currency) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, query, nullString(p.SKU), nullString(p.Barcode), p.Name, p.Description, p.ImageURL, p.CategoryID, p.BaseUnit, p.Precision, p.Stock, p.Currency)
+	if err != nil {
+		return duplicateProduct(err, p)
+	}
+	p.ID, err = res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	if err := settleDefaultStock(ctx, tx, p.ID); err != nil {
+		return err
+	}
+	if err := recordPriceChange(ctx, tx, p); err != nil {
+		return err
+	}
+	if err := valueDirectStock(ctx, tx, p.ID); err != nil {
+		return err
+	}
+	if err := recordProductRevision(ctx, tx, p.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetAll lists products, leaving out archived ones unless includeArchived.
+func (r *ProductRepository) GetAll(ctx context.Context, includeArchived bool) ([]*model.Product, error) {
+	query := `SELECT ` + productColumns + productFrom
+	if !includeArchived {
+		query += ` WHERE p.archived_at IS NULL`
+	}
+
+	rows, err := r.db.QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -30,36 +100,339 @@ func (r *ProductRepository) GetAll(ctx context.Context) ([]*model.Product, error
 
 	var products []*model.Product
 	for rows.Next() {
-		var p model.Product
-		if err := rows.Scan(&p.ID, &p.Name, &p.Description, &p.ImageURL, &p.CategoryID, &p.Stock); err != nil {
+		p, err := scanProduct(rows)
+		if err != nil {
 			return nil, err
 		}
-		products = append(products, &p)
+		products = append(products, p)
 	}
 	return products, nil
 }
 
+// Export streams products with their category names to fn in ID order,
+// reading from an open cursor instead of loading them all. Archived
+// products are left out unless includeArchived; an error from fn stops the
+// export and is returned.
+func (r *ProductRepository) Export(ctx context.Context, includeArchived bool, fn func(*model.ProductExport) error) error {
+	query := `SELECT ` + productColumns + `, COALESCE(c.name, '')` + productFrom + ` LEFT JOIN categories c ON c.id = p.category_id`
+	if !includeArchived {
+		query += ` WHERE p.archived_at IS NULL`
+	}
+	query += ` ORDER BY p.id`
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var categoryName string
+		p, err := scanProduct(rows, &categoryName)
+		if err != nil {
+			return err
+		}
+		err = fn(&model.ProductExport{
+			ID:           p.ID,
+			SKU:          p.SKU,
+			Barcode:      p.Barcode,
+			Name:         p.Name,
+			Description:  p.Description,
+			CategoryID:   p.CategoryID,
+			CategoryName: categoryName,
+			BaseUnit:     p.BaseUnit,
+			Precision:    p.Precision,
+			Stock:        p.Stock,
+			Price:        p.Price,
+			Cost:         p.Cost,
+			Currency:     p.Currency,
+			ImageURL:     p.ImageURL,
+			ArchivedAt:   p.ArchivedAt,
+			Version:      p.Version,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (r *ProductRepository) GetByID(ctx context.Context, id int64) (*model.Product, error) {
-	var p model.Product
-	err := r.db.QueryRowContext(ctx, `SELECT id, name, description, image_url, category_id, stock FROM products WHERE id = ?`, id).
-		Scan(&p.ID, &p.Name, &p.Description, &p.ImageURL, &p.CategoryID, &p.Stock)
-	if err == sql.ErrNoRows {
+	return r.getOne(ctx, `SELECT `+productColumns+productFrom+` WHERE p.id = ?`, id)
+}
+
+func (r *ProductRepository) GetByIDs(ctx context.Context, ids []int64) ([]*model.Product, error) {
+	if len(ids) == 0 {
 		return nil, nil
 	}
-	return &p, err
+
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+
+	rows, err := r.db.QueryContext(ctx, `SELECT `+productColumns+productFrom+` WHERE p.id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var products []*model.Product
+	for rows.Next() {
+		p, err := scanProduct(rows)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
+
+func (r *ProductRepository) GetBySKU(ctx context.Context, sku string) (*model.Product, error) {
+	return r.getOne(ctx, `SELECT `+productColumns+productFrom+` WHERE p.sku = ?`, sku)
+}
+
+func (r *ProductRepository) GetByBarcode(ctx context.Context, barcode string) (*model.Product, error) {
+	return r.getOne(ctx, `SELECT `+productColumns+productFrom+` WHERE p.barcode = ?`, barcode)
 }
 
-func (r *ProductRepository) Update(ctx context.Context, p *model.Product) error {
+// Update replaces a product when p.Version is its current version, or
+// unconditionally when p.Version is 0. It reports whether the product was
+// updated and sets p.Version to the new version. Stock below what other
+// warehouses hold returns a *StockBelowOutsideError.
+func (r *ProductRepository) Update(ctx context.Context, p *model.Product) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := checkStockOutsideDefault(ctx, tx, p.ID, 0, p.Stock); err != nil {
+		return false, err
+	}
+
 	query := `
 		UPDATE products
-		SET name = ?, description = ?, image_url = ?, category_id = ?, stock = ?
-		WHERE id = ?
+		SET sku = ?, barcode = ?, name = ?, description = ?, image_url = ?, category_id = ?, base_unit = ?, quantity_precision = ?, stock = ?, currency = ?,
+			version = version + 1
+		WHERE id = ? AND (? = 0 OR version = ?)
 	`
-	_, err := r.db.ExecContext(ctx, query, p.Name, p.Description, p.ImageURL, p.CategoryID, p.Stock, p.ID)
-	return err
+	res, err := tx.ExecContext(ctx, query, nullString(p.SKU), nullString(p.Barcode), p.Name, p.Description, p.ImageURL, p.CategoryID, p.BaseUnit, p.Precision, p.Stock, p.Currency,
+		p.ID, p.Version, p.Version)
+	if err != nil {
+		return false, duplicateProduct(err, p)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := settleDefaultStock(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := fitDefaultBins(ctx, tx, p.ID, 0); err != nil {
+		return false, err
+	}
+	if err := recordPriceChange(ctx, tx, p); err != nil {
+		return false, err
+	}
+	if err := valueDirectStock(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := recordProductRevision(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT version FROM products WHERE id = ?`, p.ID).Scan(&p.Version); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
 }
 
-func (r *ProductRepository) Delete(ctx context.Context, id int64) error {
-	_, err := r.db.ExecContext(ctx, `DELETE FROM products WHERE id = ?`, id)
-	return err
+// Patch writes only the given fields of p, named as in the API, with the
+// same version rule as Update. Price, cost and currency changes are also
+// recorded in the price history.
+func (r *ProductRepository) Patch(ctx context.Context, p *model.Product, fields []string) (bool, error) {
+	values := map[string]any{
+		"sku":                nullString(p.SKU),
+		"barcode":            nullString(p.Barcode),
+		"name":               p.Name,
+		"description":        p.Description,
+		"image_url":          p.ImageURL,
+		"category_id":        p.CategoryID,
+		"base_unit":          p.BaseUnit,
+		"quantity_precision": p.Precision,
+		"stock":              p.Stock,
+		"currency":           p.Currency,
+	}
+
+	var (
+		set  []string
+		args []any
+	)
+	for _, field := range fields {
+		value, ok := values[field]
+		if !ok {
+			continue
+		}
+		set = append(set, field+" = ?")
+		args = append(args, value)
+	}
+	set = append(set, "version = version + 1")
+	args = append(args, p.ID, p.Version, p.Version)
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if err := checkStockOutsideDefault(ctx, tx, p.ID, 0, p.Stock); err != nil {
+		return false, err
+	}
+
+	query := `UPDATE products SET ` + strings.Join(set, ", ") + ` WHERE id = ? AND (? = 0 OR version = ?)`
+	res, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, duplicateProduct(err, p)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := settleDefaultStock(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := fitDefaultBins(ctx, tx, p.ID, 0); err != nil {
+		return false, err
+	}
+	if err := recordPriceChange(ctx, tx, p); err != nil {
+		return false, err
+	}
+	if err := valueDirectStock(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := recordProductRevision(ctx, tx, p.ID); err != nil {
+		return false, err
+	}
+	if err := tx.QueryRowContext(ctx, `SELECT version FROM products WHERE id = ?`, p.ID).Scan(&p.Version); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetArchived archives or restores a product, subject to the same version
+// check as Update.
+func (r *ProductRepository) SetArchived(ctx context.Context, id int64, archived bool, version int) (bool, error) {
+	query := `UPDATE products SET archived_at = NULL, version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)`
+	if archived {
+		query = `UPDATE products SET archived_at = COALESCE(archived_at, UTC_TIMESTAMP()), version = version + 1 WHERE id = ? AND (? = 0 OR version = ?)`
+	}
+	res, err := r.db.ExecContext(ctx, query, id, version, version)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetStockByWarehouse returns, per product, the warehouses holding any of
+// its stock.
+func (r *ProductRepository) GetStockByWarehouse(ctx context.Context, productIDs []int64) (map[int64][]*model.WarehouseStock, error) {
+	if len(productIDs) == 0 {
+		return map[int64][]*model.WarehouseStock{}, nil
+	}
+
+	args := make([]any, len(productIDs))
+	for i, id := range productIDs {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(productIDs)), ", ")
+
+	query := `
+		SELECT s.product_id, ` + warehouseStockColumns + `
+		FROM product_stock s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.product_id IN (` + placeholders + `) AND s.stock <> 0
+		ORDER BY s.product_id, w.code
+	`
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouse stock: %w", err)
+	}
+	return scanWarehouseStock(rows)
+}
+
+// GetStockOutsideDefault returns the stock of a product held at warehouses
+// other than the default one or in transit.
+func (r *ProductRepository) GetStockOutsideDefault(ctx context.Context, productID int64) (decimal.Decimal, error) {
+	return stockOutsideDefault(ctx, r.db, "product_stock", "product_id", productID)
+}
+
+func (r *ProductRepository) HasTransactions(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM transaction_items WHERE product_id = ?)`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check product transactions: %w", err)
+	}
+	return exists, nil
+}
+
+// Delete removes a product at the given version (0 for any) and reports
+// whether it was deleted.
+func (r *ProductRepository) Delete(ctx context.Context, id int64, version int) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_variants WHERE product_id = ?`, id); err != nil {
+		return false, fmt.Errorf("failed to delete product variants: %w", err)
+	}
+	res, err := tx.ExecContext(ctx, `DELETE FROM products WHERE id = ? AND (? = 0 OR version = ?)`, id, version, version)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *ProductRepository) getOne(ctx context.Context, query string, args ...any) (*model.Product, error) {
+	p, err := scanProduct(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProduct reads productColumns, followed by any extra columns into
+// extra.
+func scanProduct(row rowScanner, extra ...any) (*model.Product, error) {
+	var (
+		p       model.Product
+		sku     sql.NullString
+		barcode sql.NullString
+	)
+	dest := append([]any{&p.ID, &sku, &barcode, &p.Name, &p.Description, &p.ImageURL, &p.CategoryID, &p.BaseUnit, &p.Precision, &p.Stock,
+		&p.Price, &p.Cost, &p.Currency, &p.ArchivedAt, &p.Version}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	p.SKU = sku.String
+	p.Barcode = barcode.String
+	return &p, nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+func nullInt64(n int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: n, Valid: n != 0}
 }
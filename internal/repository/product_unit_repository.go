@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type ProductUnitRepository struct {
+	db *sql.DB
+}
+
+func NewProductUnitRepository(db *sql.DB) *ProductUnitRepository {
+	return &ProductUnitRepository{db: db}
+}
+
+func (r *ProductUnitRepository) Insert(ctx context.Context, u *model.ProductUnit) (int64, error) {
+	query := `INSERT INTO product_units (product_id, name, factor) VALUES (?, ?, ?)`
+	res, err := r.db.ExecContext(ctx, query, u.ProductID, u.Name, u.Factor)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert product unit: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *ProductUnitRepository) GetByProductID(ctx context.Context, productID int64) ([]*model.ProductUnit, error) {
+	query := `
+		SELECT id, product_id, name, factor
+		FROM product_units
+		WHERE product_id = ?
+		ORDER BY factor, name
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product units: %w", err)
+	}
+	defer rows.Close()
+
+	var units []*model.ProductUnit
+	for rows.Next() {
+		var u model.ProductUnit
+		if err := rows.Scan(&u.ID, &u.ProductID, &u.Name, &u.Factor); err != nil {
+			return nil, fmt.Errorf("failed to scan product unit: %w", err)
+		}
+		units = append(units, &u)
+	}
+	return units, rows.Err()
+}
+
+func (r *ProductUnitRepository) GetByName(ctx context.Context, productID int64, name string) (*model.ProductUnit, error) {
+	query := `SELECT id, product_id, name, factor FROM product_units WHERE product_id = ? AND name = ?`
+
+	var u model.ProductUnit
+	err := r.db.QueryRowContext(ctx, query, productID, name).Scan(&u.ID, &u.ProductID, &u.Name, &u.Factor)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product unit: %w", err)
+	}
+	return &u, nil
+}
+
+func (r *ProductUnitRepository) Delete(ctx context.Context, productID, id int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM product_units WHERE id = ? AND product_id = ?`, id, productID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete product unit: %w", err)
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
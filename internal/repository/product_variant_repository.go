@@ -0,0 +1,258 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type ProductVariantRepository struct {
+	db *sql.DB
+}
+
+// ErrProductInUse is returned by Insert for the first variant of a product
+// that holds stock or has stock movements of its own.
+var ErrProductInUse = errors.New("product has stock or stock movements")
+
+func NewProductVariantRepository(db *sql.DB) *ProductVariantRepository {
+	return &ProductVariantRepository{db: db}
+}
+
+func (r *ProductVariantRepository) Insert(ctx context.Context, v *model.ProductVariant) (int64, error) {
+	options, err := json.Marshal(v.Options)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode variant options: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// With its first variant a product's stock becomes the sum of theirs,
+	// which would drop whatever the product holds itself.
+	var (
+		stock             decimal.Decimal
+		hasVariants, used bool
+	)
+	query := `
+		SELECT p.stock,
+			EXISTS(SELECT 1 FROM product_variants WHERE product_id = p.id),
+			EXISTS(SELECT 1 FROM transaction_items WHERE product_id = p.id)
+		FROM products p
+		WHERE p.id = ?
+		FOR UPDATE
+	`
+	if err := tx.QueryRowContext(ctx, query, v.ProductID).Scan(&stock, &hasVariants, &used); err != nil {
+		return 0, fmt.Errorf("failed to lock product: %w", err)
+	}
+	if !hasVariants && (!stock.IsZero() || used) {
+		return 0, ErrProductInUse
+	}
+
+	query = `INSERT INTO product_variants (product_id, sku, options, option_key, stock) VALUES (?, ?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, query, v.ProductID, nullString(v.SKU), options, v.OptionKey(), v.Stock)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert product variant: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+
+	if err := settleDefaultVariantStock(ctx, tx, id); err != nil {
+		return 0, err
+	}
+	if err := rollUpStock(ctx, tx, v.ProductID); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+func (r *ProductVariantRepository) GetByProductID(ctx context.Context, productID int64) ([]*model.ProductVariant, error) {
+	query := `
+		SELECT id, product_id, sku, options, stock
+		FROM product_variants
+		WHERE product_id = ?
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product variants: %w", err)
+	}
+	defer rows.Close()
+
+	var variants []*model.ProductVariant
+	for rows.Next() {
+		v, err := scanVariant(rows)
+		if err != nil {
+			return nil, err
+		}
+		variants = append(variants, v)
+	}
+	return variants, rows.Err()
+}
+
+func (r *ProductVariantRepository) GetByID(ctx context.Context, productID, id int64) (*model.ProductVariant, error) {
+	query := `SELECT id, product_id, sku, options, stock FROM product_variants WHERE id = ? AND product_id = ?`
+	v, err := scanVariant(r.db.QueryRowContext(ctx, query, id, productID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (r *ProductVariantRepository) GetBySKU(ctx context.Context, sku string) (*model.ProductVariant, error) {
+	query := `SELECT id, product_id, sku, options, stock FROM product_variants WHERE sku = ?`
+	v, err := scanVariant(r.db.QueryRowContext(ctx, query, sku))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+func (r *ProductVariantRepository) Update(ctx context.Context, v *model.ProductVariant) error {
+	options, err := json.Marshal(v.Options)
+	if err != nil {
+		return fmt.Errorf("failed to encode variant options: %w", err)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := checkStockOutsideDefault(ctx, tx, v.ProductID, v.ID, v.Stock); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE product_variants
+		SET sku = ?, options = ?, option_key = ?, stock = ?
+		WHERE id = ? AND product_id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, nullString(v.SKU), options, v.OptionKey(), v.Stock, v.ID, v.ProductID); err != nil {
+		return fmt.Errorf("failed to update product variant: %w", err)
+	}
+
+	if err := settleDefaultVariantStock(ctx, tx, v.ID); err != nil {
+		return err
+	}
+	if err := fitDefaultBins(ctx, tx, v.ProductID, v.ID); err != nil {
+		return err
+	}
+	if err := rollUpStock(ctx, tx, v.ProductID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ProductVariantRepository) Delete(ctx context.Context, productID, id int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_variants WHERE id = ? AND product_id = ?`, id, productID); err != nil {
+		return fmt.Errorf("failed to delete product variant: %w", err)
+	}
+
+	if err := rollUpStock(ctx, tx, productID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetStockByWarehouse returns, per variant of a product, the warehouses
+// holding any of its stock.
+func (r *ProductVariantRepository) GetStockByWarehouse(ctx context.Context, productID int64) (map[int64][]*model.WarehouseStock, error) {
+	query := `
+		SELECT s.variant_id, ` + warehouseStockColumns + `
+		FROM variant_stock s
+		JOIN product_variants v ON v.id = s.variant_id
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE v.product_id = ? AND s.stock <> 0
+		ORDER BY s.variant_id, w.code
+	`
+	rows, err := r.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouse stock: %w", err)
+	}
+	return scanWarehouseStock(rows)
+}
+
+func (r *ProductVariantRepository) HasTransactions(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM transaction_items WHERE variant_id = ?)`, id).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check variant transactions: %w", err)
+	}
+	return exists, nil
+}
+
+// rollUpStock sets the parent product's stock, in total and per warehouse,
+// to the sum of its variants, and values the change like stock set on the
+// product directly.
+func rollUpStock(ctx context.Context, tx *sql.Tx, productID int64) error {
+	query := `
+		UPDATE products
+		SET stock = (SELECT COALESCE(SUM(stock), 0) FROM product_variants WHERE product_id = ?), version = version + 1
+		WHERE id = ?
+	`
+	if _, err := tx.ExecContext(ctx, query, productID, productID); err != nil {
+		return fmt.Errorf("failed to roll up variant stock: %w", err)
+	}
+
+	query = `
+		INSERT INTO product_stock (product_id, warehouse_id, stock)
+		SELECT v.product_id, s.warehouse_id, SUM(s.stock)
+		FROM variant_stock s
+		JOIN product_variants v ON v.id = s.variant_id
+		WHERE v.product_id = ?
+		GROUP BY v.product_id, s.warehouse_id
+		ON DUPLICATE KEY UPDATE product_stock.stock = VALUES(stock)
+	`
+	if _, err := tx.ExecContext(ctx, query, productID); err != nil {
+		return fmt.Errorf("failed to roll up variant stock: %w", err)
+	}
+	// Warehouses no variant holds stock at any more, e.g. after a variant
+	// was deleted, hold none of the product either.
+	query = `
+		UPDATE product_stock ps
+		SET ps.stock = 0
+		WHERE ps.product_id = ? AND NOT EXISTS (
+			SELECT 1
+			FROM variant_stock s
+			JOIN product_variants v ON v.id = s.variant_id
+			WHERE v.product_id = ps.product_id AND s.warehouse_id = ps.warehouse_id
+		)
+	`
+	if _, err := tx.ExecContext(ctx, query, productID); err != nil {
+		return fmt.Errorf("failed to roll up variant stock: %w", err)
+	}
+	return valueDirectStock(ctx, tx, productID)
+}
+
+func scanVariant(row rowScanner) (*model.ProductVariant, error) {
+	var (
+		v       model.ProductVariant
+		sku     sql.NullString
+		options []byte
+	)
+	if err := row.Scan(&v.ID, &v.ProductID, &sku, &options, &v.Stock); err != nil {
+		return nil, err
+	}
+	v.SKU = sku.String
+	if err := json.Unmarshal(options, &v.Options); err != nil {
+		return nil, fmt.Errorf("failed to decode variant options: %w", err)
+	}
+	return &v, nil
+}
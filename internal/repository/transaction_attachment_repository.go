@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type TransactionAttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewTransactionAttachmentRepository(db *sql.DB) *TransactionAttachmentRepository {
+	return &TransactionAttachmentRepository{db: db}
+}
+
+func (r *TransactionAttachmentRepository) Insert(ctx context.Context, a *model.TransactionAttachment) (int64, error) {
+	query := `
+		INSERT INTO transaction_attachments (transaction_id, storage_key, file_name, content_type, size_bytes, uploaded_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := r.db.ExecContext(ctx, query, a.TransactionID, a.StorageKey, a.FileName, a.ContentType, a.SizeBytes, a.UploadedBy, a.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert transaction attachment: %w", err)
+	}
+	return res.LastInsertId()
+}
+
+func (r *TransactionAttachmentRepository) GetByTransactionID(ctx context.Context, transactionID int64) ([]*model.TransactionAttachment, error) {
+	query := `
+		SELECT id, transaction_id, storage_key, file_name, content_type, size_bytes, uploaded_by, created_at
+		FROM transaction_attachments
+		WHERE transaction_id = ?
+		ORDER BY id
+	`
+	rows, err := r.db.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query transaction attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := []*model.TransactionAttachment{}
+	for rows.Next() {
+		var a model.TransactionAttachment
+		if err := rows.Scan(&a.ID, &a.TransactionID, &a.StorageKey, &a.FileName, &a.ContentType, &a.SizeBytes,
+			&a.UploadedBy, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan transaction attachment: %w", err)
+		}
+		attachments = append(attachments, &a)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *TransactionAttachmentRepository) Delete(ctx context.Context, transactionID, id int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM transaction_attachments WHERE id = ? AND transaction_id = ?`, id, transactionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete transaction attachment: %w", err)
+	}
+	return nil
+}
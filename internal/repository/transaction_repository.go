@@ -3,10 +3,20 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 )
 
+// ErrDuplicateReference is returned by InsertTransaction when another
+// transaction of the same type has the reference number.
+var ErrDuplicateReference = errors.New("duplicate reference number")
+
 type TransactionRepository struct {
 	db *sql.DB
 }
@@ -20,96 +30,619 @@ func (r *TransactionRepository) BeginTx(ctx context.Context) (*sql.Tx, error) {
 }
 
 func (r *TransactionRepository) InsertTransaction(ctx context.Context, tx *sql.Tx, t *model.Transaction) (int64, error) {
-	query := `INSERT INTO transactions (transaction_type, user_id) VALUES (?, ?)`
-	res, err := tx.ExecContext(ctx, query, t.TransactionType, t.UserID)
+	query := `
+		INSERT INTO transactions (transaction_type, user_id, warehouse_id, destination_warehouse_id, created_at, status, reason, reference_number,
+			counterparty, notes, reverses_transaction_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := tx.ExecContext(ctx, query, t.TransactionType, t.UserID, t.WarehouseID, t.DestinationWarehouseID, t.CreatedAt, t.Status,
+		nullString(t.Reason),
+		nullString(t.ReferenceNumber), nullString(t.Counterparty), nullString(t.Notes), t.ReversesID)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == 1062 && strings.Contains(mysqlErr.Message, "uq_transactions_reference") {
+		return 0, ErrDuplicateReference
+	}
 	if err != nil {
 		return 0, err
 	}
 	return res.LastInsertId()
 }
 
-func (r *TransactionRepository) InsertTransactionItem(ctx context.Context, tx *sql.Tx, item *model.TransactionItem) error {
-	query := `INSERT INTO transaction_items (transaction_id, product_id, quantity) VALUES (?, ?, ?)`
-	_, err := tx.ExecContext(ctx, query, item.TransactionID, item.ProductID, item.Quantity)
+// UpdateTransactionStatus moves a transaction to status, recording who
+// reviewed it and when if reviewedBy is set.
+func (r *TransactionRepository) UpdateTransactionStatus(ctx context.Context, tx *sql.Tx, id int64, status string, reviewedBy *int64, reviewedAt *time.Time) error {
+	query := `UPDATE transactions SET status = ?, reviewed_by = ?, reviewed_at = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, status, reviewedBy, reviewedAt, id)
+	return err
+}
+
+// MarkReceived posts a transfer that was in transit, recording who received
+// it and when.
+func (r *TransactionRepository) MarkReceived(ctx context.Context, tx *sql.Tx, id, receivedBy int64, receivedAt time.Time) error {
+	query := `UPDATE transactions SET status = ?, received_by = ?, received_at = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, model.TransactionPosted, receivedBy, receivedAt, id)
+	return err
+}
+
+func (r *TransactionRepository) InsertTransactionItem(ctx context.Context, tx *sql.Tx, item *model.TransactionItem) (int64, error) {
+	query := `
+		INSERT INTO transaction_items (transaction_id, product_id, variant_id, quantity, unit, unit_quantity, unit_cost, unit_price, currency,
+			stock_before, stock_after, counted, received_quantity)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := tx.ExecContext(ctx, query, item.TransactionID, item.ProductID, nullInt64(item.VariantID), item.Quantity,
+		nullString(item.Unit), item.UnitQuantity, item.UnitCost, item.UnitPrice, nullString(item.Currency), item.StockBefore, item.StockAfter,
+		item.Counted, item.ReceivedQuantity)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// UpdateItemMovement records the stock movement of an item posted after it
+// was inserted.
+func (r *TransactionRepository) UpdateItemMovement(ctx context.Context, tx *sql.Tx, item *model.TransactionItem) error {
+	query := `UPDATE transaction_items SET quantity = ?, unit_quantity = ?, stock_before = ?, stock_after = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, item.Quantity, item.UnitQuantity, item.StockBefore, item.StockAfter, item.ID)
+	return err
+}
+
+// UpdateItemReceipt records how much of a transfer item arrived and the
+// stock once it did.
+func (r *TransactionRepository) UpdateItemReceipt(ctx context.Context, tx *sql.Tx, item *model.TransactionItem) error {
+	query := `UPDATE transaction_items SET received_quantity = ?, stock_after = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, item.ReceivedQuantity, item.StockAfter, item.ID)
+	return err
+}
+
+func (r *TransactionRepository) SetItemCostOfGoods(ctx context.Context, tx *sql.Tx, itemID, cost int64) error {
+	_, err := tx.ExecContext(ctx, `UPDATE transaction_items SET cost_of_goods = ? WHERE id = ?`, cost, itemID)
+	return err
+}
+
+func (r *TransactionRepository) FindProductIDBySKU(ctx context.Context, tx *sql.Tx, sku string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE sku = ?`, sku).Scan(&id)
+	return id, err
+}
+
+func (r *TransactionRepository) FindProductIDByBarcode(ctx context.Context, tx *sql.Tx, barcode string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE barcode = ?`, barcode).Scan(&id)
+	return id, err
+}
+
+func (r *TransactionRepository) FindVariantBySKU(ctx context.Context, tx *sql.Tx, sku string) (int64, int64, error) {
+	var variantID, productID int64
+	err := tx.QueryRowContext(ctx, `SELECT id, product_id FROM product_variants WHERE sku = ?`, sku).Scan(&variantID, &productID)
+	return variantID, productID, err
+}
+
+func (r *TransactionRepository) HasVariants(ctx context.Context, tx *sql.Tx, productID int64) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM product_variants WHERE product_id = ?)`, productID).Scan(&exists)
+	return exists, err
+}
+
+func (r *TransactionRepository) GetVariantStockForUpdate(ctx context.Context, tx *sql.Tx, productID, variantID int64) (decimal.Decimal, error) {
+	var stock decimal.Decimal
+	query := `SELECT stock FROM product_variants WHERE id = ? AND product_id = ? FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, variantID, productID).Scan(&stock)
+	return stock, err
+}
+
+func (r *TransactionRepository) UpdateVariantStock(ctx context.Context, tx *sql.Tx, variantID int64, newStock decimal.Decimal) error {
+	query := `UPDATE product_variants SET stock = ? WHERE id = ?`
+	_, err := tx.ExecContext(ctx, query, newStock, variantID)
+	return err
+}
+
+// GetUnitFactor returns how many base units one unit of the given name holds
+// for a product. The product's base unit itself has factor 1.
+func (r *TransactionRepository) GetUnitFactor(ctx context.Context, tx *sql.Tx, productID int64, unit string) (int64, error) {
+	var factor int64
+	query := `
+		SELECT 1 FROM products WHERE id = ? AND base_unit = ?
+		UNION ALL
+		SELECT factor FROM product_units WHERE product_id = ? AND name = ?
+		LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, productID, unit, productID, unit).Scan(&factor)
+	return factor, err
+}
+
+// LockedProduct is the part of a product row a stock movement needs.
+type LockedProduct struct {
+	Stock     decimal.Decimal
+	Precision int
+	Archived  bool
+}
+
+// GetProductForUpdate locks a product row for the rest of tx.
+func (r *TransactionRepository) GetProductForUpdate(ctx context.Context, tx *sql.Tx, productID int64) (*LockedProduct, error) {
+	var p LockedProduct
+	query := `SELECT stock, quantity_precision, archived_at IS NOT NULL FROM products WHERE id = ? FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, query, productID).Scan(&p.Stock, &p.Precision, &p.Archived); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetProductPricing returns the price and cost per base unit currently in
+// effect for a product, with their currency.
+func (r *TransactionRepository) GetProductPricing(ctx context.Context, tx *sql.Tx, productID int64) (int64, int64, string, error) {
+	return productPricing(ctx, tx, productID)
+}
+
+func productPricing(ctx context.Context, q rowQueryer, productID int64) (int64, int64, string, error) {
+	var (
+		price, cost int64
+		currency    string
+	)
+	query := `
+		SELECT COALESCE(pp.price, 0), COALESCE(pp.cost, 0), COALESCE(pp.currency, p.currency)
+		FROM products p` + effectivePriceJoin + `
+		WHERE p.id = ?
+	`
+	err := q.QueryRowContext(ctx, query, productID).Scan(&price, &cost, &currency)
+	return price, cost, currency, err
+}
+
+func (r *TransactionRepository) WarehouseExists(ctx context.Context, tx *sql.Tx, id int64) (bool, error) {
+	var exists bool
+	err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = ?)`, id).Scan(&exists)
+	return exists, err
+}
+
+// GetLocationStockForUpdate returns the stock of a product at a warehouse
+// and locks it for the rest of tx, starting it at zero if the product was
+// never held there.
+func (r *TransactionRepository) GetLocationStockForUpdate(ctx context.Context, tx *sql.Tx, warehouseID, productID int64) (decimal.Decimal, error) {
+	query := `INSERT INTO product_stock (product_id, warehouse_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE product_id = product_id`
+	if _, err := tx.ExecContext(ctx, query, productID, warehouseID); err != nil {
+		return decimal.Decimal{}, err
+	}
+	var stock decimal.Decimal
+	query = `SELECT stock FROM product_stock WHERE product_id = ? AND warehouse_id = ? FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, productID, warehouseID).Scan(&stock)
+	return stock, err
+}
+
+func (r *TransactionRepository) UpdateLocationStock(ctx context.Context, tx *sql.Tx, warehouseID, productID int64, newStock decimal.Decimal) error {
+	query := `UPDATE product_stock SET stock = ? WHERE product_id = ? AND warehouse_id = ?`
+	_, err := tx.ExecContext(ctx, query, newStock, productID, warehouseID)
 	return err
 }
 
-func (r *TransactionRepository) GetProductStockForUpdate(ctx context.Context, tx *sql.Tx, productID int64) (int64, error) {
-	var stock int64
-	query := `SELECT stock FROM products WHERE id = ? FOR UPDATE`
-	err := tx.QueryRowContext(ctx, query, productID).Scan(&stock)
+// GetVariantLocationStockForUpdate is GetLocationStockForUpdate for a
+// variant.
+func (r *TransactionRepository) GetVariantLocationStockForUpdate(ctx context.Context, tx *sql.Tx, warehouseID, variantID int64) (decimal.Decimal, error) {
+	query := `INSERT INTO variant_stock (variant_id, warehouse_id) VALUES (?, ?) ON DUPLICATE KEY UPDATE variant_id = variant_id`
+	if _, err := tx.ExecContext(ctx, query, variantID, warehouseID); err != nil {
+		return decimal.Decimal{}, err
+	}
+	var stock decimal.Decimal
+	query = `SELECT stock FROM variant_stock WHERE variant_id = ? AND warehouse_id = ? FOR UPDATE`
+	err := tx.QueryRowContext(ctx, query, variantID, warehouseID).Scan(&stock)
 	return stock, err
 }
 
-func (r *TransactionRepository) UpdateProductStock(ctx context.Context, tx *sql.Tx, productID, newStock int64) error {
-	query := `UPDATE products SET stock = ? WHERE id = ?`
+func (r *TransactionRepository) UpdateVariantLocationStock(ctx context.Context, tx *sql.Tx, warehouseID, variantID int64, newStock decimal.Decimal) error {
+	query := `UPDATE variant_stock SET stock = ? WHERE variant_id = ? AND warehouse_id = ?`
+	_, err := tx.ExecContext(ctx, query, newStock, variantID, warehouseID)
+	return err
+}
+
+// UpdateProductStock sets the stock of a product and bumps its version, so
+// an update based on the stock read before the movement is refused.
+func (r *TransactionRepository) UpdateProductStock(ctx context.Context, tx *sql.Tx, productID int64, newStock decimal.Decimal) error {
+	query := `UPDATE products SET stock = ?, version = version + 1 WHERE id = ?`
 	_, err := tx.ExecContext(ctx, query, newStock, productID)
 	return err
 }
 
-func (r *TransactionRepository) GetTransactionsByUserID(ctx context.Context, userID int64) ([]model.TransactionWithItems, error) {
+// transactionItemColumns are read by scanTransactionItem. They need
+// transaction_items ti joined with products p.
+const transactionItemColumns = `ti.id, ti.transaction_id, ti.product_id, ti.variant_id, ti.quantity, ti.unit, ti.unit_quantity,
+	ti.unit_cost, ti.unit_price, ti.currency, ti.cost_of_goods, p.name, p.sku, ti.stock_before, ti.stock_after, ti.counted,
+	ti.received_quantity`
+
+// transactionColumns are read by scanTransaction. They need transactions t
+// joined with reversalJoin.
+const transactionColumns = `t.id, t.transaction_type, t.user_id, t.warehouse_id, t.destination_warehouse_id, t.created_at, t.status, t.reason,
+	t.reference_number, t.counterparty, t.notes, t.reviewed_by, t.reviewed_at, t.received_by, t.received_at, t.reverses_transaction_id, rv.id`
+
+// reversalJoin finds, as rv, the transaction reversing t, if any.
+const reversalJoin = ` LEFT JOIN transactions rv ON rv.reverses_transaction_id = t.id`
+
+// GetTransactionByID returns a transaction with the user who recorded it
+// and its items, or nil if there is none.
+func (r *TransactionRepository) GetTransactionByID(ctx context.Context, id int64) (*model.TransactionWithItems, error) {
 	query := `
-	SELECT 
-		t.id AS transaction_id,
-		t.transaction_type,
-		t.user_id,
-		ti.id AS transaction_item_id,
-		ti.product_id,
-		ti.quantity
-	FROM 
-		transactions t
-	LEFT JOIN 
-		transaction_items ti ON t.id = ti.transaction_id
-	WHERE 
-		t.user_id = ?
-	ORDER BY 
-		t.id DESC
+		SELECT ` + transactionColumns + `, u.first_name, u.last_name, u.email
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id` + reversalJoin + `
+		WHERE t.id = ?
 	`
+	t := model.TransactionWithItems{CreatedBy: &model.TransactionUser{}}
+	err := scanTransaction(r.db.QueryRowContext(ctx, query, id), &t, &t.CreatedBy.FirstName, &t.CreatedBy.LastName, &t.CreatedBy.Email)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.CreatedBy.ID = t.UserID
+
+	found := []model.TransactionWithItems{t}
+	if err := r.attachItems(ctx, r.db, found); err != nil {
+		return nil, err
+	}
+	return &found[0], nil
+}
+
+// GetTransactionOwner returns the ID of the user who recorded a
+// transaction, or sql.ErrNoRows if there is none.
+func (r *TransactionRepository) GetTransactionOwner(ctx context.Context, id int64) (int64, error) {
+	var userID int64
+	err := r.db.QueryRowContext(ctx, `SELECT user_id FROM transactions WHERE id = ?`, id).Scan(&userID)
+	return userID, err
+}
+
+// GetTransactionForUpdate returns a transaction with its items, or nil if
+// there is none, and locks it for the rest of tx.
+func (r *TransactionRepository) GetTransactionForUpdate(ctx context.Context, tx *sql.Tx, id int64) (*model.TransactionWithItems, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions t` + reversalJoin + ` WHERE t.id = ? FOR UPDATE`
+	var t model.TransactionWithItems
+	err := scanTransaction(tx.QueryRowContext(ctx, query, id), &t)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	found := []model.TransactionWithItems{t}
+	if err := r.attachItems(ctx, tx, found); err != nil {
+		return nil, err
+	}
+	return &found[0], nil
+}
+
+// ListTransactions returns up to f.Limit transactions matching f, newest
+// first, with all of their items. Filtering by product keeps every item of
+// a matching transaction.
+func (r *TransactionRepository) ListTransactions(ctx context.Context, f model.TransactionFilter) ([]model.TransactionWithItems, error) {
+	where, args := transactionConditions(f)
+	if f.BeforeID != 0 {
+		where = append(where, "t.id < ?")
+		args = append(args, f.BeforeID)
+	}
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	query := `SELECT ` + transactionColumns + ` FROM transactions t` + reversalJoin + whereClause(where) + ` ORDER BY t.id DESC LIMIT ?`
+	args = append(args, f.Limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	transactionsMap := make(map[int64]*model.TransactionWithItems)
+	transactions := []model.TransactionWithItems{}
 	for rows.Next() {
-		var (
-			tid       int64
-			tType     string
-			uid       int64
-			tiid      sql.NullInt64
-			productID sql.NullInt64
-			quantity  sql.NullInt64
-		)
+		var t model.TransactionWithItems
+		if err := scanTransaction(rows, &t); err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := r.attachItems(ctx, r.db, transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
 
-		if err := rows.Scan(&tid, &tType, &uid, &tiid, &productID, &quantity); err != nil {
+// GetTransactionTotals aggregates all posted transactions matching f,
+// ignoring f.BeforeID and f.Limit.
+func (r *TransactionRepository) GetTransactionTotals(ctx context.Context, f model.TransactionFilter) (*model.TransactionTotals, error) {
+	where, args := transactionConditions(f)
+	where = append(where, "t.status = ?")
+	args = append(args, model.TransactionPosted)
+	totals := &model.TransactionTotals{ByType: []model.TransactionTotal{}}
+
+	query := `SELECT COUNT(*) FROM transactions t` + whereClause(where)
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&totals.Transactions); err != nil {
+		return nil, err
+	}
+
+	itemWhere, itemArgs := itemConditions(f)
+	query = `
+		SELECT t.transaction_type, COALESCE(ti.currency, ''), COUNT(DISTINCT t.id), COUNT(*), COALESCE(SUM(ti.quantity), 0),
+			COALESCE(SUM(ROUND(COALESCE(ti.unit_quantity, ti.quantity) *
+				CASE t.transaction_type WHEN 'OUT' THEN ti.unit_price ELSE ti.unit_cost END)), 0),
+			COALESCE(SUM(ti.cost_of_goods), 0)
+		FROM transactions t
+		JOIN transaction_items ti ON ti.transaction_id = t.id
+		JOIN products p ON p.id = ti.product_id` + whereClause(append(where, itemWhere...)) + `
+		GROUP BY t.transaction_type, ti.currency
+		ORDER BY t.transaction_type, ti.currency
+	`
+	rows, err := r.db.QueryContext(ctx, query, append(args, itemArgs...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.TransactionTotal
+		if err := rows.Scan(&t.TransactionType, &t.Currency, &t.Transactions, &t.Items, &t.Quantity, &t.Value, &t.CostOfGoods); err != nil {
 			return nil, err
 		}
+		totals.ByType = append(totals.ByType, t)
+	}
+	return totals, rows.Err()
+}
+
+// ExportTransactionItems streams the items of all transactions matching f
+// to fn, newest transaction first, from an open cursor. With a product or
+// category filter only the matching items are included. The transaction
+// passed along carries its creator but no items.
+func (r *TransactionRepository) ExportTransactionItems(ctx context.Context, f model.TransactionFilter, fn func(*model.TransactionWithItems, *model.TransactionItem) error) error {
+	where, args := transactionConditions(f)
+	itemWhere, itemArgs := itemConditions(f)
+	query := `
+		SELECT ` + transactionColumns + `, u.first_name, u.last_name, u.email, ` + transactionItemColumns + `
+		FROM transactions t
+		JOIN users u ON u.id = t.user_id` + reversalJoin + `
+		JOIN transaction_items ti ON ti.transaction_id = t.id
+		JOIN products p ON p.id = ti.product_id` + whereClause(append(where, itemWhere...)) + `
+		ORDER BY t.id DESC, ti.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, append(args, itemArgs...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		t := model.TransactionWithItems{CreatedBy: &model.TransactionUser{}}
+		dest, finish := transactionDest(&t)
+		item, err := scanTransactionItem(rows, append(dest, &t.CreatedBy.FirstName, &t.CreatedBy.LastName, &t.CreatedBy.Email)...)
+		if err != nil {
+			return err
+		}
+		finish()
+		t.CreatedBy.ID = t.UserID
+		if err := fn(&t, item); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// transactionConditions turns the transaction-level fields of f into
+// conditions on transactions t.
+func transactionConditions(f model.TransactionFilter) ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.UserID != 0 {
+		where = append(where, "t.user_id = ?")
+		args = append(args, f.UserID)
+	}
+	if f.WarehouseID != 0 {
+		where = append(where, "(t.warehouse_id = ? OR t.destination_warehouse_id = ?)")
+		args = append(args, f.WarehouseID, f.WarehouseID)
+	}
+	if f.Type != "" {
+		where = append(where, "t.transaction_type = ?")
+		args = append(args, f.Type)
+	}
+	if f.Status != "" {
+		where = append(where, "t.status = ?")
+		args = append(args, f.Status)
+	}
+	if f.Reference != "" {
+		where = append(where, "t.reference_number = ?")
+		args = append(args, f.Reference)
+	}
+	if f.Search != "" {
+		pattern := "%" + escapeLike(f.Search) + "%"
+		where = append(where, "(t.reference_number LIKE ? OR t.counterparty LIKE ? OR t.notes LIKE ?)")
+		args = append(args, pattern, pattern, pattern)
+	}
+	if f.ProductID != 0 {
+		where = append(where, "EXISTS (SELECT 1 FROM transaction_items fi WHERE fi.transaction_id = t.id AND fi.product_id = ?)")
+		args = append(args, f.ProductID)
+	}
+	if f.CategoryID != 0 {
+		where = append(where, `EXISTS (SELECT 1 FROM transaction_items fi JOIN products fp ON fp.id = fi.product_id
+			WHERE fi.transaction_id = t.id AND fp.category_id = ?)`)
+		args = append(args, f.CategoryID)
+	}
+	if f.From != nil {
+		where = append(where, "t.created_at >= ?")
+		args = append(args, *f.From)
+	}
+	if f.To != nil {
+		where = append(where, "t.created_at <= ?")
+		args = append(args, *f.To)
+	}
+	return where, args
+}
+
+// itemConditions narrows transaction_items ti joined with products p to
+// the items matching the product and category of f.
+func itemConditions(f model.TransactionFilter) ([]string, []any) {
+	var (
+		where []string
+		args  []any
+	)
+	if f.ProductID != 0 {
+		where = append(where, "ti.product_id = ?")
+		args = append(args, f.ProductID)
+	}
+	if f.CategoryID != 0 {
+		where = append(where, "p.category_id = ?")
+		args = append(args, f.CategoryID)
+	}
+	return where, args
+}
+
+// escapeLike makes s match literally inside a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func whereClause(conditions []string) string {
+	if len(conditions) == 0 {
+		return ""
+	}
+	return ` WHERE ` + strings.Join(conditions, " AND ")
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// attachItems loads the items of the given transactions in one query,
+// keeping the transactions in their order and items in insertion order.
+func (r *TransactionRepository) attachItems(ctx context.Context, q queryer, transactions []model.TransactionWithItems) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	index := make(map[int64]int, len(transactions))
+	args := make([]any, len(transactions))
+	for i, t := range transactions {
+		index[t.ID] = i
+		args[i] = t.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(args)), ", ")
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT `+transactionItemColumns+`
+		FROM transaction_items ti
+		JOIN products p ON p.id = ti.product_id
+		WHERE ti.transaction_id IN (`+placeholders+`)
+		ORDER BY ti.id
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanTransactionItem(rows)
+		if err != nil {
+			return err
+		}
+		t := &transactions[index[item.TransactionID]]
+		t.Items = append(t.Items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return attachItemBins(ctx, q, transactions, placeholders, args)
+}
 
-		if _, exists := transactionsMap[tid]; !exists {
-			transactionsMap[tid] = &model.TransactionWithItems{
-				ID:              tid,
-				UserID:          uid,
-				TransactionType: tType,
-				Items:           []model.TransactionItem{},
-			}
+// attachItemBins loads the bins the items of the given transactions were
+// put away to or picked from. placeholders and args select the
+// transactions by ID.
+func attachItemBins(ctx context.Context, q queryer, transactions []model.TransactionWithItems, placeholders string, args []any) error {
+	items := map[int64]*model.TransactionItem{}
+	for i := range transactions {
+		for j := range transactions[i].Items {
+			item := &transactions[i].Items[j]
+			items[item.ID] = item
 		}
+	}
 
-		if tiid.Valid {
-			transactionsMap[tid].Items = append(transactionsMap[tid].Items, model.TransactionItem{
-				ID:            tiid.Int64,
-				ProductID:     productID.Int64,
-				Quantity:      quantity.Int64,
-				TransactionID: tid,
-			})
+	rows, err := q.QueryContext(ctx, `
+		SELECT tib.transaction_item_id, b.id, `+binCode+`, tib.quantity
+		FROM transaction_item_bins tib
+		JOIN transaction_items ti ON ti.id = tib.transaction_item_id
+		JOIN bins b ON b.id = tib.bin_id
+		WHERE ti.transaction_id IN (`+placeholders+`)
+		ORDER BY `+binOrder+`
+	`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			itemID int64
+			b      model.BinQuantity
+		)
+		if err := rows.Scan(&itemID, &b.BinID, &b.BinCode, &b.Quantity); err != nil {
+			return err
+		}
+		if item := items[itemID]; item != nil {
+			item.Bins = append(item.Bins, b)
 		}
 	}
+	return rows.Err()
+}
 
-	var results []model.TransactionWithItems
-	for _, v := range transactionsMap {
-		results = append(results, *v)
+// scanTransaction reads transactionColumns into t, followed by any columns
+// selected after them into tail.
+func scanTransaction(row rowScanner, t *model.TransactionWithItems, tail ...any) error {
+	dest, finish := transactionDest(t)
+	if err := row.Scan(append(dest, tail...)...); err != nil {
+		return err
 	}
+	finish()
+	t.Items = []model.TransactionItem{}
+	return nil
+}
 
-	return results, nil
+// transactionDest returns scan destinations for transactionColumns and a
+// function that copies them into t once scanned.
+func transactionDest(t *model.TransactionWithItems) ([]any, func()) {
+	var reason, reference, counterparty, notes sql.NullString
+	dest := []any{&t.ID, &t.TransactionType, &t.UserID, &t.WarehouseID, &t.DestinationWarehouseID, &t.CreatedAt, &t.Status, &reason, &reference,
+		&counterparty, &notes, &t.ReviewedBy, &t.ReviewedAt, &t.ReceivedBy, &t.ReceivedAt, &t.ReversesID, &t.ReversedByID}
+	return dest, func() {
+		t.Reason = reason.String
+		t.ReferenceNumber = reference.String
+		t.Counterparty = counterparty.String
+		t.Notes = notes.String
+		t.Reversed = t.ReversedByID != nil
+	}
+}
+
+// scanTransactionItem reads transactionItemColumns, after any columns
+// selected before them into head.
+func scanTransactionItem(row rowScanner, head ...any) (*model.TransactionItem, error) {
+	var (
+		item      model.TransactionItem
+		variantID sql.NullInt64
+		unit      sql.NullString
+		currency  sql.NullString
+		sku       sql.NullString
+	)
+	dest := append(head, &item.ID, &item.TransactionID, &item.ProductID, &variantID, &item.Quantity, &unit, &item.UnitQuantity,
+		&item.UnitCost, &item.UnitPrice, &currency, &item.CostOfGoods, &item.ProductName, &sku, &item.StockBefore, &item.StockAfter, &item.Counted,
+		&item.ReceivedQuantity)
+	err := row.Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	if item.ReceivedQuantity != nil && item.ReceivedQuantity.Cmp(item.Quantity) != 0 {
+		discrepancy := item.ReceivedQuantity.Sub(item.Quantity)
+		item.Discrepancy = &discrepancy
+	}
+	item.VariantID = variantID.Int64
+	item.Unit = unit.String
+	item.Currency = currency.String
+	item.ProductSKU = sku.String
+	return &item, nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 )
@@ -65,3 +66,36 @@ func (r *UserRepository) UpdateConsumer(ctx context.Context, c *model.Users) err
 
 	return nil
 }
+
+// PatchUser writes only the given fields, named as in the API.
+func (r *UserRepository) PatchUser(ctx context.Context, c *model.Users, fields []string) error {
+	values := map[string]any{
+		"first_name":    c.FirstName,
+		"last_name":     c.LastName,
+		"email":         c.Email,
+		"password":      c.Password,
+		"date_of_birth": c.DateOfBirth,
+		"gender":        c.Gender,
+	}
+
+	var (
+		set  []string
+		args []any
+	)
+	for _, field := range fields {
+		if value, ok := values[field]; ok {
+			set = append(set, field+" = ?")
+			args = append(args, value)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	args = append(args, c.ID)
+
+	_, err := r.db.ExecContext(ctx, `UPDATE users SET `+strings.Join(set, ", ")+` WHERE id = ?`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to patch user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type ValuationRepository struct {
+	db *sql.DB
+}
+
+func NewValuationRepository(db *sql.DB) *ValuationRepository {
+	return &ValuationRepository{db: db}
+}
+
+// GetLatestEntry returns the most recent ledger entry of a product, or a
+// zero entry if the product has never moved. Callers hold the product's row
+// lock, which serialises ledger writes per product.
+func (r *ValuationRepository) GetLatestEntry(ctx context.Context, tx *sql.Tx, productID int64) (*model.StockLedgerEntry, error) {
+	return latestEntry(ctx, tx, productID)
+}
+
+func latestEntry(ctx context.Context, tx *sql.Tx, productID int64) (*model.StockLedgerEntry, error) {
+	var (
+		e      = model.StockLedgerEntry{ProductID: productID}
+		itemID sql.NullInt64
+	)
+	query := `
+		SELECT transaction_item_id, quantity_change, quantity_after, fifo_value_after, average_value_after, currency
+		FROM stock_ledger
+		WHERE product_id = ?
+		ORDER BY id DESC
+		LIMIT 1
+	`
+	err := tx.QueryRowContext(ctx, query, productID).Scan(&itemID, &e.QuantityChange, &e.QuantityAfter, &e.FIFOValueAfter, &e.AverageValueAfter, &e.Currency)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to get stock ledger: %w", err)
+	}
+	e.TransactionItemID = itemID.Int64
+	return &e, nil
+}
+
+func (r *ValuationRepository) InsertEntry(ctx context.Context, tx *sql.Tx, e *model.StockLedgerEntry) error {
+	return insertEntry(ctx, tx, e)
+}
+
+func insertEntry(ctx context.Context, tx *sql.Tx, e *model.StockLedgerEntry) error {
+	query := `
+		INSERT INTO stock_ledger (product_id, transaction_item_id, quantity_change, quantity_after, fifo_value_after, average_value_after, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := tx.ExecContext(ctx, query, e.ProductID, nullInt64(e.TransactionItemID), e.QuantityChange, e.QuantityAfter,
+		e.FIFOValueAfter, e.AverageValueAfter, e.Currency, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to insert stock ledger entry: %w", err)
+	}
+	return nil
+}
+
+func (r *ValuationRepository) InsertLayer(ctx context.Context, tx *sql.Tx, l *model.CostLayer) error {
+	return insertLayer(ctx, tx, l)
+}
+
+func insertLayer(ctx context.Context, tx *sql.Tx, l *model.CostLayer) error {
+	query := `
+		INSERT INTO cost_layers (product_id, transaction_item_id, quantity, remaining_quantity, total_cost, remaining_cost, currency, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := tx.ExecContext(ctx, query, l.ProductID, nullInt64(l.TransactionItemID), l.Quantity, l.RemainingQuantity,
+		l.TotalCost, l.RemainingCost, l.Currency, time.Now().UTC().Truncate(time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to insert cost layer: %w", err)
+	}
+	return nil
+}
+
+// ConsumeLayers takes quantity from the open layers of a product, oldest
+// first after the layer of firstItemID if it is still open, and returns
+// their cost together with any quantity the layers could not cover.
+func (r *ValuationRepository) ConsumeLayers(ctx context.Context, tx *sql.Tx, productID, firstItemID int64, quantity decimal.Decimal) (int64, decimal.Decimal, error) {
+	return consumeLayers(ctx, tx, productID, firstItemID, quantity)
+}
+
+func consumeLayers(ctx context.Context, tx *sql.Tx, productID, firstItemID int64, quantity decimal.Decimal) (int64, decimal.Decimal, error) {
+	if !quantity.IsPositive() {
+		return 0, decimal.Decimal{}, nil
+	}
+
+	layers, err := openLayersForUpdate(ctx, tx, productID)
+	if err != nil {
+		return 0, decimal.Decimal{}, err
+	}
+	if firstItemID != 0 {
+		if i := slices.IndexFunc(layers, func(l *model.CostLayer) bool { return l.TransactionItemID == firstItemID }); i > 0 {
+			first := layers[i]
+			copy(layers[1:i+1], layers[:i])
+			layers[0] = first
+		}
+	}
+
+	var cost int64
+	remaining := quantity
+	for _, layer := range layers {
+		if !remaining.IsPositive() {
+			break
+		}
+
+		take := remaining
+		if take.Cmp(layer.RemainingQuantity) > 0 {
+			take = layer.RemainingQuantity
+		}
+		// The last piece of a layer takes whatever cost is left so
+		// rounding never leaves value behind.
+		layerCost := layer.RemainingCost
+		if take.Cmp(layer.RemainingQuantity) < 0 {
+			layerCost = decimal.Prorate(layer.RemainingCost, take, layer.RemainingQuantity)
+		}
+
+		query := `UPDATE cost_layers SET remaining_quantity = ?, remaining_cost = ? WHERE id = ?`
+		if _, err := tx.ExecContext(ctx, query, layer.RemainingQuantity.Sub(take), layer.RemainingCost-layerCost, layer.ID); err != nil {
+			return 0, decimal.Decimal{}, fmt.Errorf("failed to update cost layer: %w", err)
+		}
+
+		cost += layerCost
+		remaining = remaining.Sub(take)
+	}
+	return cost, remaining, nil
+}
+
+// openLayersForUpdate returns the layers with quantity left, oldest first.
+func openLayersForUpdate(ctx context.Context, tx *sql.Tx, productID int64) ([]*model.CostLayer, error) {
+	query := `
+		SELECT id, product_id, transaction_item_id, quantity, remaining_quantity, total_cost, remaining_cost, currency
+		FROM cost_layers
+		WHERE product_id = ? AND remaining_quantity > 0
+		ORDER BY id
+		FOR UPDATE
+	`
+	rows, err := tx.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cost layers: %w", err)
+	}
+	defer rows.Close()
+
+	var layers []*model.CostLayer
+	for rows.Next() {
+		var (
+			l      model.CostLayer
+			itemID sql.NullInt64
+		)
+		if err := rows.Scan(&l.ID, &l.ProductID, &itemID, &l.Quantity, &l.RemainingQuantity, &l.TotalCost, &l.RemainingCost, &l.Currency); err != nil {
+			return nil, err
+		}
+		l.TransactionItemID = itemID.Int64
+		layers = append(layers, &l)
+	}
+	return layers, rows.Err()
+}
+
+// valueDirectStock brings the stock ledger of a product in line with stock
+// set on it directly rather than through a transaction. Stock added gets a
+// layer of its own at the product's current cost; stock removed leaves the
+// oldest layers first, and the average value in proportion.
+func valueDirectStock(ctx context.Context, tx *sql.Tx, productID int64) error {
+	var stock decimal.Decimal
+	if err := tx.QueryRowContext(ctx, `SELECT stock FROM products WHERE id = ?`, productID).Scan(&stock); err != nil {
+		return fmt.Errorf("failed to get product stock: %w", err)
+	}
+	last, err := latestEntry(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+	change := stock.Sub(last.QuantityAfter)
+	if change.IsZero() {
+		return nil
+	}
+
+	_, cost, currency, err := productPricing(ctx, tx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to get product pricing: %w", err)
+	}
+	entry := &model.StockLedgerEntry{
+		ProductID:      productID,
+		QuantityChange: change,
+		QuantityAfter:  stock,
+		Currency:       currency,
+	}
+
+	if change.IsPositive() {
+		value := change.MulAmount(cost)
+		layer := &model.CostLayer{
+			ProductID:         productID,
+			Quantity:          change,
+			RemainingQuantity: change,
+			TotalCost:         value,
+			RemainingCost:     value,
+			Currency:          currency,
+		}
+		if err := insertLayer(ctx, tx, layer); err != nil {
+			return err
+		}
+		entry.FIFOValueAfter = last.FIFOValueAfter + value
+		entry.AverageValueAfter = last.AverageValueAfter + value
+		return insertEntry(ctx, tx, entry)
+	}
+
+	removed := change.Neg()
+	fifoCost, _, err := consumeLayers(ctx, tx, productID, 0, removed)
+	if err != nil {
+		return err
+	}
+	averageCost := last.AverageValueAfter
+	if stock.IsPositive() {
+		averageCost = decimal.Prorate(last.AverageValueAfter, removed, last.QuantityAfter)
+	}
+	entry.FIFOValueAfter = last.FIFOValueAfter - fifoCost
+	entry.AverageValueAfter = last.AverageValueAfter - averageCost
+	return insertEntry(ctx, tx, entry)
+}
+
+// GetValuation returns, per product, the last ledger state recorded at or
+// before asOf, with the value under the given method ("fifo" or "average")
+// in the currency it was costed in.
+func (r *ValuationRepository) GetValuation(ctx context.Context, asOf time.Time, method string) ([]model.ValuationLine, error) {
+	valueColumn := "l.fifo_value_after"
+	if method == "average" {
+		valueColumn = "l.average_value_after"
+	}
+
+	query := `
+		SELECT l.product_id, p.sku, p.name, l.quantity_after, ` + valueColumn + `, l.currency
+		FROM stock_ledger l
+		JOIN (
+			SELECT product_id, MAX(id) AS id
+			FROM stock_ledger
+			WHERE created_at <= ?
+			GROUP BY product_id
+		) last ON last.id = l.id
+		JOIN products p ON p.id = l.product_id
+		ORDER BY p.name, p.id
+	`
+	rows, err := r.db.QueryContext(ctx, query, asOf.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to query valuation: %w", err)
+	}
+	defer rows.Close()
+
+	var lines []model.ValuationLine
+	for rows.Next() {
+		var (
+			line model.ValuationLine
+			sku  sql.NullString
+		)
+		if err := rows.Scan(&line.ProductID, &sku, &line.Name, &line.Quantity, &line.Value, &line.Currency); err != nil {
+			return nil, err
+		}
+		line.SKU = sku.String
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
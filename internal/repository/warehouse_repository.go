@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+type WarehouseRepository struct {
+	db *sql.DB
+}
+
+func NewWarehouseRepository(db *sql.DB) *WarehouseRepository {
+	return &WarehouseRepository{db: db}
+}
+
+// Insert adds a warehouse. If it is the default, the previous default
+// stops being one.
+func (r *WarehouseRepository) Insert(ctx context.Context, w *model.Warehouse) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if w.IsDefault {
+		if err := clearDefaultWarehouse(ctx, tx, 0); err != nil {
+			return 0, err
+		}
+	}
+	query := `INSERT INTO warehouses (code, name, address, is_default) VALUES (?, ?, ?, ?)`
+	res, err := tx.ExecContext(ctx, query, w.Code, w.Name, nullString(w.Address), w.IsDefault)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert warehouse: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last insert ID: %w", err)
+	}
+	return id, tx.Commit()
+}
+
+func (r *WarehouseRepository) GetAll(ctx context.Context) ([]*model.Warehouse, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, code, name, address, is_default FROM warehouses ORDER BY code`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query warehouses: %w", err)
+	}
+	defer rows.Close()
+
+	var warehouses []*model.Warehouse
+	for rows.Next() {
+		w, err := scanWarehouse(rows)
+		if err != nil {
+			return nil, err
+		}
+		warehouses = append(warehouses, w)
+	}
+	return warehouses, rows.Err()
+}
+
+func (r *WarehouseRepository) GetByID(ctx context.Context, id int64) (*model.Warehouse, error) {
+	return r.getOne(ctx, `SELECT id, code, name, address, is_default FROM warehouses WHERE id = ?`, id)
+}
+
+func (r *WarehouseRepository) GetByCode(ctx context.Context, code string) (*model.Warehouse, error) {
+	return r.getOne(ctx, `SELECT id, code, name, address, is_default FROM warehouses WHERE code = ?`, code)
+}
+
+// Update saves a warehouse. If it becomes the default, the previous
+// default stops being one.
+func (r *WarehouseRepository) Update(ctx context.Context, w *model.Warehouse) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if w.IsDefault {
+		if err := clearDefaultWarehouse(ctx, tx, w.ID); err != nil {
+			return err
+		}
+	}
+	query := `UPDATE warehouses SET code = ?, name = ?, address = ?, is_default = ? WHERE id = ?`
+	if _, err := tx.ExecContext(ctx, query, w.Code, w.Name, nullString(w.Address), w.IsDefault, w.ID); err != nil {
+		return fmt.Errorf("failed to update warehouse: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *WarehouseRepository) getOne(ctx context.Context, query string, args ...any) (*model.Warehouse, error) {
+	w, err := scanWarehouse(r.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+func clearDefaultWarehouse(ctx context.Context, tx *sql.Tx, keepID int64) error {
+	if _, err := tx.ExecContext(ctx, `UPDATE warehouses SET is_default = FALSE WHERE is_default AND id <> ?`, keepID); err != nil {
+		return fmt.Errorf("failed to clear default warehouse: %w", err)
+	}
+	return nil
+}
+
+func scanWarehouse(row rowScanner) (*model.Warehouse, error) {
+	var (
+		w       model.Warehouse
+		address sql.NullString
+	)
+	if err := row.Scan(&w.ID, &w.Code, &w.Name, &address, &w.IsDefault); err != nil {
+		return nil, err
+	}
+	w.Address = address.String
+	return &w, nil
+}
+
+// warehouseStockColumns are read by scanWarehouseStock, after a leading
+// owner ID. They need warehouses w.
+const warehouseStockColumns = `w.id, w.code, w.name, s.stock`
+
+// scanWarehouseStock reads rows of an owner ID and warehouseStockColumns
+// into stock per owner.
+func scanWarehouseStock(rows *sql.Rows) (map[int64][]*model.WarehouseStock, error) {
+	defer rows.Close()
+
+	stock := map[int64][]*model.WarehouseStock{}
+	for rows.Next() {
+		var (
+			ownerID int64
+			s       model.WarehouseStock
+		)
+		if err := rows.Scan(&ownerID, &s.WarehouseID, &s.WarehouseCode, &s.WarehouseName, &s.Stock); err != nil {
+			return nil, fmt.Errorf("failed to scan warehouse stock: %w", err)
+		}
+		stock[ownerID] = append(stock[ownerID], &s)
+	}
+	return stock, rows.Err()
+}
+
+// inTransitStock sums the quantity of transfers in transit whose items
+// match transaction_items column ownerColumn to ownerRef.
+func inTransitStock(ownerColumn, ownerRef string) string {
+	return `(
+		SELECT COALESCE(SUM(ti.quantity), 0)
+		FROM transaction_items ti
+		JOIN transactions t ON t.id = ti.transaction_id
+		WHERE ti.` + ownerColumn + ` = ` + ownerRef + ` AND t.status = '` + model.TransactionInTransit + `'
+	)`
+}
+
+// settleDefaultStock puts whatever stock of a product is neither held at
+// any other warehouse nor in transit at the default warehouse, so
+// per-warehouse stock still adds up after products.stock was set directly.
+func settleDefaultStock(ctx context.Context, tx *sql.Tx, productID int64) error {
+	query := `
+		INSERT INTO product_stock (product_id, warehouse_id, stock)
+		SELECT p.id, w.id, p.stock - (
+			SELECT COALESCE(SUM(ps.stock), 0) FROM product_stock ps WHERE ps.product_id = p.id AND ps.warehouse_id <> w.id
+		) - ` + inTransitStock("product_id", "p.id") + `
+		FROM products p
+		JOIN warehouses w ON w.is_default
+		WHERE p.id = ?
+		ON DUPLICATE KEY UPDATE product_stock.stock = VALUES(stock)
+	`
+	if _, err := tx.ExecContext(ctx, query, productID); err != nil {
+		return fmt.Errorf("failed to settle default warehouse stock: %w", err)
+	}
+	return nil
+}
+
+// settleDefaultVariantStock is settleDefaultStock for a variant.
+func settleDefaultVariantStock(ctx context.Context, tx *sql.Tx, variantID int64) error {
+	query := `
+		INSERT INTO variant_stock (variant_id, warehouse_id, stock)
+		SELECT v.id, w.id, v.stock - (
+			SELECT COALESCE(SUM(vs.stock), 0) FROM variant_stock vs WHERE vs.variant_id = v.id AND vs.warehouse_id <> w.id
+		) - ` + inTransitStock("variant_id", "v.id") + `
+		FROM product_variants v
+		JOIN warehouses w ON w.is_default
+		WHERE v.id = ?
+		ON DUPLICATE KEY UPDATE variant_stock.stock = VALUES(stock)
+	`
+	if _, err := tx.ExecContext(ctx, query, variantID); err != nil {
+		return fmt.Errorf("failed to settle default warehouse stock: %w", err)
+	}
+	return nil
+}
+
+// StockBelowOutsideError is returned when stock set directly on a product
+// or variant is less than what it holds at other warehouses or in transit.
+type StockBelowOutsideError struct {
+	Outside decimal.Decimal
+}
+
+func (e *StockBelowOutsideError) Error() string {
+	return fmt.Sprintf("stock is below the %s held outside the default warehouse", e.Outside)
+}
+
+// checkStockOutsideDefault locks a product for the rest of tx and makes sure
+// stock, set directly on it or on its variant variantID, is no less than
+// what is held at other warehouses or in transit. Transactions lock the
+// product before moving its stock, so that can't change until tx ends.
+func checkStockOutsideDefault(ctx context.Context, tx *sql.Tx, productID, variantID int64, stock decimal.Decimal) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM products WHERE id = ? FOR UPDATE`, productID).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock product: %w", err)
+	}
+
+	table, ownerColumn, ownerID := "product_stock", "product_id", productID
+	if variantID != 0 {
+		table, ownerColumn, ownerID = "variant_stock", "variant_id", variantID
+	}
+	outside, err := stockOutsideDefault(ctx, tx, table, ownerColumn, ownerID)
+	if err != nil {
+		return err
+	}
+	if stock.Cmp(outside) < 0 {
+		return &StockBelowOutsideError{Outside: outside}
+	}
+	return nil
+}
+
+// rowQueryer is satisfied by both *sql.DB and *sql.Tx.
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// stockOutsideDefault sums the stock held at warehouses other than the
+// default one, from product_stock or variant_stock by owner column, and
+// the stock in transit.
+func stockOutsideDefault(ctx context.Context, db rowQueryer, table, ownerColumn string, ownerID int64) (decimal.Decimal, error) {
+	var stock decimal.Decimal
+	query := `
+		SELECT COALESCE(SUM(s.stock), 0) + ` + inTransitStock(ownerColumn, "?") + `
+		FROM ` + table + ` s
+		JOIN warehouses w ON w.id = s.warehouse_id
+		WHERE s.` + ownerColumn + ` = ? AND NOT w.is_default
+	`
+	if err := db.QueryRowContext(ctx, query, ownerID, ownerID).Scan(&stock); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("failed to get warehouse stock: %w", err)
+	}
+	return stock, nil
+}
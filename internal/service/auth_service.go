@@ -60,7 +60,7 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (string
 		return "", fmt.Errorf("invalid credentials")
 	}
 
-	token, err := utils.GenerateJWT(users.ID, s.jwtSecret)
+	token, err := utils.GenerateJWT(users.ID, users.Role, s.jwtSecret)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type BinService struct {
+	repo       *repository.BinRepository
+	warehouses *repository.WarehouseRepository
+}
+
+func NewBinService(repo *repository.BinRepository, warehouses *repository.WarehouseRepository) *BinService {
+	return &BinService{repo: repo, warehouses: warehouses}
+}
+
+// Create adds a bin to a warehouse. Its position is uppercased and must be
+// free.
+func (s *BinService) Create(ctx context.Context, b *model.Bin) (*model.Bin, error) {
+	if err := s.checkWarehouse(ctx, b.WarehouseID); err != nil {
+		return nil, err
+	}
+
+	for _, part := range []struct {
+		field string
+		value *string
+	}{{"zone", &b.Zone}, {"aisle", &b.Aisle}, {"rack", &b.Rack}, {"bin", &b.Bin}} {
+		*part.value = strings.ToUpper(strings.TrimSpace(*part.value))
+		if *part.value == "" {
+			return nil, NewValidationError(part.field, part.field+" is required")
+		}
+	}
+
+	existing, err := s.repo.GetIDByPosition(ctx, b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check bin position: %w", err)
+	}
+	if existing != 0 {
+		return nil, NewValidationError("bin", fmt.Sprintf("bin %d is already at this position", existing))
+	}
+
+	id, err := s.repo.Insert(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, b.WarehouseID, id)
+}
+
+// List returns the bins of a warehouse in the order a picker walks them.
+func (s *BinService) List(ctx context.Context, warehouseID int64) ([]*model.Bin, error) {
+	if err := s.checkWarehouse(ctx, warehouseID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByWarehouse(ctx, warehouseID)
+}
+
+// GetByID returns a bin of a warehouse with its contents.
+func (s *BinService) GetByID(ctx context.Context, warehouseID, id int64) (*model.Bin, error) {
+	b, err := s.repo.GetByID(ctx, warehouseID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bin: %w", err)
+	}
+	if b == nil {
+		return nil, ErrNotFound
+	}
+	b.Items, err = s.repo.GetContents(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// PickList suggests where to pick quantity of a product, or of a variant
+// of it, at a warehouse. OUT items without a bin are picked this way.
+func (s *BinService) PickList(ctx context.Context, warehouseID, productID, variantID int64, quantity decimal.Decimal) (*model.PickList, error) {
+	if err := s.checkWarehouse(ctx, warehouseID); err != nil {
+		return nil, err
+	}
+	bins, err := s.repo.GetItemBins(ctx, warehouseID, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+	picks, unbinned := planPicks(bins, quantity)
+	return &model.PickList{Bins: picks, Unbinned: unbinned}, nil
+}
+
+func (s *BinService) checkWarehouse(ctx context.Context, id int64) error {
+	w, err := s.warehouses.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get warehouse: %w", err)
+	}
+	if w == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// planPicks takes quantity from bins in order, each up to what it holds,
+// and returns what to take from each and what is left over.
+func planPicks(bins []model.BinQuantity, quantity decimal.Decimal) ([]model.BinQuantity, decimal.Decimal) {
+	picks := []model.BinQuantity{}
+	for _, b := range bins {
+		if !quantity.IsPositive() {
+			break
+		}
+		if b.Quantity.Cmp(quantity) > 0 {
+			b.Quantity = quantity
+		}
+		picks = append(picks, b)
+		quantity = quantity.Sub(b.Quantity)
+	}
+	return picks, quantity
+}
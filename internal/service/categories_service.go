@@ -48,6 +48,8 @@ func (s *CategoriesService) GetByID(ctx context.Context, id int64) (*model.Categ
 	return category, nil
 }
 
+// UpdateCategory saves a category. A non-zero category.Version must match
+// the stored version, otherwise ErrVersionMismatch is returned.
 func (s *CategoriesService) UpdateCategory(ctx context.Context, category *model.Categories) error {
 	if category == nil {
 		return fmt.Errorf("category is nil")
@@ -56,17 +58,71 @@ func (s *CategoriesService) UpdateCategory(ctx context.Context, category *model.
 		return fmt.Errorf("missing category ID")
 	}
 
-	err := s.Repo.UpdateCategory(ctx, category)
+	updated, err := s.Repo.UpdateCategory(ctx, category)
 	if err != nil {
 		return fmt.Errorf("failed to update category: %w", err)
 	}
-	return nil
+	return s.checkWritten(ctx, category.ID, updated)
 }
 
-func (s *CategoriesService) DeleteCategory(ctx context.Context, id int64) error {
+// PatchCategory saves a category after a merge patch, writing only the
+// fields that changed.
+func (s *CategoriesService) PatchCategory(ctx context.Context, category *model.Categories) error {
+	existing, err := s.Repo.GetCategoryByID(ctx, category.ID)
+	if err != nil {
+		return err
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+	if category.Version != 0 && category.Version != existing.Version {
+		return ErrVersionMismatch
+	}
+
+	var fields []string
+	if category.Name != existing.Name {
+		fields = append(fields, "name")
+	}
+	if category.Description != existing.Description {
+		fields = append(fields, "description")
+	}
+	if len(fields) == 0 {
+		category.Version = existing.Version
+		return nil
+	}
+
+	updated, err := s.Repo.PatchCategory(ctx, category, fields)
+	if err != nil {
+		return err
+	}
+	return s.checkWritten(ctx, category.ID, updated)
+}
+
+func (s *CategoriesService) DeleteCategory(ctx context.Context, id int64, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("invalid category ID")
 	}
 
-	return s.Repo.DeleteCategory(ctx, id)
+	deleted, err := s.Repo.DeleteCategory(ctx, id, version)
+	if err != nil {
+		return err
+	}
+	return s.checkWritten(ctx, id, deleted)
+}
+
+// checkWritten tells a missing category apart from a stale version when a
+// versioned write matched no row.
+func (s *CategoriesService) checkWritten(ctx context.Context, id int64, written bool) error {
+	if written {
+		return nil
+	}
+
+	category, err := s.Repo.GetCategoryByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if category == nil {
+		return ErrNotFound
+	}
+	return ErrVersionMismatch
 }
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// ErrNotFound is returned when the requested resource does not exist.
+var ErrNotFound = errors.New("not found")
+
+// ErrVersionMismatch is returned when an update or delete names a version
+// that is no longer current. Handlers surface it as 412.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// ValidationError reports business-rule failures on specific input fields.
+// Handlers surface Fields as a 400 response.
+type ValidationError struct {
+	Fields map[string]string
+}
+
+func NewValidationError(field, message string) *ValidationError {
+	return &ValidationError{Fields: map[string]string{field: message}}
+}
+
+func (e *ValidationError) Error() string {
+	keys := make([]string, 0, len(e.Fields))
+	for k := range e.Fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		msgs = append(msgs, e.Fields[k])
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
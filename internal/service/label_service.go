@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/barcode"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/label"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type LabelService struct {
+	productRepo *repository.ProductRepository
+}
+
+func NewLabelService(productRepo *repository.ProductRepository) *LabelService {
+	return &LabelService{productRepo: productRepo}
+}
+
+// RenderLabel draws a single product's barcode and returns the image bytes
+// together with their content type.
+func (s *LabelService) RenderLabel(ctx context.Context, id int64, req dto.LabelRequest) ([]byte, string, error) {
+	product, err := s.productRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return nil, "", ErrNotFound
+	}
+
+	symbol, err := productSymbol(product, req.Symbology)
+	if err != nil {
+		return nil, "", err
+	}
+
+	scale := req.Scale
+	if scale == 0 {
+		scale = 4
+	}
+
+	if req.Format == "svg" {
+		return barcode.SVG(symbol, scale), "image/svg+xml", nil
+	}
+	img, err := barcode.PNG(symbol, scale)
+	if err != nil {
+		return nil, "", err
+	}
+	return img, "image/png", nil
+}
+
+// RenderSheet lays out labels for several products, in the order requested,
+// as a printable PDF or a ZPL job.
+func (s *LabelService) RenderSheet(ctx context.Context, req dto.LabelSheetRequest) ([]byte, string, error) {
+	products, err := s.productRepo.GetByIDs(ctx, req.ProductIDs)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get products: %w", err)
+	}
+
+	byID := make(map[int64]*model.Product, len(products))
+	for _, p := range products {
+		byID[p.ID] = p
+	}
+
+	items := make([]label.Item, 0, len(req.ProductIDs))
+	for i, id := range req.ProductIDs {
+		product, ok := byID[id]
+		if !ok {
+			return nil, "", NewValidationError(fmt.Sprintf("product_ids[%d]", i), fmt.Sprintf("product %d does not exist", id))
+		}
+
+		symbol, err := productSymbol(product, req.Symbology)
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, label.Item{Title: product.Name, Symbol: symbol})
+	}
+
+	if req.Format == "zpl" {
+		return label.ZPL(items), "application/zpl", nil
+	}
+	return label.PDF(items), "application/pdf", nil
+}
+
+// productSymbol encodes a product using the requested symbology. Without
+// one, products with a 13-digit barcode get EAN-13 and the rest Code 128.
+func productSymbol(p *model.Product, symbology string) (*barcode.Symbol, error) {
+	if symbology == "" {
+		symbology = barcode.Code128
+		if len(p.Barcode) == 13 {
+			symbology = barcode.EAN13
+		}
+	}
+
+	switch symbology {
+	case barcode.EAN13:
+		if len(p.Barcode) != 13 {
+			return nil, NewValidationError("symbology", fmt.Sprintf("product %d has no EAN-13 barcode", p.ID))
+		}
+		return barcode.EncodeEAN13(p.Barcode)
+	case barcode.QR:
+		return barcode.EncodeQR(labelPayload(p))
+	default:
+		return barcode.EncodeCode128(labelPayload(p))
+	}
+}
+
+// labelPayload is the identifier printed on Code 128 and QR labels.
+func labelPayload(p *model.Product) string {
+	switch {
+	case p.SKU != "":
+		return p.SKU
+	case p.Barcode != "":
+		return p.Barcode
+	default:
+		return strconv.FormatInt(p.ID, 10)
+	}
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"log"
+	"net/http"
+
+	_ "image/gif"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/storage"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
+)
+
+const (
+	MaxImageBytes    = 5 << 20
+	maxImagePixels   = 40_000_000
+	thumbnailMaxSize = 256
+)
+
+var imageExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+type ProductImageService struct {
+	productRepo *repository.ProductRepository
+	imageRepo   *repository.ProductImageRepository
+	store       storage.BlobStore
+}
+
+func NewProductImageService(productRepo *repository.ProductRepository, imageRepo *repository.ProductImageRepository, store storage.BlobStore) *ProductImageService {
+	return &ProductImageService{productRepo: productRepo, imageRepo: imageRepo, store: store}
+}
+
+// Upload validates an image by its content rather than its declared type,
+// stores it with a generated thumbnail and appends it to the product's
+// images.
+func (s *ProductImageService) Upload(ctx context.Context, productID int64, data []byte) (*model.ProductImage, error) {
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, NewValidationError("image", "image is empty")
+	}
+	if len(data) > MaxImageBytes {
+		return nil, NewValidationError("image", fmt.Sprintf("image must be at most %d bytes", MaxImageBytes))
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := imageExtensions[contentType]
+	if !ok {
+		return nil, NewValidationError("image", fmt.Sprintf("unsupported image type %s", contentType))
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, NewValidationError("image", "image could not be decoded")
+	}
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, NewValidationError("image", "image dimensions are too large")
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, NewValidationError("image", "image could not be decoded")
+	}
+	thumb, thumbType, err := encodeThumbnail(utils.Thumbnail(src, thumbnailMaxSize), contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	img := &model.ProductImage{
+		ProductID:    productID,
+		StorageKey:   fmt.Sprintf("products/%d/%s.%s", productID, name, ext),
+		ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.%s", productID, name, imageExtensions[thumbType]),
+		ContentType:  contentType,
+		SizeBytes:    int64(len(data)),
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+	}
+
+	if err := s.store.Put(ctx, img.StorageKey, contentType, data); err != nil {
+		return nil, fmt.Errorf("failed to store image: %w", err)
+	}
+	if err := s.store.Put(ctx, img.ThumbnailKey, thumbType, thumb); err != nil {
+		s.removeBlobs(ctx, img.StorageKey)
+		return nil, fmt.Errorf("failed to store thumbnail: %w", err)
+	}
+
+	img.ID, err = s.imageRepo.Insert(ctx, img)
+	if err != nil {
+		s.removeBlobs(ctx, img.StorageKey, img.ThumbnailKey)
+		return nil, err
+	}
+
+	if err := s.syncPrimary(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	images, err := s.List(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, stored := range images {
+		if stored.ID == img.ID {
+			return stored, nil
+		}
+	}
+	return nil, fmt.Errorf("uploaded image %d not found", img.ID)
+}
+
+func (s *ProductImageService) List(ctx context.Context, productID int64) ([]*model.ProductImage, error) {
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+
+	images, err := s.imageRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		img.URL = s.store.URL(img.StorageKey)
+		img.ThumbnailURL = s.store.URL(img.ThumbnailKey)
+	}
+	return images, nil
+}
+
+func (s *ProductImageService) Delete(ctx context.Context, productID, imageID int64) error {
+	images, err := s.List(ctx, productID)
+	if err != nil {
+		return err
+	}
+
+	var target *model.ProductImage
+	for _, img := range images {
+		if img.ID == imageID {
+			target = img
+		}
+	}
+	if target == nil {
+		return ErrNotFound
+	}
+
+	if err := s.imageRepo.Delete(ctx, productID, imageID); err != nil {
+		return err
+	}
+	s.removeBlobs(ctx, target.StorageKey, target.ThumbnailKey)
+
+	return s.syncPrimary(ctx, productID)
+}
+
+// Reorder sets the display order; ids must list every image of the product
+// exactly once.
+func (s *ProductImageService) Reorder(ctx context.Context, productID int64, ids []int64) ([]*model.ProductImage, error) {
+	images, err := s.List(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[int64]bool, len(images))
+	for _, img := range images {
+		existing[img.ID] = true
+	}
+	if len(ids) != len(images) {
+		return nil, NewValidationError("image_ids", "image_ids must list every image of the product")
+	}
+	for i, id := range ids {
+		if !existing[id] {
+			return nil, NewValidationError(fmt.Sprintf("image_ids[%d]", i), fmt.Sprintf("image %d does not belong to the product or is repeated", id))
+		}
+		delete(existing, id)
+	}
+
+	if err := s.imageRepo.Reorder(ctx, productID, ids); err != nil {
+		return nil, err
+	}
+	if err := s.syncPrimary(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.List(ctx, productID)
+}
+
+func (s *ProductImageService) ensureProduct(ctx context.Context, productID int64) error {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// syncPrimary points products.image_url at the first image so existing
+// product responses keep showing a picture, and clears it once the last
+// image is gone.
+func (s *ProductImageService) syncPrimary(ctx context.Context, productID int64) error {
+	images, err := s.imageRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return s.imageRepo.SetPrimaryImageURL(ctx, productID, "")
+	}
+	return s.imageRepo.SetPrimaryImageURL(ctx, productID, s.store.URL(images[0].StorageKey))
+}
+
+func (s *ProductImageService) removeBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("[ProductImageService] Failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// encodeThumbnail keeps PNG for sources that may carry transparency and
+// uses JPEG otherwise.
+func encodeThumbnail(img image.Image, sourceType string) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if sourceType == "image/png" || sourceType == "image/gif" {
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, "", fmt.Errorf("failed to encode thumbnail: %w", err)
+		}
+		return buf.Bytes(), "image/png", nil
+	}
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, "", fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), "image/jpeg", nil
+}
+
+func randomName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate file name: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/storage"
+)
+
+type ProductImportService struct {
+	store storage.BlobStore
+}
+
+func NewProductImportService(store storage.BlobStore) *ProductImportService {
+	return &ProductImportService{store: store}
+}
+
+// SaveErrorReport stores the rejected rows of an import as a CSV file with
+// one line per field error and returns where it can be downloaded.
+func (s *ProductImportService) SaveErrorReport(ctx context.Context, rows []model.ImportRowError) (string, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"row", "sku", "field", "message"})
+	for _, row := range rows {
+		fields := make([]string, 0, len(row.Errors))
+		for field := range row.Errors {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			_ = w.Write([]string{strconv.Itoa(row.Row), row.SKU, field, row.Errors[field]})
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return "", fmt.Errorf("failed to write error report: %w", err)
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf("imports/%s/errors.csv", name)
+	if err := s.store.Put(ctx, key, "text/csv", buf.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to store error report: %w", err)
+	}
+	return s.store.URL(key), nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type ProductPriceService struct {
+	productRepo *repository.ProductRepository
+	priceRepo   *repository.ProductPriceRepository
+}
+
+func NewProductPriceService(productRepo *repository.ProductRepository, priceRepo *repository.ProductPriceRepository) *ProductPriceService {
+	return &ProductPriceService{productRepo: productRepo, priceRepo: priceRepo}
+}
+
+// Create adds a price history entry. The currency defaults to the one the
+// product is currently priced in and the effective date to now.
+func (s *ProductPriceService) Create(ctx context.Context, price *model.ProductPrice) (*model.ProductPrice, error) {
+	product, err := s.productRepo.GetByID(ctx, price.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return nil, ErrNotFound
+	}
+
+	if price.Price < 0 {
+		return nil, NewValidationError("price", "price must not be negative")
+	}
+	if price.Cost < 0 {
+		return nil, NewValidationError("cost", "cost must not be negative")
+	}
+	price.Currency = strings.ToUpper(strings.TrimSpace(price.Currency))
+	if price.Currency == "" {
+		price.Currency = product.Currency
+	}
+	if price.EffectiveFrom.IsZero() {
+		price.EffectiveFrom = time.Now()
+	}
+	price.EffectiveFrom = price.EffectiveFrom.UTC().Truncate(time.Second)
+
+	price.ID, err = s.priceRepo.Insert(ctx, price)
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := s.priceRepo.GetByProductID(ctx, price.ProductID)
+	if err != nil {
+		return nil, err
+	}
+	for _, stored := range prices {
+		if stored.ID == price.ID {
+			return stored, nil
+		}
+	}
+	return nil, fmt.Errorf("created price %d not found", price.ID)
+}
+
+func (s *ProductPriceService) List(ctx context.Context, productID int64) ([]*model.ProductPrice, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return nil, ErrNotFound
+	}
+	return s.priceRepo.GetByProductID(ctx, productID)
+}
@@ -2,45 +2,485 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
 )
 
 type ProductService struct {
-	repo *repository.ProductRepository
+	repo           *repository.ProductRepository
+	categoriesRepo *repository.CategoriesRepository
+	variantRepo    *repository.ProductVariantRepository
+	historyRepo    *repository.ProductHistoryRepository
 }
 
-func NewProductService(repo *repository.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+func NewProductService(repo *repository.ProductRepository, categoriesRepo *repository.CategoriesRepository, variantRepo *repository.ProductVariantRepository, historyRepo *repository.ProductHistoryRepository) *ProductService {
+	return &ProductService{repo: repo, categoriesRepo: categoriesRepo, variantRepo: variantRepo, historyRepo: historyRepo}
 }
 
 func (s *ProductService) Insert(ctx context.Context, p *model.Product) error {
-	return s.repo.Insert(ctx, p)
+	if err := s.validate(ctx, p); err != nil {
+		return err
+	}
+	return duplicateProductError(s.repo.Insert(ctx, p))
 }
 
-func (s *ProductService) GetAll(ctx context.Context) ([]*model.Product, error) {
-	return s.repo.GetAll(ctx)
+func (s *ProductService) GetAll(ctx context.Context, includeArchived bool) ([]*model.Product, error) {
+	products, err := s.repo.GetAll(ctx, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	return products, s.attachWarehouseStock(ctx, products...)
+}
+
+// Export streams the catalog to fn one product at a time.
+func (s *ProductService) Export(ctx context.Context, includeArchived bool, fn func(*model.ProductExport) error) error {
+	return s.repo.Export(ctx, includeArchived, fn)
 }
 
 func (s *ProductService) GetByID(ctx context.Context, id int64) (*model.Product, error) {
 	if id <= 0 {
 		return nil, fmt.Errorf("invalid product ID")
 	}
-	return s.repo.GetByID(ctx, id)
+
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil || product == nil {
+		return product, err
+	}
+
+	product.Variants, err = s.variantRepo.GetByProductID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.attachWarehouseStock(ctx, product); err != nil {
+		return nil, err
+	}
+
+	if len(product.Variants) > 0 {
+		stock, err := s.variantRepo.GetStockByWarehouse(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range product.Variants {
+			v.StockByWarehouse = stock[v.ID]
+		}
+	}
+	return product, nil
+}
+
+// attachWarehouseStock fills in where the stock of each product is held.
+func (s *ProductService) attachWarehouseStock(ctx context.Context, products ...*model.Product) error {
+	ids := make([]int64, len(products))
+	for i, p := range products {
+		ids[i] = p.ID
+	}
+	stock, err := s.repo.GetStockByWarehouse(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, p := range products {
+		p.StockByWarehouse = stock[p.ID]
+	}
+	return nil
+}
+
+// GetAsOf returns the revision of a product in effect at the given time, or
+// nil if the product did not exist then.
+func (s *ProductService) GetAsOf(ctx context.Context, id int64, asOf time.Time) (*model.ProductRevision, error) {
+	if id <= 0 {
+		return nil, fmt.Errorf("invalid product ID")
+	}
+	return s.historyRepo.GetAsOf(ctx, id, asOf)
+}
+
+// History returns every revision of a product, oldest first, each with the
+// fields changed from the revision before it.
+func (s *ProductService) History(ctx context.Context, id int64) ([]*model.ProductRevision, error) {
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return nil, ErrNotFound
+	}
+
+	revisions, err := s.historyRepo.GetByProductID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(revisions); i++ {
+		revisions[i].Changes, err = diffSnapshots(revisions[i-1].Product, revisions[i].Product)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return revisions, nil
+}
+
+func (s *ProductService) GetBySKU(ctx context.Context, sku string) (*model.Product, error) {
+	return s.repo.GetBySKU(ctx, sku)
+}
+
+func (s *ProductService) GetByBarcode(ctx context.Context, code string) (*model.Product, error) {
+	normalized, err := utils.NormalizeGTIN(code)
+	if err != nil {
+		return nil, NewValidationError("barcode", err.Error())
+	}
+	product, err := s.repo.GetByBarcode(ctx, normalized)
+	if err != nil || product == nil {
+		return product, err
+	}
+	return product, s.attachWarehouseStock(ctx, product)
 }
 
+// Update replaces a product. A non-zero p.Version must match the current
+// version, otherwise ErrVersionMismatch is returned. On success p.Version
+// holds the new version.
 func (s *ProductService) Update(ctx context.Context, p *model.Product) error {
 	if p.ID <= 0 {
 		return fmt.Errorf("invalid product ID")
 	}
-	return s.repo.Update(ctx, p)
+	if err := s.validate(ctx, p); err != nil {
+		return err
+	}
+
+	if err := s.keepVariantStock(ctx, p); err != nil {
+		return err
+	}
+
+	updated, err := s.repo.Update(ctx, p)
+	return s.checkWritten(ctx, p.ID, updated, err)
+}
+
+// Patch saves p, which holds the product after a merge patch was applied,
+// writing only the fields that differ from the stored product. Versions
+// follow the same rule as in Update.
+func (s *ProductService) Patch(ctx context.Context, p *model.Product) error {
+	if p.ID <= 0 {
+		return fmt.Errorf("invalid product ID")
+	}
+
+	fields, err := s.changedFields(ctx, p)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+
+	updated, err := s.repo.Patch(ctx, p, fields)
+	return s.checkWritten(ctx, p.ID, updated, err)
+}
+
+// Upsert creates p when p.ID is 0 and otherwise saves it like Patch,
+// reporting whether the product was created, updated or left unchanged.
+// With dryRun, p is only validated and nothing is written.
+func (s *ProductService) Upsert(ctx context.Context, p *model.Product, dryRun bool) (string, error) {
+	if p.ID == 0 {
+		if err := s.validate(ctx, p); err != nil {
+			return "", err
+		}
+		if !dryRun {
+			if err := s.repo.Insert(ctx, p); err != nil {
+				return "", duplicateProductError(err)
+			}
+		}
+		return model.ImportCreated, nil
+	}
+
+	fields, err := s.changedFields(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 {
+		return model.ImportUnchanged, nil
+	}
+	if dryRun {
+		// The write checks this under a lock; a dry run reports it early.
+		if err := s.checkWarehouseStock(ctx, p); err != nil {
+			return "", err
+		}
+	} else {
+		updated, err := s.repo.Patch(ctx, p, fields)
+		if err := s.checkWritten(ctx, p.ID, updated, err); err != nil {
+			return "", err
+		}
+	}
+	return model.ImportUpdated, nil
+}
+
+// changedFields validates p against the stored product and lists the
+// fields that differ. When nothing differs p.Version is set to the current
+// version.
+func (s *ProductService) changedFields(ctx context.Context, p *model.Product) ([]string, error) {
+	existing, err := s.repo.GetByID(ctx, p.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+	if p.Version != 0 && p.Version != existing.Version {
+		return nil, ErrVersionMismatch
+	}
+
+	if err := s.validate(ctx, p); err != nil {
+		return nil, err
+	}
+	if err := s.keepVariantStock(ctx, p); err != nil {
+		return nil, err
+	}
+
+	fields := changedProductFields(existing, p)
+	if len(fields) == 0 {
+		p.Version = existing.Version
+	}
+	return fields, nil
+}
+
+// keepVariantStock overrides p.Stock for products with variants, whose
+// stock is rolled up from them and can only change through the variants.
+func (s *ProductService) keepVariantStock(ctx context.Context, p *model.Product) error {
+	variants, err := s.variantRepo.GetByProductID(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if len(variants) > 0 {
+		p.Stock = decimal.Decimal{}
+		for _, v := range variants {
+			p.Stock = p.Stock.Add(v.Stock)
+		}
+	}
+	return nil
+}
+
+// checkWarehouseStock makes sure stock set directly on p, which is held at
+// the default warehouse, does not drop below what other warehouses hold.
+// Stock there only changes through transactions. The repository checks this
+// again under a lock when writing.
+func (s *ProductService) checkWarehouseStock(ctx context.Context, p *model.Product) error {
+	elsewhere, err := s.repo.GetStockOutsideDefault(ctx, p.ID)
+	if err != nil {
+		return err
+	}
+	if p.Stock.Cmp(elsewhere) < 0 {
+		return stockBelowOutsideError(elsewhere)
+	}
+	return nil
+}
+
+// stockBelowOutsideError is the validation error for stock set directly
+// below outside, what other warehouses hold or is in transit.
+func stockBelowOutsideError(outside decimal.Decimal) error {
+	return NewValidationError("stock", fmt.Sprintf("stock must be at least %s, the stock held at other warehouses or in transit", outside))
+}
+
+// Archive hides a product from listings and blocks further OUT movements
+// while keeping its transaction history intact. version follows the same
+// rule as in Update.
+func (s *ProductService) Archive(ctx context.Context, id int64, version int) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid product ID")
+	}
+	updated, err := s.repo.SetArchived(ctx, id, true, version)
+	return s.checkWritten(ctx, id, updated, err)
+}
+
+func (s *ProductService) Unarchive(ctx context.Context, id int64, version int) error {
+	if id <= 0 {
+		return fmt.Errorf("invalid product ID")
+	}
+	updated, err := s.repo.SetArchived(ctx, id, false, version)
+	return s.checkWritten(ctx, id, updated, err)
 }
 
-func (s *ProductService) Delete(ctx context.Context, id int64) error {
+// Delete removes a product permanently, which is only possible before it
+// has been used in any transaction.
+func (s *ProductService) Delete(ctx context.Context, id int64, version int) error {
 	if id <= 0 {
 		return fmt.Errorf("invalid product ID")
 	}
-	return s.repo.Delete(ctx, id)
+
+	used, err := s.repo.HasTransactions(ctx, id)
+	if err != nil {
+		return err
+	}
+	if used {
+		return NewValidationError("permanent", "product has transaction history and can only be archived")
+	}
+	deleted, err := s.repo.Delete(ctx, id, version)
+	return s.checkWritten(ctx, id, deleted, err)
+}
+
+// duplicateProductError reports a sku or barcode taken by another product
+// between validation and the write as a validation error on that field.
+func duplicateProductError(err error) error {
+	var dup *repository.DuplicateProductError
+	if errors.As(err, &dup) {
+		return NewValidationError(dup.Field, dup.Error())
+	}
+	return err
+}
+
+// checkWritten explains a versioned write that matched no row: the product
+// is either gone or was changed by someone else.
+func (s *ProductService) checkWritten(ctx context.Context, id int64, written bool, err error) error {
+	var below *repository.StockBelowOutsideError
+	if errors.As(err, &below) {
+		return stockBelowOutsideError(below.Outside)
+	}
+	if err != nil || written {
+		return duplicateProductError(err)
+	}
+
+	product, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return ErrNotFound
+	}
+	return ErrVersionMismatch
+}
+
+func (s *ProductService) validate(ctx context.Context, p *model.Product) error {
+	p.BaseUnit = strings.ToLower(strings.TrimSpace(p.BaseUnit))
+	if p.BaseUnit == "" {
+		p.BaseUnit = model.DefaultBaseUnit
+	}
+
+	p.Currency = strings.ToUpper(strings.TrimSpace(p.Currency))
+	if p.Currency == "" {
+		p.Currency = model.DefaultCurrency
+	}
+	if p.Price < 0 {
+		return NewValidationError("price", "price must not be negative")
+	}
+	if p.Cost < 0 {
+		return NewValidationError("cost", "cost must not be negative")
+	}
+
+	if p.Stock.IsNegative() {
+		return NewValidationError("stock", "stock must not be negative")
+	}
+	if p.Precision < 0 || p.Precision > decimal.Scale {
+		return NewValidationError("quantity_precision", fmt.Sprintf("quantity_precision must be between 0 and %d", decimal.Scale))
+	}
+	if p.Stock.Places() > p.Precision {
+		return NewValidationError("stock", fmt.Sprintf("stock allows at most %d decimal places", p.Precision))
+	}
+
+	if p.Barcode != "" {
+		normalized, err := utils.NormalizeGTIN(p.Barcode)
+		if err != nil {
+			return NewValidationError("barcode", err.Error())
+		}
+		p.Barcode = normalized
+
+		existing, err := s.repo.GetByBarcode(ctx, p.Barcode)
+		if err != nil {
+			return fmt.Errorf("failed to check barcode: %w", err)
+		}
+		if existing != nil && existing.ID != p.ID {
+			return NewValidationError("barcode", fmt.Sprintf("barcode %s is already used by product %d", p.Barcode, existing.ID))
+		}
+	}
+
+	if p.SKU != "" {
+		existing, err := s.repo.GetBySKU(ctx, p.SKU)
+		if err != nil {
+			return fmt.Errorf("failed to check sku: %w", err)
+		}
+		if existing != nil && existing.ID != p.ID {
+			return NewValidationError("sku", fmt.Sprintf("sku %s is already used by product %d", p.SKU, existing.ID))
+		}
+
+		variant, err := s.variantRepo.GetBySKU(ctx, p.SKU)
+		if err != nil {
+			return fmt.Errorf("failed to check sku: %w", err)
+		}
+		if variant != nil {
+			return NewValidationError("sku", fmt.Sprintf("sku %s is already used by variant %d", p.SKU, variant.ID))
+		}
+	}
+
+	category, err := s.categoriesRepo.GetCategoryByID(ctx, int64(p.CategoryID))
+	if err != nil {
+		return fmt.Errorf("failed to check category: %w", err)
+	}
+	if category == nil {
+		return NewValidationError("category_id", fmt.Sprintf("category %d does not exist", p.CategoryID))
+	}
+
+	return nil
+}
+
+// changedProductFields lists, by API name, the fields of p that differ
+// from the stored product.
+func changedProductFields(old, p *model.Product) []string {
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	add("sku", old.SKU != p.SKU)
+	add("barcode", old.Barcode != p.Barcode)
+	add("name", old.Name != p.Name)
+	add("description", old.Description != p.Description)
+	add("image_url", old.ImageURL != p.ImageURL)
+	add("category_id", old.CategoryID != p.CategoryID)
+	add("base_unit", old.BaseUnit != p.BaseUnit)
+	add("quantity_precision", old.Precision != p.Precision)
+	add("stock", old.Stock.Cmp(p.Stock) != 0)
+	add("price", old.Price != p.Price)
+	add("cost", old.Cost != p.Cost)
+	add("currency", old.Currency != p.Currency)
+	return fields
+}
+
+// diffSnapshots lists the fields, by JSON name, whose values differ.
+func diffSnapshots(from, to model.ProductSnapshot) ([]model.FieldChange, error) {
+	before, err := snapshotFields(from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := snapshotFields(to)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(after))
+	for field := range after {
+		fields = append(fields, field)
+	}
+	for field := range before {
+		if _, ok := after[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	var changes []model.FieldChange
+	for _, field := range fields {
+		if !reflect.DeepEqual(before[field], after[field]) {
+			changes = append(changes, model.FieldChange{Field: field, From: before[field], To: after[field]})
+		}
+	}
+	return changes, nil
+}
+
+func snapshotFields(s model.ProductSnapshot) (map[string]any, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]any{}
+	return fields, json.Unmarshal(data, &fields)
 }
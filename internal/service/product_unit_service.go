@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type ProductUnitService struct {
+	productRepo *repository.ProductRepository
+	unitRepo    *repository.ProductUnitRepository
+}
+
+func NewProductUnitService(productRepo *repository.ProductRepository, unitRepo *repository.ProductUnitRepository) *ProductUnitService {
+	return &ProductUnitService{productRepo: productRepo, unitRepo: unitRepo}
+}
+
+func (s *ProductUnitService) Create(ctx context.Context, u *model.ProductUnit) (*model.ProductUnit, error) {
+	product, err := s.getProduct(ctx, u.ProductID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.Name = strings.ToLower(strings.TrimSpace(u.Name))
+	if u.Name == product.BaseUnit {
+		return nil, NewValidationError("name", fmt.Sprintf("%s is already the base unit", u.Name))
+	}
+	if u.Factor <= 1 {
+		return nil, NewValidationError("factor", "factor must be greater than 1")
+	}
+
+	existing, err := s.unitRepo.GetByName(ctx, u.ProductID, u.Name)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, NewValidationError("name", fmt.Sprintf("unit %s already exists", u.Name))
+	}
+
+	u.ID, err = s.unitRepo.Insert(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *ProductUnitService) List(ctx context.Context, productID int64) ([]*model.ProductUnit, error) {
+	product, err := s.getProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	units, err := s.unitRepo.GetByProductID(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	base := &model.ProductUnit{ProductID: productID, Name: product.BaseUnit, Factor: 1}
+	return append([]*model.ProductUnit{base}, units...), nil
+}
+
+func (s *ProductUnitService) Delete(ctx context.Context, productID, id int64) error {
+	deleted, err := s.unitRepo.Delete(ctx, productID, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Stock reports the product's stock in the requested unit as whole units
+// plus the remainder in base units.
+func (s *ProductUnitService) Stock(ctx context.Context, productID int64, unit string) (*model.StockInUnit, error) {
+	product, err := s.getProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	factor := int64(1)
+	if unit == "" {
+		unit = product.BaseUnit
+	}
+	if unit != product.BaseUnit {
+		u, err := s.unitRepo.GetByName(ctx, productID, unit)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, NewValidationError("unit", fmt.Sprintf("unit %q is not configured for this product", unit))
+		}
+		factor = u.Factor
+	}
+
+	quantity, remainder := product.Stock.QuoRemInt(factor)
+	return &model.StockInUnit{
+		ProductID: productID,
+		BaseUnit:  product.BaseUnit,
+		BaseStock: product.Stock,
+		Unit:      unit,
+		Factor:    factor,
+		Quantity:  quantity,
+		Remainder: remainder,
+	}, nil
+}
+
+func (s *ProductUnitService) getProduct(ctx context.Context, productID int64) (*model.Product, error) {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return nil, ErrNotFound
+	}
+	return product, nil
+}
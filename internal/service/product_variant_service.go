@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type ProductVariantService struct {
+	productRepo *repository.ProductRepository
+	variantRepo *repository.ProductVariantRepository
+}
+
+func NewProductVariantService(productRepo *repository.ProductRepository, variantRepo *repository.ProductVariantRepository) *ProductVariantService {
+	return &ProductVariantService{productRepo: productRepo, variantRepo: variantRepo}
+}
+
+// Create adds a variant. Once a product has variants its stock is the sum
+// of theirs, so the first variant is refused while the product holds stock
+// or has stock movements of its own.
+func (s *ProductVariantService) Create(ctx context.Context, v *model.ProductVariant) (*model.ProductVariant, error) {
+	if err := s.ensureProduct(ctx, v.ProductID); err != nil {
+		return nil, err
+	}
+	if err := s.validate(ctx, v); err != nil {
+		return nil, err
+	}
+
+	id, err := s.variantRepo.Insert(ctx, v)
+	if errors.Is(err, repository.ErrProductInUse) {
+		return nil, NewValidationError("product_id",
+			fmt.Sprintf("product %d has stock or stock movements; variants can only be added to a product without them", v.ProductID))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.variantRepo.GetByID(ctx, v.ProductID, id)
+}
+
+func (s *ProductVariantService) List(ctx context.Context, productID int64) ([]*model.ProductVariant, error) {
+	if err := s.ensureProduct(ctx, productID); err != nil {
+		return nil, err
+	}
+	return s.variantRepo.GetByProductID(ctx, productID)
+}
+
+func (s *ProductVariantService) Update(ctx context.Context, v *model.ProductVariant) (*model.ProductVariant, error) {
+	existing, err := s.variantRepo.GetByID(ctx, v.ProductID, v.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get variant: %w", err)
+	}
+	if existing == nil {
+		return nil, ErrNotFound
+	}
+	if err := s.validate(ctx, v); err != nil {
+		return nil, err
+	}
+
+	// Stock set directly is held at the default warehouse; stock at other
+	// warehouses only changes through transactions, so it can't drop below
+	// what they hold.
+	err = s.variantRepo.Update(ctx, v)
+	var below *repository.StockBelowOutsideError
+	if errors.As(err, &below) {
+		return nil, stockBelowOutsideError(below.Outside)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.variantRepo.GetByID(ctx, v.ProductID, v.ID)
+}
+
+func (s *ProductVariantService) Delete(ctx context.Context, productID, id int64) error {
+	existing, err := s.variantRepo.GetByID(ctx, productID, id)
+	if err != nil {
+		return fmt.Errorf("failed to get variant: %w", err)
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+
+	used, err := s.variantRepo.HasTransactions(ctx, id)
+	if err != nil {
+		return err
+	}
+	if used {
+		return NewValidationError("variant_id", fmt.Sprintf("variant %d has stock movements and cannot be deleted", id))
+	}
+
+	return s.variantRepo.Delete(ctx, productID, id)
+}
+
+func (s *ProductVariantService) ensureProduct(ctx context.Context, productID int64) error {
+	product, err := s.productRepo.GetByID(ctx, productID)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if product == nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *ProductVariantService) validate(ctx context.Context, v *model.ProductVariant) error {
+	if v.Stock.IsNegative() {
+		return NewValidationError("stock", "stock must not be negative")
+	}
+
+	product, err := s.productRepo.GetByID(ctx, v.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get product: %w", err)
+	}
+	if product != nil && v.Stock.Places() > product.Precision {
+		return NewValidationError("stock", fmt.Sprintf("stock allows at most %d decimal places", product.Precision))
+	}
+
+	siblings, err := s.variantRepo.GetByProductID(ctx, v.ProductID)
+	if err != nil {
+		return err
+	}
+	for _, other := range siblings {
+		if other.ID != v.ID && other.OptionKey() == v.OptionKey() {
+			return NewValidationError("options", fmt.Sprintf("variant %d already has these options", other.ID))
+		}
+	}
+
+	if v.SKU != "" {
+		other, err := s.variantRepo.GetBySKU(ctx, v.SKU)
+		if err != nil {
+			return fmt.Errorf("failed to check sku: %w", err)
+		}
+		if other != nil && other.ID != v.ID {
+			return NewValidationError("sku", fmt.Sprintf("sku %s is already used by variant %d", v.SKU, other.ID))
+		}
+		owner, err := s.productRepo.GetBySKU(ctx, v.SKU)
+		if err != nil {
+			return fmt.Errorf("failed to check sku: %w", err)
+		}
+		if owner != nil {
+			return NewValidationError("sku", fmt.Sprintf("sku %s is already used by product %d", v.SKU, owner.ID))
+		}
+	}
+
+	return nil
+}
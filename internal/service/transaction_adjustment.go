@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+// ErrNotPending is returned when approving or rejecting a transaction that
+// is not waiting for approval. Handlers surface it as 409.
+var ErrNotPending = errors.New("transaction is not pending approval")
+
+// reasonCountCorrection sets stock to a counted quantity. The other ADJUST
+// reasons move it by a quantity: found adds stock, damaged and lost remove
+// it.
+const reasonCountCorrection = "count_correction"
+
+// validateAdjustment checks the fields that only apply to ADJUST
+// transactions.
+func validateAdjustment(req *dto.CreateTransactionRequest) error {
+	if req.TransactionType != "ADJUST" {
+		if req.Reason != "" {
+			return NewValidationError("reason", "reason only applies to ADJUST transactions")
+		}
+		for i, item := range req.Items {
+			if item.Counted != nil {
+				return NewValidationError(fmt.Sprintf("items[%d].counted", i), "counted only applies to ADJUST transactions")
+			}
+		}
+		return nil
+	}
+
+	if req.Reason == "" {
+		return NewValidationError("reason", "reason is required for ADJUST transactions")
+	}
+	for i, item := range req.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		switch {
+		case req.Reason == reasonCountCorrection && item.Counted == nil:
+			return NewValidationError(field+".counted", "counted is required for count_correction")
+		case req.Reason != reasonCountCorrection && item.Counted != nil:
+			return NewValidationError(field+".counted", "counted only applies to count_correction")
+		case item.Counted != nil && !item.Quantity.IsZero():
+			return NewValidationError(field+".quantity", "give either quantity or counted")
+		case item.UnitCost != nil:
+			return NewValidationError(field+".unit_cost", "adjustments are valued at the product's cost")
+		case item.UnitPrice != nil:
+			return NewValidationError(field+".unit_price", "unit_price only applies to OUT items")
+		}
+	}
+	return nil
+}
+
+// recordAdjustment records the items of an ADJUST transaction t. Unless
+// it needs approval, stock is moved straight away; otherwise t is left
+// pending with the items as requested.
+func (s *TransactionService) recordAdjustment(ctx context.Context, tx *sql.Tx, t *model.Transaction, req *dto.CreateTransactionRequest) error {
+	items := make([]*model.TransactionItem, len(req.Items))
+	values := map[string]int64{}
+	for i, item := range req.Items {
+		itemModel, value, err := s.prepareAdjustment(ctx, tx, i, t, item)
+		if err != nil {
+			return err
+		}
+		items[i] = itemModel
+		values[itemModel.Currency] += value
+	}
+
+	if s.needsApproval(req.Role, values) {
+		log.Printf("[TransactionService] Adjustment %d is waiting for approval", t.ID)
+		t.Status = model.TransactionPending
+		if err := s.repo.UpdateTransactionStatus(ctx, tx, t.ID, t.Status, nil, nil); err != nil {
+			return fmt.Errorf("failed to update transaction status: %w", err)
+		}
+		for _, item := range items {
+			if _, err := s.repo.InsertTransactionItem(ctx, tx, item); err != nil {
+				return fmt.Errorf("failed to insert transaction item: %w", err)
+			}
+		}
+		return nil
+	}
+
+	for i, item := range items {
+		if err := s.postAdjustment(ctx, tx, i, t.WarehouseID, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// needsApproval reports whether an adjustment worth values, per currency,
+// has to wait for a manager. Managers and admins need no approval.
+func (s *TransactionService) needsApproval(role string, values map[string]int64) bool {
+	if role == model.RoleManager || role == model.RoleAdmin {
+		return false
+	}
+	for _, value := range values {
+		if value > s.approvalThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareAdjustment checks an ADJUST item and turns it into a transaction
+// item valued at the product's current cost. Its Quantity is the change in
+// base units the item would make now at t's warehouse, where a count is
+// taken. The value of that change is returned for the approval check.
+func (s *TransactionService) prepareAdjustment(ctx context.Context, tx *sql.Tx, index int, t *model.Transaction, item dto.TransactionItemRequest) (*model.TransactionItem, int64, error) {
+	productID, variantID, err := s.resolveItem(ctx, tx, index, item)
+	if err != nil {
+		return nil, 0, err
+	}
+	item.ProductID = productID
+	item.VariantID = variantID
+
+	factor, err := s.unitFactor(ctx, tx, index, &item)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("product not found or locked: %w", err)
+	}
+
+	if item.VariantID == 0 {
+		hasVariants, err := s.repo.HasVariants(ctx, tx, item.ProductID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to check product variants: %w", err)
+		}
+		if hasVariants {
+			return nil, 0, NewValidationError(fmt.Sprintf("items[%d].variant_id", index), fmt.Sprintf("product ID %d has variants; variant_id is required", item.ProductID))
+		}
+	} else {
+		_, err = s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, NewValidationError(fmt.Sprintf("items[%d].variant_id", index), fmt.Sprintf("variant %d does not belong to product ID %d", item.VariantID, item.ProductID))
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("variant not found or locked: %w", err)
+		}
+	}
+	current, err := s.itemStockAt(ctx, tx, t.WarehouseID, item.ProductID, item.VariantID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	_, cost, currency, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get product pricing: %w", err)
+	}
+	itemModel := &model.TransactionItem{
+		TransactionID: t.ID,
+		ProductID:     item.ProductID,
+		VariantID:     item.VariantID,
+		Currency:      currency,
+	}
+
+	field := fmt.Sprintf("items[%d].quantity", index)
+	if item.Counted != nil {
+		// A count is kept in base units only, valued per base unit.
+		field = fmt.Sprintf("items[%d].counted", index)
+		counted, err := item.Counted.MulInt(factor)
+		if err != nil {
+			return nil, 0, NewValidationError(field, "counted is out of range")
+		}
+		itemModel.Counted = &counted
+		itemModel.Quantity = counted.Sub(current)
+		itemModel.UnitCost = &cost
+	} else {
+		unitQuantity := item.Quantity
+		quantity, err := item.Quantity.MulInt(factor)
+		if err != nil {
+			return nil, 0, NewValidationError(field, "quantity is out of range")
+		}
+		itemModel.Quantity = quantity
+		if t.Reason != "found" {
+			unitQuantity = unitQuantity.Neg()
+			itemModel.Quantity = itemModel.Quantity.Neg()
+		}
+		if item.Unit != "" {
+			itemModel.Unit = item.Unit
+			itemModel.UnitQuantity = &unitQuantity
+		}
+		unitCost := cost * factor
+		itemModel.UnitCost = &unitCost
+	}
+	if itemModel.Quantity.Places() > product.Precision || (itemModel.Counted != nil && itemModel.Counted.Places() > product.Precision) {
+		return nil, 0, NewValidationError(field, fmt.Sprintf("product ID %d allows at most %d decimal places", item.ProductID, product.Precision))
+	}
+
+	change := itemModel.Quantity
+	if change.IsNegative() {
+		change = change.Neg()
+	}
+	return itemModel, change.MulAmount(cost), nil
+}
+
+// postAdjustment moves the stock of an ADJUST item at a warehouse,
+// inserting the item if it has no ID yet. A counted item is settled against
+// the stock on hand there now, so approving a count later still sets the
+// counted quantity.
+func (s *TransactionService) postAdjustment(ctx context.Context, tx *sql.Tx, index int, warehouseID int64, item *model.TransactionItem) error {
+	belowZero := NewValidationError(fmt.Sprintf("items[%d].quantity", index),
+		fmt.Sprintf("adjustment would take the stock of product ID %d at warehouse %d below zero", item.ProductID, warehouseID))
+
+	product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("product not found or locked: %w", err)
+	}
+	stock := product.Stock
+
+	var variantStock decimal.Decimal
+	if item.VariantID != 0 {
+		variantStock, err = s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID)
+		if err != nil {
+			return fmt.Errorf("variant not found or locked: %w", err)
+		}
+	}
+
+	delta := item.Quantity
+	if item.Counted != nil {
+		current, err := s.itemStockAt(ctx, tx, warehouseID, item.ProductID, item.VariantID)
+		if err != nil {
+			return err
+		}
+		delta = item.Counted.Sub(current)
+	}
+
+	if item.VariantID != 0 {
+		newVariantStock := variantStock.Add(delta)
+		if newVariantStock.IsNegative() {
+			return belowZero
+		}
+		if err := s.repo.UpdateVariantStock(ctx, tx, item.VariantID, newVariantStock); err != nil {
+			return fmt.Errorf("failed to update variant stock: %w", err)
+		}
+	}
+
+	newStock := stock.Add(delta)
+	if newStock.IsNegative() {
+		return belowZero
+	}
+	if err := s.repo.UpdateProductStock(ctx, tx, item.ProductID, newStock); err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+	moved, err := s.moveLocationStock(ctx, tx, warehouseID, item.ProductID, item.VariantID, delta)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return belowZero
+	}
+
+	item.Quantity = delta
+	item.StockBefore = &stock
+	item.StockAfter = &newStock
+	if item.ID == 0 {
+		item.ID, err = s.repo.InsertTransactionItem(ctx, tx, item)
+	} else {
+		err = s.repo.UpdateItemMovement(ctx, tx, item)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record transaction item: %w", err)
+	}
+
+	switch {
+	case delta.IsPositive():
+		lineCost := delta.MulAmount(*item.UnitCost)
+		if item.UnitQuantity != nil {
+			lineCost = item.UnitQuantity.MulAmount(*item.UnitCost)
+		}
+		return s.valuation.Receive(ctx, tx, item.ProductID, item.ID, delta, lineCost, item.Currency)
+	case delta.IsNegative():
+		_, cost, _, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to get product pricing: %w", err)
+		}
+		cogs, err := s.valuation.Issue(ctx, tx, item.ProductID, item.ID, delta.Neg(), cost, item.Currency)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SetItemCostOfGoods(ctx, tx, item.ID, cogs); err != nil {
+			return fmt.Errorf("failed to record cost of goods: %w", err)
+		}
+	}
+	return nil
+}
+
+// Review approves or rejects a pending adjustment on behalf of reviewerID.
+// Approving moves its stock as of now; rejecting leaves stock alone.
+func (s *TransactionService) Review(ctx context.Context, id, reviewerID int64, approve bool) (*model.TransactionWithItems, error) {
+	log.Printf("[TransactionService] Reviewing transaction %d by user_id: %d, approve: %t", id, reviewerID, approve)
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := s.repo.GetTransactionForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if t == nil {
+		return nil, ErrNotFound
+	}
+	if t.Status != model.TransactionPending {
+		return nil, ErrNotPending
+	}
+
+	status := model.TransactionRejected
+	if approve {
+		status = model.TransactionPosted
+		for i := range t.Items {
+			if err := s.postAdjustment(ctx, tx, i, t.WarehouseID, &t.Items[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	reviewedAt := time.Now().UTC().Truncate(time.Second)
+	if err := s.repo.UpdateTransactionStatus(ctx, tx, id, status, &reviewerID, &reviewedAt); err != nil {
+		return nil, fmt.Errorf("failed to update transaction status: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetByID(ctx, id, 0)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/storage"
+)
+
+const (
+	MaxAttachmentBytes    = 10 << 20
+	maxAttachmentNameSize = 255
+)
+
+// attachmentExtensions are the accepted attachment types, as detected from
+// their content: scans and photos of paperwork.
+var attachmentExtensions = map[string]string{
+	"application/pdf": "pdf",
+	"image/jpeg":      "jpg",
+	"image/png":       "png",
+	"image/webp":      "webp",
+}
+
+type TransactionAttachmentService struct {
+	transactionRepo *repository.TransactionRepository
+	attachmentRepo  *repository.TransactionAttachmentRepository
+	store           storage.BlobStore
+}
+
+func NewTransactionAttachmentService(transactionRepo *repository.TransactionRepository, attachmentRepo *repository.TransactionAttachmentRepository,
+	store storage.BlobStore) *TransactionAttachmentService {
+	return &TransactionAttachmentService{transactionRepo: transactionRepo, attachmentRepo: attachmentRepo, store: store}
+}
+
+// Upload stores a file with transaction transactionID on behalf of userID.
+// owner limits it to transactions recorded by that user, as in
+// TransactionService.GetByID. The type is taken from the content rather
+// than the name.
+func (s *TransactionAttachmentService) Upload(ctx context.Context, transactionID, owner, userID int64, fileName string, data []byte) (*model.TransactionAttachment, error) {
+	if err := s.ensureTransaction(ctx, transactionID, owner); err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, NewValidationError("file", "file is empty")
+	}
+	if len(data) > MaxAttachmentBytes {
+		return nil, NewValidationError("file", fmt.Sprintf("file must be at most %d bytes", MaxAttachmentBytes))
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := attachmentExtensions[contentType]
+	if !ok {
+		return nil, NewValidationError("file", fmt.Sprintf("unsupported file type %s; use PDF, JPEG, PNG or WebP", contentType))
+	}
+
+	name, err := randomName()
+	if err != nil {
+		return nil, err
+	}
+	a := &model.TransactionAttachment{
+		TransactionID: transactionID,
+		FileName:      attachmentName(fileName, ext),
+		ContentType:   contentType,
+		SizeBytes:     int64(len(data)),
+		StorageKey:    fmt.Sprintf("transactions/%d/%s.%s", transactionID, name, ext),
+		UploadedBy:    userID,
+		CreatedAt:     time.Now().UTC().Truncate(time.Second),
+	}
+
+	if err := s.store.Put(ctx, a.StorageKey, contentType, data); err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+	a.ID, err = s.attachmentRepo.Insert(ctx, a)
+	if err != nil {
+		s.removeBlob(ctx, a.StorageKey)
+		return nil, err
+	}
+	a.URL = s.store.URL(a.StorageKey)
+	return a, nil
+}
+
+// List returns the attachments of a transaction, oldest first. owner is as
+// for Upload.
+func (s *TransactionAttachmentService) List(ctx context.Context, transactionID, owner int64) ([]*model.TransactionAttachment, error) {
+	if err := s.ensureTransaction(ctx, transactionID, owner); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.attachmentRepo.GetByTransactionID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		a.URL = s.store.URL(a.StorageKey)
+	}
+	return attachments, nil
+}
+
+// Delete removes an attachment and its file. owner is as for Upload.
+func (s *TransactionAttachmentService) Delete(ctx context.Context, transactionID, attachmentID, owner int64) error {
+	attachments, err := s.List(ctx, transactionID, owner)
+	if err != nil {
+		return err
+	}
+
+	var target *model.TransactionAttachment
+	for _, a := range attachments {
+		if a.ID == attachmentID {
+			target = a
+		}
+	}
+	if target == nil {
+		return ErrNotFound
+	}
+
+	if err := s.attachmentRepo.Delete(ctx, transactionID, attachmentID); err != nil {
+		return err
+	}
+	s.removeBlob(ctx, target.StorageKey)
+	return nil
+}
+
+// Authorize reports ErrNotFound unless the transaction exists and, when
+// owner is set, was recorded by owner, as for List.
+func (s *TransactionAttachmentService) Authorize(ctx context.Context, transactionID, owner int64) error {
+	return s.ensureTransaction(ctx, transactionID, owner)
+}
+
+func (s *TransactionAttachmentService) ensureTransaction(ctx context.Context, transactionID, owner int64) error {
+	userID, err := s.transactionRepo.GetTransactionOwner(ctx, transactionID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != 0 && userID != owner) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *TransactionAttachmentService) removeBlob(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil {
+		log.Printf("[TransactionAttachmentService] Failed to delete blob %s: %v", key, err)
+	}
+}
+
+// attachmentName keeps the base of the uploaded file name for display,
+// falling back to a generic name with the detected extension.
+func attachmentName(fileName, ext string) string {
+	name := strings.TrimSpace(filepath.Base(strings.ReplaceAll(fileName, `\`, "/")))
+	if name == "." || name == "/" || name == "" || !utf8.ValidString(name) {
+		return "attachment." + ext
+	}
+	for len(name) > maxAttachmentNameSize {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+// validateBins checks that only IN and OUT items name a bin.
+func validateBins(req *dto.CreateTransactionRequest) error {
+	if req.TransactionType == "IN" || req.TransactionType == "OUT" {
+		return nil
+	}
+	for i, item := range req.Items {
+		if item.BinID != 0 {
+			return NewValidationError(fmt.Sprintf("items[%d].bin_id", i), "bin_id only applies to IN and OUT items")
+		}
+	}
+	return nil
+}
+
+// putAway puts quantity of an item received at a warehouse into binID, or
+// without one into the bin FindPutawayBin suggests. Without a bin with
+// room the stock stays unbinned.
+func (s *TransactionService) putAway(ctx context.Context, tx *sql.Tx, index int, warehouseID, productID, variantID int64, quantity decimal.Decimal, binID int64) ([]model.BinQuantity, error) {
+	field := fmt.Sprintf("items[%d].bin_id", index)
+	requested := binID != 0
+	if !requested {
+		var err error
+		binID, err = s.repo.FindPutawayBin(ctx, tx, warehouseID, productID, variantID, quantity)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find putaway bin: %w", err)
+		}
+		if binID == 0 {
+			return nil, nil
+		}
+	}
+
+	bin, err := s.repo.GetBinForUpdate(ctx, tx, binID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && bin.WarehouseID != warehouseID) {
+		return nil, NewValidationError(field, fmt.Sprintf("bin %d is not in warehouse %d", binID, warehouseID))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("bin not found or locked: %w", err)
+	}
+	if bin.Capacity != nil && bin.Used.Add(quantity).Cmp(*bin.Capacity) > 0 {
+		if !requested {
+			// Another putaway filled the bin after it was suggested.
+			return nil, nil
+		}
+		return nil, NewValidationError(field, fmt.Sprintf("bin %s has room for %s more", bin.Code, bin.Capacity.Sub(bin.Used)))
+	}
+
+	if err := s.repo.AddBinStock(ctx, tx, binID, productID, variantID, quantity); err != nil {
+		return nil, fmt.Errorf("failed to update bin stock: %w", err)
+	}
+	return []model.BinQuantity{{BinID: binID, BinCode: bin.Code, Quantity: quantity}}, nil
+}
+
+// pickBins takes quantity of an item going out of a warehouse out of binID,
+// which must hold enough of it, or without one out of the bins of the pick
+// list. Whatever the bins don't hold comes from unbinned stock.
+func (s *TransactionService) pickBins(ctx context.Context, tx *sql.Tx, index int, warehouseID, productID, variantID int64, quantity decimal.Decimal, binID int64) ([]model.BinQuantity, error) {
+	bins, err := s.repo.GetItemBinsForUpdate(ctx, tx, warehouseID, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var picks []model.BinQuantity
+	if binID == 0 {
+		picks, _ = planPicks(bins, quantity)
+	} else {
+		held := model.BinQuantity{BinID: binID}
+		for _, b := range bins {
+			if b.BinID == binID {
+				held = b
+			}
+		}
+		if held.Quantity.Cmp(quantity) < 0 {
+			return nil, NewValidationError(fmt.Sprintf("items[%d].bin_id", index),
+				fmt.Sprintf("bin %d holds %s of product ID %d at warehouse %d", binID, held.Quantity, productID, warehouseID))
+		}
+		held.Quantity = quantity
+		picks = []model.BinQuantity{held}
+	}
+
+	for _, p := range picks {
+		if err := s.repo.TakeBinStock(ctx, tx, p.BinID, productID, variantID, p.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to update bin stock: %w", err)
+		}
+	}
+	return picks, nil
+}
+
+// fitBins takes stock of an item out of the bins of a warehouse, along the
+// pick list, until they hold no more than stock, what is left of the item
+// there. It keeps bins right after movements that don't pick.
+func (s *TransactionService) fitBins(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64, stock decimal.Decimal) error {
+	bins, err := s.repo.GetItemBinsForUpdate(ctx, tx, warehouseID, productID, variantID)
+	if err != nil {
+		return err
+	}
+	excess := stock.Neg()
+	for _, b := range bins {
+		excess = excess.Add(b.Quantity)
+	}
+	if !excess.IsPositive() {
+		return nil
+	}
+
+	picks, _ := planPicks(bins, excess)
+	for _, p := range picks {
+		if err := s.repo.TakeBinStock(ctx, tx, p.BinID, productID, variantID, p.Quantity); err != nil {
+			return fmt.Errorf("failed to update bin stock: %w", err)
+		}
+	}
+	return nil
+}
+
+// takeFromBins takes stock of an item back out of bins it was put away to,
+// as far as they still hold it, before it leaves the warehouse.
+func (s *TransactionService) takeFromBins(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64, bins []model.BinQuantity) ([]model.BinQuantity, error) {
+	if len(bins) == 0 {
+		return nil, nil
+	}
+	held, err := s.repo.GetItemBinsForUpdate(ctx, tx, warehouseID, productID, variantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var taken []model.BinQuantity
+	for _, b := range bins {
+		for _, h := range held {
+			if h.BinID != b.BinID {
+				continue
+			}
+			if h.Quantity.Cmp(b.Quantity) < 0 {
+				b.Quantity = h.Quantity
+			}
+			if !b.Quantity.IsPositive() {
+				break
+			}
+			if err := s.repo.TakeBinStock(ctx, tx, b.BinID, productID, variantID, b.Quantity); err != nil {
+				return nil, fmt.Errorf("failed to update bin stock: %w", err)
+			}
+			taken = append(taken, b)
+		}
+	}
+	return taken, nil
+}
+
+// returnToBins puts stock of an item that came back to a warehouse into
+// bins it was picked from, as far as they have room. The rest stays
+// unbinned.
+func (s *TransactionService) returnToBins(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64, bins []model.BinQuantity) ([]model.BinQuantity, error) {
+	var returned []model.BinQuantity
+	for _, b := range bins {
+		bin, err := s.repo.GetBinForUpdate(ctx, tx, b.BinID)
+		if err != nil {
+			return nil, fmt.Errorf("bin not found or locked: %w", err)
+		}
+		if bin.WarehouseID != warehouseID || (bin.Capacity != nil && bin.Used.Add(b.Quantity).Cmp(*bin.Capacity) > 0) {
+			continue
+		}
+		if err := s.repo.AddBinStock(ctx, tx, b.BinID, productID, variantID, b.Quantity); err != nil {
+			return nil, fmt.Errorf("failed to update bin stock: %w", err)
+		}
+		b.BinCode = bin.Code
+		returned = append(returned, b)
+	}
+	return returned, nil
+}
+
+// recordItemBins records the bins a transaction item was put away to or
+// picked from.
+func (s *TransactionService) recordItemBins(ctx context.Context, tx *sql.Tx, itemID int64, bins []model.BinQuantity) error {
+	for _, b := range bins {
+		if err := s.repo.InsertItemBin(ctx, tx, itemID, b); err != nil {
+			return fmt.Errorf("failed to record item bin: %w", err)
+		}
+	}
+	return nil
+}
@@ -2,57 +2,270 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/utils"
 )
 
+// ErrIdempotencyMismatch is returned when an Idempotency-Key is reused
+// with a different request body. Handlers surface it as 422.
+var ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// ErrAlreadyReversed is returned when reversing a transaction that has
+// already been reversed, or that is itself a reversal. Handlers surface it
+// as 409.
+var ErrAlreadyReversed = errors.New("transaction is already reversed")
+
+// ErrNotPosted is returned when reversing a transaction that never moved
+// stock because it is pending or was rejected. Handlers surface it as 409.
+var ErrNotPosted = errors.New("transaction is not posted")
+
+// idempotencyKeyTTL is how long a key is remembered.
+const idempotencyKeyTTL = 24 * time.Hour
+
 type TransactionService struct {
-	repo *repository.TransactionRepository
+	repo        *repository.TransactionRepository
+	valuation   *ValuationService
+	idempotency *repository.IdempotencyRepository
+	// approvalThreshold is the value, in minor units of any one currency,
+	// above which an adjustment by staff waits for a manager.
+	approvalThreshold int64
 }
 
-func NewTransactionService(repo *repository.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+func NewTransactionService(repo *repository.TransactionRepository, valuation *ValuationService, idempotency *repository.IdempotencyRepository,
+	approvalThreshold int64) *TransactionService {
+	return &TransactionService{repo: repo, valuation: valuation, idempotency: idempotency, approvalThreshold: approvalThreshold}
 }
 
-func (s *TransactionService) Create(ctx context.Context, req *dto.CreateTransactionRequest) error {
+// Create records a transaction and returns it.
+func (s *TransactionService) Create(ctx context.Context, req *dto.CreateTransactionRequest) (*model.Transaction, error) {
+	return s.create(ctx, req, nil)
+}
+
+// CreateIdempotent is Create for a request sent with an Idempotency-Key.
+// The first request with a key is carried out and the response built by
+// respond is stored with the key in the same database transaction. Later
+// requests with the key and the same requestHash get that record back,
+// flagged as replayed, without creating anything; a different requestHash
+// gives ErrIdempotencyMismatch.
+func (s *TransactionService) CreateIdempotent(ctx context.Context, req *dto.CreateTransactionRequest, key, requestHash string,
+	respond func(*model.Transaction) (int, []byte, error)) (*model.IdempotencyRecord, bool, error) {
+	rec, err := s.storedResponse(ctx, req.UserID, key, requestHash)
+	if rec != nil || err != nil {
+		return rec, true, err
+	}
+
+	rec = &model.IdempotencyRecord{UserID: req.UserID, Key: key, RequestHash: requestHash}
+	_, err = s.create(ctx, req, func(tx *sql.Tx, t *model.Transaction) error {
+		status, body, err := respond(t)
+		if err != nil {
+			return err
+		}
+		rec.TransactionID = t.ID
+		rec.ResponseStatus = status
+		rec.ResponseBody = body
+		rec.CreatedAt = t.CreatedAt
+		return s.idempotency.Insert(ctx, tx, rec)
+	})
+	if errors.Is(err, repository.ErrDuplicateKey) {
+		// A concurrent request with the same key committed first; answer
+		// as it was answered.
+		rec, err = s.storedResponse(ctx, req.UserID, key, requestHash)
+		if rec == nil && err == nil {
+			err = fmt.Errorf("idempotency key %q disappeared", key)
+		}
+		return rec, true, err
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return rec, false, nil
+}
+
+func (s *TransactionService) storedResponse(ctx context.Context, userID int64, key, requestHash string) (*model.IdempotencyRecord, error) {
+	rec, err := s.idempotency.Get(ctx, userID, key, time.Now().UTC().Add(-idempotencyKeyTTL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check idempotency key: %w", err)
+	}
+	if rec != nil && rec.RequestHash != requestHash {
+		return nil, ErrIdempotencyMismatch
+	}
+	return rec, nil
+}
+
+// create records a transaction. beforeCommit, if set, runs last inside the
+// database transaction and can veto it.
+func (s *TransactionService) create(ctx context.Context, req *dto.CreateTransactionRequest, beforeCommit func(*sql.Tx, *model.Transaction) error) (*model.Transaction, error) {
 	log.Printf("[TransactionService] Creating transaction for user_id: %d, type: %s", req.UserID, req.TransactionType)
 
+	if err := validateAdjustment(req); err != nil {
+		return nil, err
+	}
+	if err := validateTransfer(req); err != nil {
+		return nil, err
+	}
+	if err := validateBins(req); err != nil {
+		return nil, err
+	}
+
 	tx, err := s.repo.BeginTx(ctx)
 	if err != nil {
 		log.Printf("[TransactionService] Failed to begin transaction: %v", err)
-		return err
+		return nil, err
 	}
 	defer tx.Rollback()
 
+	if err := s.checkWarehouse(ctx, tx, "warehouse_id", req.WarehouseID); err != nil {
+		return nil, err
+	}
+
 	transaction := &model.Transaction{
 		UserID:          req.UserID,
+		WarehouseID:     req.WarehouseID,
 		TransactionType: req.TransactionType,
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+		Status:          model.TransactionPosted,
+		Reason:          req.Reason,
+		ReferenceNumber: strings.TrimSpace(req.ReferenceNumber),
+		Counterparty:    strings.TrimSpace(req.Counterparty),
+		Notes:           strings.TrimSpace(req.Notes),
+	}
+	if req.TransactionType == "TRANSFER" {
+		if err := s.checkWarehouse(ctx, tx, "destination_warehouse_id", req.DestinationWarehouseID); err != nil {
+			return nil, err
+		}
+		transaction.DestinationWarehouseID = &req.DestinationWarehouseID
+		if req.InTransit {
+			transaction.Status = model.TransactionInTransit
+		}
 	}
 	transactionID, err := s.repo.InsertTransaction(ctx, tx, transaction)
+	if errors.Is(err, repository.ErrDuplicateReference) {
+		return nil, NewValidationError("reference_number",
+			fmt.Sprintf("reference number %s is already used by another %s transaction", transaction.ReferenceNumber, transaction.TransactionType))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+	transaction.ID = transactionID
+
+	switch req.TransactionType {
+	case "ADJUST":
+		err = s.recordAdjustment(ctx, tx, transaction, req)
+	case "TRANSFER":
+		err = s.recordTransfer(ctx, tx, transaction, req)
+	default:
+		err = s.recordMovement(ctx, tx, transaction, req)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to insert transaction: %w", err)
+		return nil, err
+	}
+
+	if beforeCommit != nil {
+		if err := beforeCommit(tx, transaction); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
 	}
 
-	for _, item := range req.Items {
+	return transaction, nil
+}
+
+// recordMovement records the items of an IN or OUT transaction t and moves
+// their stock, in total, at t's warehouse and in its bins.
+func (s *TransactionService) recordMovement(ctx context.Context, tx *sql.Tx, t *model.Transaction, req *dto.CreateTransactionRequest) error {
+	for i, item := range req.Items {
+
+		productID, variantID, err := s.resolveItem(ctx, tx, i, item)
+		if err != nil {
+			return err
+		}
+		item.ProductID = productID
+		item.VariantID = variantID
+
+		// Quantities are converted to the product's base unit; the unit the
+		// item was entered in is kept on the transaction item for reference.
+		unitQuantity := item.Quantity
+		factor, err := s.unitFactor(ctx, tx, i, &item)
+		if err != nil {
+			return err
+		}
+		item.Quantity, err = item.Quantity.MulInt(factor)
+		if err != nil {
+			return NewValidationError(fmt.Sprintf("items[%d].quantity", i), "quantity is out of range")
+		}
 
-		stock, err := s.repo.GetProductStockForUpdate(ctx, tx, item.ProductID)
+		product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
 		if err != nil {
 			return fmt.Errorf("product not found or locked: %w", err)
 		}
+		if product.Archived && t.TransactionType == "OUT" {
+			return NewValidationError(fmt.Sprintf("items[%d].product_id", i), fmt.Sprintf("product ID %d is archived", item.ProductID))
+		}
+		if item.Quantity.Places() > product.Precision {
+			return NewValidationError(fmt.Sprintf("items[%d].quantity", i), fmt.Sprintf("product ID %d allows at most %d decimal places", item.ProductID, product.Precision))
+		}
+		stock := product.Stock
+
+		if item.VariantID == 0 {
+			hasVariants, err := s.repo.HasVariants(ctx, tx, item.ProductID)
+			if err != nil {
+				return fmt.Errorf("failed to check product variants: %w", err)
+			}
+			if hasVariants {
+				return NewValidationError(fmt.Sprintf("items[%d].variant_id", i), fmt.Sprintf("product ID %d has variants; variant_id is required", item.ProductID))
+			}
+		} else {
+			variantStock, err := s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID)
+			if errors.Is(err, sql.ErrNoRows) {
+				return NewValidationError(fmt.Sprintf("items[%d].variant_id", i), fmt.Sprintf("variant %d does not belong to product ID %d", item.VariantID, item.ProductID))
+			}
+			if err != nil {
+				return fmt.Errorf("variant not found or locked: %w", err)
+			}
+
+			newVariantStock := variantStock
+			if t.TransactionType == "IN" {
+				newVariantStock = newVariantStock.Add(item.Quantity)
+			} else if t.TransactionType == "OUT" {
+				if item.Quantity.Cmp(variantStock) > 0 {
+
+					return fmt.Errorf("insufficient stock for variant ID %d of product ID %d", item.VariantID, item.ProductID)
+				}
+				newVariantStock = newVariantStock.Sub(item.Quantity)
+			}
+
+			if err := s.repo.UpdateVariantStock(ctx, tx, item.VariantID, newVariantStock); err != nil {
+
+				return fmt.Errorf("failed to update variant stock: %w", err)
+			}
+		}
 
 		newStock := stock
-		if req.TransactionType == "IN" {
-			newStock += item.Quantity
-		} else if req.TransactionType == "OUT" {
-			if item.Quantity > stock {
+		if t.TransactionType == "IN" {
+			newStock = newStock.Add(item.Quantity)
+		} else if t.TransactionType == "OUT" {
+			if item.Quantity.Cmp(stock) > 0 {
 
 				return fmt.Errorf("insufficient stock for product ID %d", item.ProductID)
 			}
-			newStock -= item.Quantity
+			newStock = newStock.Sub(item.Quantity)
 		}
 
 		if err := s.repo.UpdateProductStock(ctx, tx, item.ProductID, newStock); err != nil {
@@ -60,26 +273,488 @@ func (s *TransactionService) Create(ctx context.Context, req *dto.CreateTransact
 			return fmt.Errorf("failed to update stock: %w", err)
 		}
 
+		// Picks come out of bins before the stock leaves the warehouse, so
+		// moving it has no excess to take out of them.
+		var bins []model.BinQuantity
+		delta := item.Quantity
+		if t.TransactionType == "OUT" {
+			delta = delta.Neg()
+			bins, err = s.pickBins(ctx, tx, i, t.WarehouseID, item.ProductID, item.VariantID, item.Quantity, item.BinID)
+			if err != nil {
+				return err
+			}
+		}
+		moved, err := s.moveLocationStock(ctx, tx, t.WarehouseID, item.ProductID, item.VariantID, delta)
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return NewValidationError(fmt.Sprintf("items[%d].quantity", i),
+				fmt.Sprintf("insufficient stock of product ID %d at warehouse %d", item.ProductID, t.WarehouseID))
+		}
+		if t.TransactionType == "IN" {
+			bins, err = s.putAway(ctx, tx, i, t.WarehouseID, item.ProductID, item.VariantID, item.Quantity, item.BinID)
+			if err != nil {
+				return err
+			}
+		}
+
 		itemModel := &model.TransactionItem{
-			TransactionID: transactionID,
+			TransactionID: t.ID,
 			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
 			Quantity:      item.Quantity,
+			StockBefore:   &stock,
+			StockAfter:    &newStock,
+		}
+		if item.Unit != "" {
+			itemModel.Unit = item.Unit
+			itemModel.UnitQuantity = &unitQuantity
+		}
+		baseCost, err := s.applyPricing(ctx, tx, i, t.TransactionType, item, factor, itemModel)
+		if err != nil {
+			return err
 		}
 
-		if err := s.repo.InsertTransactionItem(ctx, tx, itemModel); err != nil {
+		itemID, err := s.repo.InsertTransactionItem(ctx, tx, itemModel)
+		if err != nil {
 
 			return fmt.Errorf("failed to insert transaction item: %w", err)
 		}
+		if err := s.recordItemBins(ctx, tx, itemID, bins); err != nil {
+			return err
+		}
+
+		if t.TransactionType == "IN" {
+			lineCost := unitQuantity.MulAmount(*itemModel.UnitCost)
+			if err := s.valuation.Receive(ctx, tx, item.ProductID, itemID, item.Quantity, lineCost, itemModel.Currency); err != nil {
+				return err
+			}
+		} else if t.TransactionType == "OUT" {
+			cogs, err := s.valuation.Issue(ctx, tx, item.ProductID, itemID, item.Quantity, baseCost, itemModel.Currency)
+			if err != nil {
+				return err
+			}
+			if err := s.repo.SetItemCostOfGoods(ctx, tx, itemID, cogs); err != nil {
+				return fmt.Errorf("failed to record cost of goods: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// unitFactor normalises the unit an item was entered in and returns how
+// many base units one of it holds.
+func (s *TransactionService) unitFactor(ctx context.Context, tx *sql.Tx, index int, item *dto.TransactionItemRequest) (int64, error) {
+	item.Unit = strings.ToLower(strings.TrimSpace(item.Unit))
+	if item.Unit == "" {
+		return 1, nil
+	}
+	factor, err := s.repo.GetUnitFactor(ctx, tx, item.ProductID, item.Unit)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, NewValidationError(fmt.Sprintf("items[%d].unit", index), fmt.Sprintf("unit %q is not configured for product ID %d", item.Unit, item.ProductID))
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get unit conversion: %w", err)
+	}
+	return factor, nil
+}
+
+// Reverse undoes transaction id by recording a compensating transaction,
+// linked to it, with the same items: an OUT for an IN, an IN for an OUT,
+// an opposite ADJUST for an ADJUST and a TRANSFER of what arrived back to
+// its source for a TRANSFER. owner limits it to transactions recorded by
+// that user, as in GetByID; userID records the reversal. Stock taken back
+// must still be on hand at the original's warehouse. Stock returned comes
+// back there at the cost of goods it left with.
+func (s *TransactionService) Reverse(ctx context.Context, id, owner, userID int64) (*model.Transaction, error) {
+	log.Printf("[TransactionService] Reversing transaction %d for user_id: %d", id, userID)
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	original, err := s.repo.GetTransactionForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if original == nil || (owner != 0 && original.UserID != owner) {
+		return nil, ErrNotFound
+	}
+	if original.Reversed || original.ReversesID != nil {
+		return nil, ErrAlreadyReversed
+	}
+	if original.Status != model.TransactionPosted {
+		return nil, ErrNotPosted
+	}
+
+	reversal := &model.Transaction{
+		UserID:          userID,
+		WarehouseID:     original.WarehouseID,
+		TransactionType: original.TransactionType,
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+		Status:          model.TransactionPosted,
+		Reason:          original.Reason,
+		Counterparty:    original.Counterparty,
+		ReversesID:      &original.ID,
+	}
+	switch original.TransactionType {
+	case "IN":
+		reversal.TransactionType = "OUT"
+	case "OUT":
+		reversal.TransactionType = "IN"
+	case "TRANSFER":
+		reversal.WarehouseID = *original.DestinationWarehouseID
+		reversal.DestinationWarehouseID = &original.WarehouseID
+	}
+	// The row lock on the original keeps a concurrent reversal waiting
+	// until this one is committed and visible above.
+	reversal.ID, err = s.repo.InsertTransaction(ctx, tx, reversal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert transaction: %w", err)
+	}
+
+	for i, item := range original.Items {
+		if original.TransactionType == "TRANSFER" {
+			err = s.reverseTransferItem(ctx, tx, i, reversal, item)
+		} else {
+			err = s.reverseItem(ctx, tx, i, reversal, item)
+		}
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return reversal, nil
+}
+
+// reverseItem records the item of reversal that undoes item, moving stock
+// and value back.
+func (s *TransactionService) reverseItem(ctx context.Context, tx *sql.Tx, index int, reversal *model.Transaction, item model.TransactionItem) error {
+	// delta is the change in stock that undoes item; ADJUST quantities
+	// are already signed.
+	delta := item.Quantity.Neg()
+	if reversal.TransactionType == "IN" {
+		delta = item.Quantity
+	}
+	insufficient := NewValidationError(fmt.Sprintf("items[%d].quantity", index),
+		fmt.Sprintf("insufficient stock of product ID %d to reverse this item", item.ProductID))
+
+	product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("product not found or locked: %w", err)
+	}
+	stock := product.Stock
+
+	if item.VariantID != 0 {
+		variantStock, err := s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID)
+		if err != nil {
+			return fmt.Errorf("variant not found or locked: %w", err)
+		}
+		newVariantStock := variantStock.Add(delta)
+		if newVariantStock.IsNegative() {
+			return insufficient
+		}
+		if err := s.repo.UpdateVariantStock(ctx, tx, item.VariantID, newVariantStock); err != nil {
+			return fmt.Errorf("failed to update variant stock: %w", err)
+		}
+	}
+
+	newStock := stock.Add(delta)
+	if newStock.IsNegative() {
+		return insufficient
+	}
+	if err := s.repo.UpdateProductStock(ctx, tx, item.ProductID, newStock); err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	// Stock comes back out of the bins item put it away to, or goes back
+	// into the bins it was picked from.
+	var bins []model.BinQuantity
+	if delta.IsNegative() {
+		bins, err = s.takeFromBins(ctx, tx, reversal.WarehouseID, item.ProductID, item.VariantID, item.Bins)
+		if err != nil {
+			return err
+		}
+	}
+	moved, err := s.moveLocationStock(ctx, tx, reversal.WarehouseID, item.ProductID, item.VariantID, delta)
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return insufficient
+	}
+	if delta.IsPositive() {
+		bins, err = s.returnToBins(ctx, tx, reversal.WarehouseID, item.ProductID, item.VariantID, item.Bins)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, cost, _, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get product pricing: %w", err)
+	}
+
+	itemModel := &model.TransactionItem{
+		TransactionID: reversal.ID,
+		ProductID:     item.ProductID,
+		VariantID:     item.VariantID,
+		Quantity:      item.Quantity,
+		Unit:          item.Unit,
+		UnitQuantity:  item.UnitQuantity,
+		Currency:      item.Currency,
+		StockBefore:   &stock,
+		StockAfter:    &newStock,
+	}
+	quantity := item.Quantity
+	entered := item.Quantity
+	if item.UnitQuantity != nil {
+		entered = *item.UnitQuantity
+	}
+	if reversal.TransactionType == "ADJUST" {
+		itemModel.Quantity = delta
+		if item.UnitQuantity != nil {
+			unitQuantity := item.UnitQuantity.Neg()
+			itemModel.UnitQuantity = &unitQuantity
+		}
+		if quantity.IsNegative() {
+			quantity = quantity.Neg()
+			entered = entered.Neg()
+		}
+	}
+
+	// Goods sent back go out at what they were received for; goods
+	// returned come in at their cost of goods.
+	var lineCost int64
+	switch {
+	case delta.IsNegative() && reversal.TransactionType == "OUT":
+		itemModel.UnitPrice = item.UnitCost
+	case delta.IsNegative():
+		itemModel.UnitCost = item.UnitCost
+	case delta.IsPositive():
+		lineCost = quantity.MulAmount(cost)
+		if item.CostOfGoods != nil {
+			lineCost = *item.CostOfGoods
+		}
+		unitCost := decimal.Prorate(lineCost, decimal.New(1), entered)
+		itemModel.UnitCost = &unitCost
+	}
+
+	itemID, err := s.repo.InsertTransactionItem(ctx, tx, itemModel)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction item: %w", err)
+	}
+	if err := s.recordItemBins(ctx, tx, itemID, bins); err != nil {
+		return err
+	}
+
+	switch {
+	case delta.IsPositive():
+		return s.valuation.Receive(ctx, tx, item.ProductID, itemID, quantity, lineCost, itemModel.Currency)
+	case delta.IsNegative():
+		cogs, err := s.valuation.Return(ctx, tx, item.ProductID, itemID, item.ID, quantity, cost, itemModel.Currency)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SetItemCostOfGoods(ctx, tx, itemID, cogs); err != nil {
+			return fmt.Errorf("failed to record cost of goods: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetByID returns a transaction recorded by the given user, or by anyone
+// when userID is 0. Transactions of other users are reported as
+// ErrNotFound.
+func (s *TransactionService) GetByID(ctx context.Context, id, userID int64) (*model.TransactionWithItems, error) {
+	t, err := s.repo.GetTransactionByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if t == nil || (userID != 0 && t.UserID != userID) {
+		return nil, ErrNotFound
+	}
+	return t, nil
+}
+
+const (
+	defaultTransactionPageSize = 20
+	maxTransactionPageSize     = 100
+)
+
+// transactionTypes are the values accepted for transaction_type.
+var transactionTypes = []string{"IN", "OUT", "ADJUST", "TRANSFER"}
+
+// transactionStatuses are the values accepted for status.
+var transactionStatuses = []string{model.TransactionPosted, model.TransactionPending, model.TransactionRejected, model.TransactionInTransit}
+
+// List returns one page of transactions matching f, newest first. cursor
+// is the NextCursor of the previous page, or empty for the first page.
+func (s *TransactionService) List(ctx context.Context, f model.TransactionFilter, cursor string) (*model.TransactionPage, error) {
+	if err := validateFilter(f); err != nil {
+		return nil, err
+	}
+	switch {
+	case f.Limit == 0:
+		f.Limit = defaultTransactionPageSize
+	case f.Limit < 0 || f.Limit > maxTransactionPageSize:
+		return nil, NewValidationError("limit", fmt.Sprintf("limit must be between 1 and %d", maxTransactionPageSize))
+	}
+	if cursor != "" {
+		beforeID, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, NewValidationError("cursor", "cursor is invalid")
+		}
+		f.BeforeID = beforeID
+	}
+
+	// One extra row tells whether another page follows.
+	pageSize := f.Limit
+	f.Limit++
+	transactions, err := s.repo.ListTransactions(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	page := &model.TransactionPage{Transactions: transactions}
+	if len(transactions) > pageSize {
+		page.Transactions = transactions[:pageSize]
+		page.NextCursor = encodeCursor(page.Transactions[pageSize-1].ID)
+	}
+	return page, nil
+}
+
+// ListWithTotals is List with the totals of every matching transaction
+// added to the page.
+func (s *TransactionService) ListWithTotals(ctx context.Context, f model.TransactionFilter, cursor string) (*model.TransactionPage, error) {
+	page, err := s.List(ctx, f, cursor)
+	if err != nil {
+		return nil, err
+	}
+	page.Totals, err = s.repo.GetTransactionTotals(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction totals: %w", err)
+	}
+	return page, nil
+}
 
-		return fmt.Errorf("failed to commit transaction: %w", err)
+// Export streams every item matching f to fn; see
+// TransactionRepository.ExportTransactionItems.
+func (s *TransactionService) Export(ctx context.Context, f model.TransactionFilter, fn func(*model.TransactionWithItems, *model.TransactionItem) error) error {
+	if err := validateFilter(f); err != nil {
+		return err
 	}
+	return s.repo.ExportTransactionItems(ctx, f, fn)
+}
 
+func validateFilter(f model.TransactionFilter) error {
+	if f.Type != "" && !slices.Contains(transactionTypes, f.Type) {
+		return NewValidationError("type", "type must be one of "+strings.Join(transactionTypes, ", "))
+	}
+	if f.Status != "" && !slices.Contains(transactionStatuses, f.Status) {
+		return NewValidationError("status", "status must be one of "+strings.Join(transactionStatuses, ", "))
+	}
+	if f.From != nil && f.To != nil && f.From.After(*f.To) {
+		return NewValidationError("from", "from must not be after to")
+	}
 	return nil
 }
 
-func (s *TransactionService) GetByUserID(ctx context.Context, userID int64) ([]model.TransactionWithItems, error) {
-	return s.repo.GetTransactionsByUserID(ctx, userID)
+// Cursors are opaque to clients so the ordering key can change later.
+func encodeCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return id, nil
+}
+
+// applyPricing records the unit cost of an IN item or the unit price of an
+// OUT item. Unless given, they are taken from the product's current cost or
+// price per base unit, scaled to the unit the item was entered in. The
+// product's current cost per base unit is returned for valuation.
+func (s *TransactionService) applyPricing(ctx context.Context, tx *sql.Tx, index int, transactionType string, item dto.TransactionItemRequest, factor int64, itemModel *model.TransactionItem) (int64, error) {
+	price, cost, currency, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get product pricing: %w", err)
+	}
+	itemModel.Currency = currency
+
+	switch transactionType {
+	case "IN":
+		if item.UnitPrice != nil {
+			return 0, NewValidationError(fmt.Sprintf("items[%d].unit_price", index), "unit_price only applies to OUT items")
+		}
+		unitCost := cost * factor
+		if item.UnitCost != nil {
+			unitCost = *item.UnitCost
+		}
+		itemModel.UnitCost = &unitCost
+	case "OUT":
+		if item.UnitCost != nil {
+			return 0, NewValidationError(fmt.Sprintf("items[%d].unit_cost", index), "unit_cost only applies to IN items")
+		}
+		unitPrice := price * factor
+		if item.UnitPrice != nil {
+			unitPrice = *item.UnitPrice
+		}
+		itemModel.UnitPrice = &unitPrice
+	}
+	return cost, nil
+}
+
+// resolveItem returns the product and, if any, the variant referenced by an
+// item. Without a product_id the item is looked up by SKU, which may belong
+// to either a variant or a product, or by barcode.
+func (s *TransactionService) resolveItem(ctx context.Context, tx *sql.Tx, index int, item dto.TransactionItemRequest) (int64, int64, error) {
+	if item.ProductID != 0 {
+		return item.ProductID, item.VariantID, nil
+	}
+
+	var (
+		field     string
+		id        int64
+		variantID int64
+		err       error
+	)
+	if item.SKU != "" {
+		field = fmt.Sprintf("items[%d].sku", index)
+		variantID, id, err = s.repo.FindVariantBySKU(ctx, tx, item.SKU)
+		if errors.Is(err, sql.ErrNoRows) {
+			variantID = 0
+			id, err = s.repo.FindProductIDBySKU(ctx, tx, item.SKU)
+		}
+	} else {
+		field = fmt.Sprintf("items[%d].barcode", index)
+		barcode, nErr := utils.NormalizeGTIN(item.Barcode)
+		if nErr != nil {
+			return 0, 0, NewValidationError(field, nErr.Error())
+		}
+		id, err = s.repo.FindProductIDByBarcode(ctx, tx, barcode)
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, NewValidationError(field, "no product matches this identifier")
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to resolve product: %w", err)
+	}
+	if variantID == 0 {
+		variantID = item.VariantID
+	}
+	return id, variantID, nil
 }
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/dto"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+)
+
+// ErrNotInTransit is returned when receiving a transaction that is not a
+// transfer in transit. Handlers surface it as 409.
+var ErrNotInTransit = errors.New("transaction is not a transfer in transit")
+
+// validateTransfer checks the fields that only apply to TRANSFER
+// transactions.
+func validateTransfer(req *dto.CreateTransactionRequest) error {
+	if req.TransactionType != "TRANSFER" {
+		if req.DestinationWarehouseID != 0 {
+			return NewValidationError("destination_warehouse_id", "destination_warehouse_id only applies to TRANSFER transactions")
+		}
+		if req.InTransit {
+			return NewValidationError("in_transit", "in_transit only applies to TRANSFER transactions")
+		}
+		return nil
+	}
+
+	if req.DestinationWarehouseID == 0 {
+		return NewValidationError("destination_warehouse_id", "destination_warehouse_id is required for TRANSFER transactions")
+	}
+	if req.DestinationWarehouseID == req.WarehouseID {
+		return NewValidationError("destination_warehouse_id", "destination_warehouse_id must differ from warehouse_id")
+	}
+	for i, item := range req.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		switch {
+		case item.UnitCost != nil:
+			return NewValidationError(field+".unit_cost", "transfers are valued at the product's cost")
+		case item.UnitPrice != nil:
+			return NewValidationError(field+".unit_price", "unit_price only applies to OUT items")
+		}
+	}
+	return nil
+}
+
+// recordTransfer records the items of a TRANSFER transaction t and takes
+// their stock out of t's warehouse. A posted transfer puts it into the
+// destination straight away; one in transit leaves that to Receive. Totals
+// don't change: stock in transit still counts towards them.
+func (s *TransactionService) recordTransfer(ctx context.Context, tx *sql.Tx, t *model.Transaction, req *dto.CreateTransactionRequest) error {
+	for i, item := range req.Items {
+		productID, variantID, err := s.resolveItem(ctx, tx, i, item)
+		if err != nil {
+			return err
+		}
+		item.ProductID = productID
+		item.VariantID = variantID
+
+		unitQuantity := item.Quantity
+		factor, err := s.unitFactor(ctx, tx, i, &item)
+		if err != nil {
+			return err
+		}
+		item.Quantity, err = item.Quantity.MulInt(factor)
+		if err != nil {
+			return NewValidationError(fmt.Sprintf("items[%d].quantity", i), "quantity is out of range")
+		}
+
+		stock, err := s.lockTransferItem(ctx, tx, fmt.Sprintf("items[%d]", i), item.ProductID, item.VariantID, item.Quantity)
+		if err != nil {
+			return err
+		}
+
+		bins, err := s.pickBins(ctx, tx, i, t.WarehouseID, item.ProductID, item.VariantID, item.Quantity, 0)
+		if err != nil {
+			return err
+		}
+		moved, err := s.moveLocationStock(ctx, tx, t.WarehouseID, item.ProductID, item.VariantID, item.Quantity.Neg())
+		if err != nil {
+			return err
+		}
+		if !moved {
+			return NewValidationError(fmt.Sprintf("items[%d].quantity", i),
+				fmt.Sprintf("insufficient stock of product ID %d at warehouse %d", item.ProductID, t.WarehouseID))
+		}
+
+		_, cost, currency, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to get product pricing: %w", err)
+		}
+		unitCost := cost * factor
+		itemModel := &model.TransactionItem{
+			TransactionID: t.ID,
+			ProductID:     item.ProductID,
+			VariantID:     item.VariantID,
+			Quantity:      item.Quantity,
+			UnitCost:      &unitCost,
+			Currency:      currency,
+			StockBefore:   &stock,
+			StockAfter:    &stock,
+		}
+		if item.Unit != "" {
+			itemModel.Unit = item.Unit
+			itemModel.UnitQuantity = &unitQuantity
+		}
+
+		if t.Status == model.TransactionPosted {
+			if _, err := s.moveLocationStock(ctx, tx, *t.DestinationWarehouseID, item.ProductID, item.VariantID, item.Quantity); err != nil {
+				return err
+			}
+			received := item.Quantity
+			itemModel.ReceivedQuantity = &received
+		}
+
+		itemID, err := s.repo.InsertTransactionItem(ctx, tx, itemModel)
+		if err != nil {
+			return fmt.Errorf("failed to insert transaction item: %w", err)
+		}
+		if err := s.recordItemBins(ctx, tx, itemID, bins); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockTransferItem locks the totals of a transfer item, checking that
+// quantity suits the product and that the variant, if any, belongs to it,
+// and returns the product's total stock. field prefixes validation errors.
+func (s *TransactionService) lockTransferItem(ctx context.Context, tx *sql.Tx, field string, productID, variantID int64, quantity decimal.Decimal) (decimal.Decimal, error) {
+	product, err := s.repo.GetProductForUpdate(ctx, tx, productID)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("product not found or locked: %w", err)
+	}
+	if quantity.Places() > product.Precision {
+		return decimal.Decimal{}, NewValidationError(field+".quantity", fmt.Sprintf("product ID %d allows at most %d decimal places", productID, product.Precision))
+	}
+
+	if variantID == 0 {
+		hasVariants, err := s.repo.HasVariants(ctx, tx, productID)
+		if err != nil {
+			return decimal.Decimal{}, fmt.Errorf("failed to check product variants: %w", err)
+		}
+		if hasVariants {
+			return decimal.Decimal{}, NewValidationError(field+".variant_id", fmt.Sprintf("product ID %d has variants; variant_id is required", productID))
+		}
+		return product.Stock, nil
+	}
+	_, err = s.repo.GetVariantStockForUpdate(ctx, tx, productID, variantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return decimal.Decimal{}, NewValidationError(field+".variant_id", fmt.Sprintf("variant %d does not belong to product ID %d", variantID, productID))
+	}
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("variant not found or locked: %w", err)
+	}
+	return product.Stock, nil
+}
+
+// Receive records, on behalf of userID, the arrival of transfer id, which
+// must be in transit. received gives what arrived of some of its items, in
+// base units; the others arrived as dispatched. What arrived is put into
+// the destination warehouse and the transfer is posted. A shortfall leaves
+// stock and is written off at its cost of goods; a surplus is added to
+// stock at the product's current cost.
+func (s *TransactionService) Receive(ctx context.Context, id, userID int64, received []dto.ReceivedItemRequest) (*model.TransactionWithItems, error) {
+	log.Printf("[TransactionService] Receiving transfer %d for user_id: %d", id, userID)
+
+	tx, err := s.repo.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	t, err := s.repo.GetTransactionForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if t == nil {
+		return nil, ErrNotFound
+	}
+	if t.TransactionType != "TRANSFER" || t.Status != model.TransactionInTransit {
+		return nil, ErrNotInTransit
+	}
+
+	quantities := make(map[int64]decimal.Decimal, len(t.Items))
+	for _, item := range t.Items {
+		quantities[item.ID] = item.Quantity
+	}
+	listed := make(map[int64]bool, len(received))
+	for i, r := range received {
+		field := fmt.Sprintf("items[%d].item_id", i)
+		if _, ok := quantities[r.ItemID]; !ok {
+			return nil, NewValidationError(field, fmt.Sprintf("item %d is not part of transaction %d", r.ItemID, id))
+		}
+		if listed[r.ItemID] {
+			return nil, NewValidationError(field, fmt.Sprintf("item %d is listed more than once", r.ItemID))
+		}
+		listed[r.ItemID] = true
+		quantities[r.ItemID] = r.ReceivedQuantity
+	}
+
+	for i := range t.Items {
+		item := &t.Items[i]
+		if err := s.receiveItem(ctx, tx, fmt.Sprintf("items[%d]", i), t, item, quantities[item.ID]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.MarkReceived(ctx, tx, id, userID, time.Now().UTC().Truncate(time.Second)); err != nil {
+		return nil, fmt.Errorf("failed to update transaction: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetByID(ctx, id, 0)
+}
+
+// receiveItem puts quantity of a transfer item into t's destination and
+// settles any difference from what was dispatched against the totals.
+func (s *TransactionService) receiveItem(ctx context.Context, tx *sql.Tx, field string, t *model.TransactionWithItems, item *model.TransactionItem, quantity decimal.Decimal) error {
+	product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("product not found or locked: %w", err)
+	}
+	if quantity.Places() > product.Precision {
+		return NewValidationError(field+".received_quantity", fmt.Sprintf("product ID %d allows at most %d decimal places", item.ProductID, product.Precision))
+	}
+
+	discrepancy := quantity.Sub(item.Quantity)
+	insufficient := NewValidationError(field+".received_quantity",
+		fmt.Sprintf("stock of product ID %d is below the shortfall of this item", item.ProductID))
+	if item.VariantID != 0 {
+		variantStock, err := s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID)
+		if err != nil {
+			return fmt.Errorf("variant not found or locked: %w", err)
+		}
+		newVariantStock := variantStock.Add(discrepancy)
+		if newVariantStock.IsNegative() {
+			return insufficient
+		}
+		if err := s.repo.UpdateVariantStock(ctx, tx, item.VariantID, newVariantStock); err != nil {
+			return fmt.Errorf("failed to update variant stock: %w", err)
+		}
+	}
+	newStock := product.Stock.Add(discrepancy)
+	if newStock.IsNegative() {
+		return insufficient
+	}
+	if err := s.repo.UpdateProductStock(ctx, tx, item.ProductID, newStock); err != nil {
+		return fmt.Errorf("failed to update stock: %w", err)
+	}
+
+	if _, err := s.moveLocationStock(ctx, tx, *t.DestinationWarehouseID, item.ProductID, item.VariantID, quantity); err != nil {
+		return err
+	}
+
+	item.ReceivedQuantity = &quantity
+	item.StockAfter = &newStock
+	if err := s.repo.UpdateItemReceipt(ctx, tx, item); err != nil {
+		return fmt.Errorf("failed to update transaction item: %w", err)
+	}
+
+	_, cost, _, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("failed to get product pricing: %w", err)
+	}
+	switch {
+	case discrepancy.IsNegative():
+		cogs, err := s.valuation.Issue(ctx, tx, item.ProductID, item.ID, discrepancy.Neg(), cost, item.Currency)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.SetItemCostOfGoods(ctx, tx, item.ID, cogs); err != nil {
+			return fmt.Errorf("failed to record cost of goods: %w", err)
+		}
+	case discrepancy.IsPositive():
+		return s.valuation.Receive(ctx, tx, item.ProductID, item.ID, discrepancy, discrepancy.MulAmount(cost), item.Currency)
+	}
+	return nil
+}
+
+// reverseTransferItem records the item of reversal that moves what arrived
+// of a transfer item back to where it came from.
+func (s *TransactionService) reverseTransferItem(ctx context.Context, tx *sql.Tx, index int, reversal *model.Transaction, item model.TransactionItem) error {
+	quantity := item.Quantity
+	if item.ReceivedQuantity != nil {
+		quantity = *item.ReceivedQuantity
+	}
+
+	product, err := s.repo.GetProductForUpdate(ctx, tx, item.ProductID)
+	if err != nil {
+		return fmt.Errorf("product not found or locked: %w", err)
+	}
+	if item.VariantID != 0 {
+		if _, err := s.repo.GetVariantStockForUpdate(ctx, tx, item.ProductID, item.VariantID); err != nil {
+			return fmt.Errorf("variant not found or locked: %w", err)
+		}
+	}
+
+	moved, err := s.moveLocationStock(ctx, tx, reversal.WarehouseID, item.ProductID, item.VariantID, quantity.Neg())
+	if err != nil {
+		return err
+	}
+	if !moved {
+		return NewValidationError(fmt.Sprintf("items[%d].quantity", index),
+			fmt.Sprintf("insufficient stock of product ID %d at warehouse %d to reverse this item", item.ProductID, reversal.WarehouseID))
+	}
+	if _, err := s.moveLocationStock(ctx, tx, *reversal.DestinationWarehouseID, item.ProductID, item.VariantID, quantity); err != nil {
+		return err
+	}
+	// What comes back goes into the bins it was picked from.
+	picked, _ := planPicks(item.Bins, quantity)
+	bins, err := s.returnToBins(ctx, tx, *reversal.DestinationWarehouseID, item.ProductID, item.VariantID, picked)
+	if err != nil {
+		return err
+	}
+
+	itemModel := &model.TransactionItem{
+		TransactionID:    reversal.ID,
+		ProductID:        item.ProductID,
+		VariantID:        item.VariantID,
+		Quantity:         quantity,
+		Unit:             item.Unit,
+		UnitQuantity:     item.UnitQuantity,
+		UnitCost:         item.UnitCost,
+		Currency:         item.Currency,
+		StockBefore:      &product.Stock,
+		StockAfter:       &product.Stock,
+		ReceivedQuantity: &quantity,
+	}
+	// What arrived no longer matches the unit the item was entered in, so
+	// it goes back in base units at the product's current cost.
+	if quantity.Cmp(item.Quantity) != 0 {
+		_, cost, _, err := s.repo.GetProductPricing(ctx, tx, item.ProductID)
+		if err != nil {
+			return fmt.Errorf("failed to get product pricing: %w", err)
+		}
+		itemModel.Unit = ""
+		itemModel.UnitQuantity = nil
+		itemModel.UnitCost = &cost
+	}
+
+	itemID, err := s.repo.InsertTransactionItem(ctx, tx, itemModel)
+	if err != nil {
+		return fmt.Errorf("failed to insert transaction item: %w", err)
+	}
+	return s.recordItemBins(ctx, tx, itemID, bins)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+)
+
+// checkWarehouse makes sure the warehouse a transaction names in field
+// exists.
+func (s *TransactionService) checkWarehouse(ctx context.Context, tx *sql.Tx, field string, id int64) error {
+	exists, err := s.repo.WarehouseExists(ctx, tx, id)
+	if err != nil {
+		return fmt.Errorf("failed to check warehouse: %w", err)
+	}
+	if !exists {
+		return NewValidationError(field, fmt.Sprintf("warehouse %d does not exist", id))
+	}
+	return nil
+}
+
+// lockLocation locks the stock of a product, and of its variant if any, at
+// a warehouse for the rest of tx and returns both. Movements lock the
+// product's totals first and its location rows and bins after, so
+// concurrent movements wait for each other per product without
+// deadlocking.
+func (s *TransactionService) lockLocation(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64) (decimal.Decimal, decimal.Decimal, error) {
+	stock, err := s.repo.GetLocationStockForUpdate(ctx, tx, warehouseID, productID)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("warehouse stock not found or locked: %w", err)
+	}
+	if variantID == 0 {
+		return stock, decimal.Decimal{}, nil
+	}
+	variantStock, err := s.repo.GetVariantLocationStockForUpdate(ctx, tx, warehouseID, variantID)
+	if err != nil {
+		return decimal.Decimal{}, decimal.Decimal{}, fmt.Errorf("warehouse stock not found or locked: %w", err)
+	}
+	return stock, variantStock, nil
+}
+
+// itemStockAt locks and returns the stock of an item at a warehouse: that
+// of its variant if it has one, otherwise that of its product.
+func (s *TransactionService) itemStockAt(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64) (decimal.Decimal, error) {
+	stock, variantStock, err := s.lockLocation(ctx, tx, warehouseID, productID, variantID)
+	if variantID != 0 {
+		return variantStock, err
+	}
+	return stock, err
+}
+
+// moveLocationStock changes the stock of an item at a warehouse by delta,
+// for its product and, if it has one, its variant. It reports false,
+// changing nothing, if either would drop below zero there. Stock taken out
+// comes out of bins as far as unbinned stock doesn't cover it.
+func (s *TransactionService) moveLocationStock(ctx context.Context, tx *sql.Tx, warehouseID, productID, variantID int64, delta decimal.Decimal) (bool, error) {
+	stock, variantStock, err := s.lockLocation(ctx, tx, warehouseID, productID, variantID)
+	if err != nil {
+		return false, err
+	}
+	newStock := stock.Add(delta)
+	newVariantStock := variantStock.Add(delta)
+	if newStock.IsNegative() || (variantID != 0 && newVariantStock.IsNegative()) {
+		return false, nil
+	}
+
+	if err := s.repo.UpdateLocationStock(ctx, tx, warehouseID, productID, newStock); err != nil {
+		return false, fmt.Errorf("failed to update warehouse stock: %w", err)
+	}
+	if variantID != 0 {
+		if err := s.repo.UpdateVariantLocationStock(ctx, tx, warehouseID, variantID, newVariantStock); err != nil {
+			return false, fmt.Errorf("failed to update warehouse stock: %w", err)
+		}
+	}
+
+	if delta.IsNegative() {
+		itemStock := newStock
+		if variantID != 0 {
+			itemStock = newVariantStock
+		}
+		if err := s.fitBins(ctx, tx, warehouseID, productID, variantID, itemStock); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
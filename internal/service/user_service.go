@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 
+	"golang.org/x/crypto/bcrypt"
+
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
 )
@@ -49,3 +51,38 @@ func (s *UserService) Update(ctx context.Context, consumer *model.Users) error {
 
 	return nil
 }
+
+// Patch saves a user after a merge patch, writing only the fields that
+// changed. A non-empty Password is a new plain-text password and is hashed.
+func (s *UserService) Patch(ctx context.Context, user *model.Users) error {
+	existing, err := s.Repo.GetByIDUser(ctx, user.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if existing == nil {
+		return ErrNotFound
+	}
+
+	var fields []string
+	add := func(name string, changed bool) {
+		if changed {
+			fields = append(fields, name)
+		}
+	}
+	add("first_name", user.FirstName != existing.FirstName)
+	add("last_name", user.LastName != existing.LastName)
+	add("email", user.Email != existing.Email)
+	add("date_of_birth", !user.DateOfBirth.Equal(existing.DateOfBirth))
+	add("gender", user.Gender != existing.Gender)
+
+	if user.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.Password = string(hashed)
+		fields = append(fields, "password")
+	}
+
+	return s.Repo.PatchUser(ctx, user, fields)
+}
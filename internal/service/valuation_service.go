@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+const (
+	ValuationFIFO    = "fifo"
+	ValuationAverage = "average"
+)
+
+// ValuationService keeps FIFO cost layers and a moving-average value per
+// product in step with stock movements. Both are always maintained so the
+// configured method only decides which one is reported as cost of goods.
+type ValuationService struct {
+	repo   *repository.ValuationRepository
+	method string
+}
+
+func NewValuationService(repo *repository.ValuationRepository, method string) *ValuationService {
+	return &ValuationService{repo: repo, method: method}
+}
+
+func (s *ValuationService) Method() string {
+	return s.method
+}
+
+// Receive adds stock received by an IN item at the given total cost in
+// currency.
+func (s *ValuationService) Receive(ctx context.Context, tx *sql.Tx, productID, itemID int64, quantity decimal.Decimal, cost int64, currency string) error {
+	last, err := s.repo.GetLatestEntry(ctx, tx, productID)
+	if err != nil {
+		return err
+	}
+
+	layer := &model.CostLayer{
+		ProductID:         productID,
+		TransactionItemID: itemID,
+		Quantity:          quantity,
+		RemainingQuantity: quantity,
+		TotalCost:         cost,
+		RemainingCost:     cost,
+		Currency:          currency,
+	}
+	if err := s.repo.InsertLayer(ctx, tx, layer); err != nil {
+		return err
+	}
+
+	return s.repo.InsertEntry(ctx, tx, &model.StockLedgerEntry{
+		ProductID:         productID,
+		TransactionItemID: itemID,
+		QuantityChange:    quantity,
+		QuantityAfter:     last.QuantityAfter.Add(quantity),
+		FIFOValueAfter:    last.FIFOValueAfter + cost,
+		AverageValueAfter: last.AverageValueAfter + cost,
+		Currency:          currency,
+	})
+}
+
+// Issue removes stock taken by an OUT item and returns its cost of goods
+// in currency under the configured method. Quantity beyond what the ledger
+// has valued is costed at fallbackUnitCost per base unit.
+func (s *ValuationService) Issue(ctx context.Context, tx *sql.Tx, productID, itemID int64, quantity decimal.Decimal, fallbackUnitCost int64, currency string) (int64, error) {
+	return s.issue(ctx, tx, productID, itemID, 0, quantity, fallbackUnitCost, currency)
+}
+
+// Return removes stock sent back from the receipt of IN item
+// receiptItemID, as when that receipt is reversed. Under FIFO the receipt's
+// own layer is used up first so its cost leaves with it; otherwise it works
+// like Issue.
+func (s *ValuationService) Return(ctx context.Context, tx *sql.Tx, productID, itemID, receiptItemID int64, quantity decimal.Decimal, fallbackUnitCost int64, currency string) (int64, error) {
+	return s.issue(ctx, tx, productID, itemID, receiptItemID, quantity, fallbackUnitCost, currency)
+}
+
+func (s *ValuationService) issue(ctx context.Context, tx *sql.Tx, productID, itemID, firstItemID int64, quantity decimal.Decimal, fallbackUnitCost int64, currency string) (int64, error) {
+	last, err := s.repo.GetLatestEntry(ctx, tx, productID)
+	if err != nil {
+		return 0, err
+	}
+
+	covered := quantity
+	if covered.Cmp(last.QuantityAfter) > 0 {
+		covered = last.QuantityAfter
+	}
+	if covered.IsNegative() {
+		covered = decimal.Decimal{}
+	}
+	uncoveredCost := quantity.Sub(covered).MulAmount(fallbackUnitCost)
+
+	fifoCost, shortfall, err := s.repo.ConsumeLayers(ctx, tx, productID, firstItemID, covered)
+	if err != nil {
+		return 0, err
+	}
+	fifoCOGS := fifoCost + shortfall.MulAmount(fallbackUnitCost) + uncoveredCost
+
+	averageCost := last.AverageValueAfter
+	if covered.Cmp(last.QuantityAfter) < 0 {
+		averageCost = decimal.Prorate(last.AverageValueAfter, covered, last.QuantityAfter)
+	}
+	averageCOGS := averageCost + uncoveredCost
+
+	err = s.repo.InsertEntry(ctx, tx, &model.StockLedgerEntry{
+		ProductID:         productID,
+		TransactionItemID: itemID,
+		QuantityChange:    quantity.Neg(),
+		QuantityAfter:     last.QuantityAfter.Sub(covered),
+		FIFOValueAfter:    last.FIFOValueAfter - fifoCost,
+		AverageValueAfter: last.AverageValueAfter - averageCost,
+		Currency:          currency,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if s.method == ValuationAverage {
+		return averageCOGS, nil
+	}
+	return fifoCOGS, nil
+}
+
+// Report values stock on hand as of the given time. An empty method uses
+// the configured one.
+func (s *ValuationService) Report(ctx context.Context, asOf time.Time, method string) (*model.ValuationReport, error) {
+	if method == "" {
+		method = s.method
+	}
+	if method != ValuationFIFO && method != ValuationAverage {
+		return nil, NewValidationError("method", fmt.Sprintf("method must be %s or %s", ValuationFIFO, ValuationAverage))
+	}
+
+	lines, err := s.repo.GetValuation(ctx, asOf, method)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ValuationReport{
+		AsOf:   asOf,
+		Method: method,
+		Items:  []model.ValuationLine{},
+		Totals: map[string]int64{},
+	}
+	for _, line := range lines {
+		if line.Quantity.IsZero() && line.Value == 0 {
+			continue
+		}
+		report.Items = append(report.Items, line)
+		report.Totals[line.Currency] += line.Value
+	}
+	return report, nil
+}
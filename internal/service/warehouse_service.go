@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
+)
+
+type WarehouseService struct {
+	repo *repository.WarehouseRepository
+}
+
+func NewWarehouseService(repo *repository.WarehouseRepository) *WarehouseService {
+	return &WarehouseService{repo: repo}
+}
+
+// Create adds a warehouse. Making it the default takes that role from the
+// current default.
+func (s *WarehouseService) Create(ctx context.Context, w *model.Warehouse) (*model.Warehouse, error) {
+	if err := s.validate(ctx, w); err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.Insert(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *WarehouseService) List(ctx context.Context) ([]*model.Warehouse, error) {
+	return s.repo.GetAll(ctx)
+}
+
+func (s *WarehouseService) GetByID(ctx context.Context, id int64) (*model.Warehouse, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get warehouse: %w", err)
+	}
+	if w == nil {
+		return nil, ErrNotFound
+	}
+	return w, nil
+}
+
+// Update saves a warehouse. There is always a default warehouse, so the
+// default can only be moved by making another warehouse the default.
+func (s *WarehouseService) Update(ctx context.Context, w *model.Warehouse) (*model.Warehouse, error) {
+	existing, err := s.GetByID(ctx, w.ID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.IsDefault && !w.IsDefault {
+		return nil, NewValidationError("is_default", "make another warehouse the default instead")
+	}
+	if err := s.validate(ctx, w); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, w); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, w.ID)
+}
+
+func (s *WarehouseService) validate(ctx context.Context, w *model.Warehouse) error {
+	w.Code = strings.ToUpper(strings.TrimSpace(w.Code))
+	w.Name = strings.TrimSpace(w.Name)
+	w.Address = strings.TrimSpace(w.Address)
+	if w.Code == "" {
+		return NewValidationError("code", "code is required")
+	}
+	if w.Name == "" {
+		return NewValidationError("name", "name is required")
+	}
+
+	existing, err := s.repo.GetByCode(ctx, w.Code)
+	if err != nil {
+		return fmt.Errorf("failed to check code: %w", err)
+	}
+	if existing != nil && existing.ID != w.ID {
+		return NewValidationError("code", fmt.Sprintf("code %s is already used by warehouse %d", w.Code, existing.ID))
+	}
+	return nil
+}
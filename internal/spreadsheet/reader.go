@@ -0,0 +1,103 @@
+// Package spreadsheet reads and writes tabular files such as product
+// catalogs one row at a time, so large files never need to fit in memory.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Reader returns the rows of a sheet in order, skipping empty ones. Read
+// returns io.EOF after the last row. Row reports the 1-based row number of
+// the last row read as the user sees it in the file.
+type Reader interface {
+	Read() ([]string, error)
+	Row() int
+}
+
+// FormatFromName guesses the format of an uploaded file from its extension.
+func FormatFromName(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".csv", ".txt":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	}
+	return ""
+}
+
+// NewReader opens the first sheet of src in the given format.
+func NewReader(format string, src io.ReaderAt, size int64) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVReader(io.NewSectionReader(src, 0, size)), nil
+	case FormatXLSX:
+		return NewXLSXReader(src, size)
+	}
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
+type csvReader struct {
+	r *csv.Reader
+}
+
+// NewCSVReader reads comma-separated rows. A UTF-8 byte order mark, as
+// written by spreadsheet programs, is skipped and rows may have differing
+// numbers of columns.
+func NewCSVReader(r io.Reader) Reader {
+	cr := csv.NewReader(&bomSkipper{r: r})
+	cr.FieldsPerRecord = -1
+	return &csvReader{r: cr}
+}
+
+func (c *csvReader) Read() ([]string, error) {
+	for {
+		record, err := c.r.Read()
+		if err != nil || !isBlank(record) {
+			return record, err
+		}
+	}
+}
+
+func (c *csvReader) Row() int {
+	line, _ := c.r.FieldPos(0)
+	return line
+}
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+type bomSkipper struct {
+	r       io.Reader
+	checked bool
+}
+
+func (b *bomSkipper) Read(p []byte) (int, error) {
+	if b.checked {
+		return b.r.Read(p)
+	}
+	b.checked = true
+
+	head := make([]byte, len(utf8BOM))
+	n, err := io.ReadFull(b.r, head)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	head = head[:n]
+	if bytes.Equal(head, utf8BOM) {
+		head = nil
+	}
+	b.r = io.MultiReader(bytes.NewReader(head), b.r)
+	return b.r.Read(p)
+}
@@ -0,0 +1,306 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidXLSX is returned when a file is not a readable XLSX workbook.
+var ErrInvalidXLSX = errors.New("invalid xlsx file")
+
+const (
+	maxSharedStrings = 1 << 20
+	// maxColumns is the column limit of Excel, column XFD.
+	maxColumns = 16384
+)
+
+type xlsxReader struct {
+	sheet   io.ReadCloser
+	dec     *xml.Decoder
+	strings []string
+	row     int
+}
+
+// NewXLSXReader streams the first worksheet of an XLSX workbook. Only the
+// shared string table is held in memory; cells are returned as the text
+// stored in the file, so numbers keep their raw form such as "12.5".
+func NewXLSXReader(src io.ReaderAt, size int64) (Reader, error) {
+	zr, err := zip.NewReader(src, size)
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	sheetPath, err := firstSheetPath(files)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := readSharedStrings(files["xl/sharedStrings.xml"])
+	if err != nil {
+		return nil, err
+	}
+
+	sheetFile, ok := files[sheetPath]
+	if !ok {
+		return nil, ErrInvalidXLSX
+	}
+	sheet, err := sheetFile.Open()
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	return &xlsxReader{sheet: sheet, dec: xml.NewDecoder(sheet), strings: shared}, nil
+}
+
+func (x *xlsxReader) Row() int {
+	return x.row
+}
+
+func (x *xlsxReader) Read() ([]string, error) {
+	for {
+		tok, err := x.dec.Token()
+		if err == io.EOF {
+			x.sheet.Close()
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+		}
+
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		if n, err := strconv.Atoi(attr(start, "r")); err == nil {
+			x.row = n
+		} else {
+			x.row++
+		}
+
+		cells, err := x.readRow()
+		if err != nil {
+			return nil, err
+		}
+		if !isBlank(cells) {
+			return cells, nil
+		}
+	}
+}
+
+// readRow collects the cells of the row element just opened. Cells carry
+// their own column reference, so skipped columns come back as "".
+func (x *xlsxReader) readRow() ([]string, error) {
+	var cells []string
+	for {
+		tok, err := x.dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			col := columnIndex(attr(t, "r"))
+			if col < 0 {
+				col = len(cells)
+			}
+			if col >= maxColumns {
+				return nil, fmt.Errorf("%w: column out of range", ErrInvalidXLSX)
+			}
+			value, err := x.readCell(t)
+			if err != nil {
+				return nil, err
+			}
+			for len(cells) <= col {
+				cells = append(cells, "")
+			}
+			cells[col] = value
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return cells, nil
+			}
+		}
+	}
+}
+
+func (x *xlsxReader) readCell(start xml.StartElement) (string, error) {
+	var cell struct {
+		V  string `xml:"v"`
+		IS struct {
+			T string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		} `xml:"is"`
+	}
+	if err := x.dec.DecodeElement(&cell, &start); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+
+	switch attr(start, "t") {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(cell.V))
+		if err != nil || i < 0 || i >= len(x.strings) {
+			return "", fmt.Errorf("%w: bad shared string index %q", ErrInvalidXLSX, cell.V)
+		}
+		return x.strings[i], nil
+	case "inlineStr":
+		text := cell.IS.T
+		for _, run := range cell.IS.R {
+			text += run.T
+		}
+		return text, nil
+	case "b":
+		if cell.V == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	}
+	return cell.V, nil
+}
+
+// firstSheetPath resolves the first sheet listed in the workbook to its
+// part name through the workbook relationships.
+func firstSheetPath(files map[string]*zip.File) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files["xl/workbook.xml"], &workbook); err != nil {
+		return "", err
+	}
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("%w: workbook has no sheets", ErrInvalidXLSX)
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "", fmt.Errorf("%w: first sheet not found", ErrInvalidXLSX)
+}
+
+// readSharedStrings loads the shared string table, joining rich text runs
+// and leaving out phonetic hints. A workbook without one is valid.
+func readSharedStrings(f *zip.File) ([]string, error) {
+	if f == nil {
+		return nil, nil
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, ErrInvalidXLSX
+	}
+	defer rc.Close()
+
+	var (
+		shared []string
+		dec    = xml.NewDecoder(rc)
+	)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "si" {
+			continue
+		}
+
+		var si struct {
+			T string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		}
+		if err := dec.DecodeElement(&si, &start); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+		}
+		text := si.T
+		for _, run := range si.R {
+			text += run.T
+		}
+		if len(shared) >= maxSharedStrings {
+			return nil, fmt.Errorf("%w: too many shared strings", ErrInvalidXLSX)
+		}
+		shared = append(shared, text)
+	}
+}
+
+func decodePart(f *zip.File, v any) error {
+	if f == nil {
+		return ErrInvalidXLSX
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return ErrInvalidXLSX
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidXLSX, err)
+	}
+	return nil
+}
+
+// columnIndex turns the letters of a cell reference such as "AB12" into a
+// 0-based column index, or -1 if there are none.
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' || n == 3 {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+func attr(el xml.StartElement, name string) string {
+	for _, a := range el.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func isBlank(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// buildWorkbook zips a workbook whose first sheet is at xl/worksheets/data.xml,
+// so the reader has to follow the relationships to find it.
+func buildWorkbook(t *testing.T, sharedStrings, sheetData string) *bytes.Reader {
+	t.Helper()
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"`
+	parts := map[string]string{
+		"xl/workbook.xml": `<workbook ` + ns + ` xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId7"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+			`<Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/data.xml"/>` +
+			`</Relationships>`,
+		"xl/worksheets/data.xml": `<worksheet ` + ns + `><sheetData>` + sheetData + `</sheetData></worksheet>`,
+	}
+	if sharedStrings != "" {
+		parts["xl/sharedStrings.xml"] = `<sst ` + ns + `>` + sharedStrings + `</sst>`
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(f, xml.Header+body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+type readRow struct {
+	row   int
+	cells []string
+}
+
+func readAll(t *testing.T, r Reader) []readRow {
+	t.Helper()
+	var rows []readRow
+	for {
+		cells, err := r.Read()
+		if err == io.EOF {
+			return rows
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows = append(rows, readRow{r.Row(), cells})
+	}
+}
+
+func TestXLSXReader(t *testing.T) {
+	shared := `<si><t>sku</t></si>` +
+		`<si><t>name</t></si>` +
+		`<si><r><t>Kopi </t></r><r><rPr><b/></rPr><t>Arabika</t></r><rPh><t>ignored</t></rPh></si>` +
+		`<si><t xml:space="preserve"> padded </t></si>`
+	sheet := `<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="inlineStr"><is><t>stock</t></is></c></row>` +
+		// Row 2 is blank and row 3 holds only whitespace; both are skipped.
+		`<row r="3"><c r="A3" t="inlineStr"><is><t> </t></is></c></row>` +
+		// Rows 4 and 5 are missing; cells leave gaps and a rich inline string.
+		`<row r="6"><c r="A6" t="s"><v>2</v></c><c r="C6"><v>12.5</v></c><c r="E6" t="inlineStr"><is><r><t>a&amp;</t></r><r><t>b</t></r></is></c></row>` +
+		// Without r attributes rows and cells follow on from the previous ones.
+		`<row><c t="b"><v>1</v></c><c t="b"><v>0</v></c><c t="s"><v>3</v></c><c><v>-3</v></c></row>` +
+		`<row r="30"><c r="AB30"><v>1E-3</v></c></row>`
+
+	src := buildWorkbook(t, shared, sheet)
+	r, err := NewXLSXReader(src, src.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sparse := make([]string, 28)
+	sparse[27] = "1E-3"
+	want := []readRow{
+		{1, []string{"sku", "name", "stock"}},
+		{6, []string{"Kopi Arabika", "", "12.5", "", "a&b"}},
+		{7, []string{"TRUE", "FALSE", " padded ", "-3"}},
+		{30, sparse},
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q\nwant %q", got, want)
+	}
+}
+
+func TestXLSXReaderRejectsBadFiles(t *testing.T) {
+	tests := []struct {
+		name          string
+		shared, sheet string
+	}{
+		{"shared string index out of range", `<si><t>only</t></si>`, `<row r="1"><c r="A1" t="s"><v>1</v></c></row>`},
+		{"shared string without a table", ``, `<row r="1"><c r="A1" t="s"><v>0</v></c></row>`},
+		{"column past XFD", ``, `<row r="1"><c r="XFE1"><v>1</v></c></row>`},
+		{"truncated sheet", ``, `<row r="1"><c r="A1"><v>1</v>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := buildWorkbook(t, tt.shared, tt.sheet)
+			r, err := NewXLSXReader(src, src.Size())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Read(); !errors.Is(err, ErrInvalidXLSX) {
+				t.Errorf("Read err = %v, want ErrInvalidXLSX", err)
+			}
+		})
+	}
+
+	if _, err := NewXLSXReader(bytes.NewReader([]byte("sku,name\n")), 9); !errors.Is(err, ErrInvalidXLSX) {
+		t.Errorf("NewXLSXReader of a CSV err = %v, want ErrInvalidXLSX", err)
+	}
+}
+
+func TestXLSXRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"sku", "name", "stock", "price"},
+		{"KOPI-01", "Kopi <Arabika> & \"Robusta\"", "12.5", "45000"},
+		{"TEH-01", "  spaced  ", "", "-3"},
+		{"GULA-01", "", "1e3", "0.25"},
+		{"", "", "", ""},
+		{"ES-01", "line\nbreak", "7", "x"},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf, 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Numeric columns hold plain decimals as number cells and anything else,
+	// such as "1e3" or "x", as text.
+	sheet := readPart(t, buf.Bytes(), "xl/worksheets/sheet1.xml")
+	for _, cell := range []string{`<c r="C2"><v>12.5</v></c>`, `<c r="D3"><v>-3</v></c>`, `<c r="C4" t="inlineStr">`, `<c r="D6" t="inlineStr">`} {
+		if !bytes.Contains(sheet, []byte(cell)) {
+			t.Errorf("sheet has no %s", cell)
+		}
+	}
+
+	r, err := NewReader(FormatXLSX, bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []readRow{
+		{1, rows[0]},
+		{2, rows[1]},
+		{3, rows[2]},
+		{4, []string{"GULA-01", "", "1e3", "0.25"}},
+		{6, rows[5]},
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q\nwant %q", got, want)
+	}
+}
+
+func readPart(t *testing.T, workbook []byte, name string) []byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestColumnNames(t *testing.T) {
+	tests := []struct {
+		col  int
+		name string
+	}{
+		{0, "A"}, {25, "Z"}, {26, "AA"}, {27, "AB"}, {701, "ZZ"}, {702, "AAA"}, {16383, "XFD"},
+	}
+	for _, tt := range tests {
+		if got := columnName(tt.col); got != tt.name {
+			t.Errorf("columnName(%d) = %q, want %q", tt.col, got, tt.name)
+		}
+		if got := columnIndex(tt.name + "12"); got != tt.col {
+			t.Errorf("columnIndex(%q) = %d, want %d", tt.name+"12", got, tt.col)
+		}
+	}
+	if got := columnIndex("12"); got != -1 {
+		t.Errorf("columnIndex without letters = %d, want -1", got)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	src := "\xef\xbb\xbfsku,name\n\n,\nKOPI-01,\"Kopi, Arabika\",extra\n"
+	r, err := NewReader(FormatCSV, bytes.NewReader([]byte(src)), int64(len(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []readRow{
+		{1, []string{"sku", "name"}},
+		{4, []string{"KOPI-01", "Kopi, Arabika", "extra"}},
+	}
+	if got := readAll(t, r); !reflect.DeepEqual(got, want) {
+		t.Errorf("rows = %q\nwant %q", got, want)
+	}
+}
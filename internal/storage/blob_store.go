@@ -0,0 +1,37 @@
+// Package storage keeps uploaded files such as product images outside the
+// database, behind a small interface with local and S3-compatible backends.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// ErrNotExist is returned by Open when no blob is stored under the key.
+var ErrNotExist = errors.New("blob does not exist")
+
+// ErrInvalidKey is returned for keys that are empty, absolute or contain
+// empty, "." or ".." segments.
+var ErrInvalidKey = errors.New("invalid blob key")
+
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL returns where clients can download the blob.
+	URL(key string) string
+}
+
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
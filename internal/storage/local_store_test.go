@@ -0,0 +1,118 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"products/1/a.jpg", true},
+		{"a", true},
+		{"a/.hidden", true},
+		{"a/..b", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"..", false},
+		{"../a", false},
+		{"a/../../b", false},
+		{"a/./b", false},
+		{"a//b", false},
+		{"a/", false},
+	}
+	for _, tt := range tests {
+		if got := validKey(tt.key); got != tt.want {
+			t.Errorf("validKey(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestLocalStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Put(ctx, "products/1/a.txt", "text/plain", []byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := s.Open(ctx, "products/1/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(data) != "hello" {
+		t.Errorf("Open read %q", data)
+	}
+	if got := s.URL("products/1/a.txt"); got != "/media/products/1/a.txt" {
+		t.Errorf("URL = %q", got)
+	}
+
+	if err := s.Delete(ctx, "products/1/a.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Open(ctx, "products/1/a.txt"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("Open after Delete err = %v, want ErrNotExist", err)
+	}
+	if err := s.Delete(ctx, "products/1/a.txt"); err != nil {
+		t.Errorf("Delete of a missing blob err = %v", err)
+	}
+}
+
+func TestLocalStoreDirectoriesAreNotBlobs(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(ctx, "transactions/1/a.pdf", "application/pdf", []byte("%PDF")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"transactions", "transactions/1"} {
+		if _, err := s.Open(ctx, key); !errors.Is(err, ErrNotExist) {
+			t.Errorf("Open(%q) err = %v, want ErrNotExist", key, err)
+		}
+	}
+}
+
+func TestLocalStoreRejectsPathTraversal(t *testing.T) {
+	ctx := context.Background()
+	parent := t.TempDir()
+	root := filepath.Join(parent, "uploads")
+	s, err := NewLocalStore(root, "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(parent, "secret"), []byte("secret"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"../secret", "a/../../secret", "/secret", "../escaped", ""} {
+		if err := s.Put(ctx, key, "text/plain", []byte("x")); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Put(%q) err = %v, want ErrInvalidKey", key, err)
+		}
+		if _, err := s.Open(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Open(%q) err = %v, want ErrInvalidKey", key, err)
+		}
+		if err := s.Delete(ctx, key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Delete(%q) err = %v, want ErrInvalidKey", key, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(parent, "escaped")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Put wrote outside the root: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(parent, "secret")); err != nil || string(data) != "secret" {
+		t.Errorf("file outside the root changed: %q, %v", data, err)
+	}
+}
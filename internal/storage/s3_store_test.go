@@ -0,0 +1,308 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// The examples of the Amazon S3 API reference, "Signature Calculations for
+// the Authorization Header: Transferring Payload in a Single Chunk".
+const (
+	exampleSecretKey = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+	exampleHost      = "examplebucket.s3.amazonaws.com"
+	exampleDate      = "20130524T000000Z"
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func TestSignV4(t *testing.T) {
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		query         string
+		headers       map[string]string
+		payloadHash   string
+		canonical     string
+		stringToSign  string
+		signature     string
+		signedHeaders string
+	}{
+		{
+			name:   "GET object",
+			method: "GET",
+			path:   "/test.txt",
+			headers: map[string]string{
+				"host":                 exampleHost,
+				"range":                "bytes=0-9",
+				"x-amz-content-sha256": emptyPayloadHash,
+				"x-amz-date":           exampleDate,
+			},
+			payloadHash: emptyPayloadHash,
+			canonical: "GET\n/test.txt\n\n" +
+				"host:examplebucket.s3.amazonaws.com\n" +
+				"range:bytes=0-9\n" +
+				"x-amz-content-sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n" +
+				"x-amz-date:20130524T000000Z\n\n" +
+				"host;range;x-amz-content-sha256;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign: "AWS4-HMAC-SHA256\n20130524T000000Z\n20130524/us-east-1/s3/aws4_request\n" +
+				"7344ae5b7ee6c3e7e6b0fe0640412a37625d1fbfff95c48bbb2dc43964946972",
+			signature:     "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41",
+			signedHeaders: "host;range;x-amz-content-sha256;x-amz-date",
+		},
+		{
+			name:   "PUT object",
+			method: "PUT",
+			path:   uriEncode("/test$file.text"),
+			headers: map[string]string{
+				"date":                 "Fri, 24 May 2013 00:00:00 GMT",
+				"host":                 exampleHost,
+				"x-amz-content-sha256": sha256Hex([]byte("Welcome to Amazon S3.")),
+				"x-amz-date":           exampleDate,
+				"x-amz-storage-class":  "REDUCED_REDUNDANCY",
+			},
+			payloadHash: sha256Hex([]byte("Welcome to Amazon S3.")),
+			canonical: "PUT\n/test%24file.text\n\n" +
+				"date:Fri, 24 May 2013 00:00:00 GMT\n" +
+				"host:examplebucket.s3.amazonaws.com\n" +
+				"x-amz-content-sha256:44ce7dd67c959e0d3524ffac1771dfbba87d2b6b4b4e99e42034a8b803f8b072\n" +
+				"x-amz-date:20130524T000000Z\n" +
+				"x-amz-storage-class:REDUCED_REDUNDANCY\n\n" +
+				"date;host;x-amz-content-sha256;x-amz-date;x-amz-storage-class\n" +
+				"44ce7dd67c959e0d3524ffac1771dfbba87d2b6b4b4e99e42034a8b803f8b072",
+			stringToSign: "AWS4-HMAC-SHA256\n20130524T000000Z\n20130524/us-east-1/s3/aws4_request\n" +
+				"9e0e90d9c76de8fa5b200d8c849cd5b8dc7a3be3951ddb7f6a76b4158342019d",
+			signature:     "98ad721746da40c64f1a55b78f14c238d841ea1380cd77a1b5971af0ece108bd",
+			signedHeaders: "date;host;x-amz-content-sha256;x-amz-date;x-amz-storage-class",
+		},
+		{
+			name:   "GET bucket lifecycle",
+			method: "GET",
+			path:   "/",
+			query:  "lifecycle=",
+			headers: map[string]string{
+				"host":                 exampleHost,
+				"x-amz-content-sha256": emptyPayloadHash,
+				"x-amz-date":           exampleDate,
+			},
+			payloadHash: emptyPayloadHash,
+			canonical: "GET\n/\nlifecycle=\n" +
+				"host:examplebucket.s3.amazonaws.com\n" +
+				"x-amz-content-sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n" +
+				"x-amz-date:20130524T000000Z\n\n" +
+				"host;x-amz-content-sha256;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign: "AWS4-HMAC-SHA256\n20130524T000000Z\n20130524/us-east-1/s3/aws4_request\n" +
+				"9766c798316ff2757b517bc739a67f6213b4ab36dd5da2f94eaebf79c77395ca",
+			signature:     "fea454ca298b7da1c68078a5d1bdbfbbe0d65c699e0f91ac7a200a0136783543",
+			signedHeaders: "host;x-amz-content-sha256;x-amz-date",
+		},
+		{
+			name:   "GET bucket list objects",
+			method: "GET",
+			path:   "/",
+			query:  "max-keys=2&prefix=J",
+			headers: map[string]string{
+				"host":                 exampleHost,
+				"x-amz-content-sha256": emptyPayloadHash,
+				"x-amz-date":           exampleDate,
+			},
+			payloadHash: emptyPayloadHash,
+			canonical: "GET\n/\nmax-keys=2&prefix=J\n" +
+				"host:examplebucket.s3.amazonaws.com\n" +
+				"x-amz-content-sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855\n" +
+				"x-amz-date:20130524T000000Z\n\n" +
+				"host;x-amz-content-sha256;x-amz-date\n" +
+				"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+			stringToSign: "AWS4-HMAC-SHA256\n20130524T000000Z\n20130524/us-east-1/s3/aws4_request\n" +
+				"df57d21db20da04d7fa30298dd4488ba3a2b47ca3a489c74750e0f1e7df1b9b7",
+			signature:     "34b48302e7b5fa45bde8084f4b7868a86f0a534bc59db6670ed5711ef69dc6f7",
+			signedHeaders: "host;x-amz-content-sha256;x-amz-date",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canonical, signedHeaders := canonicalRequest(tt.method, tt.path, tt.query, tt.headers, tt.payloadHash)
+			if canonical != tt.canonical {
+				t.Errorf("canonical request =\n%s\nwant\n%s", canonical, tt.canonical)
+			}
+			if signedHeaders != tt.signedHeaders {
+				t.Errorf("signed headers = %q, want %q", signedHeaders, tt.signedHeaders)
+			}
+
+			scope, stringToSign, signature := signV4(exampleSecretKey, "us-east-1", "s3", exampleDate, canonical)
+			if scope != "20130524/us-east-1/s3/aws4_request" {
+				t.Errorf("scope = %q", scope)
+			}
+			if stringToSign != tt.stringToSign {
+				t.Errorf("string to sign =\n%s\nwant\n%s", stringToSign, tt.stringToSign)
+			}
+			if signature != tt.signature {
+				t.Errorf("signature = %s, want %s", signature, tt.signature)
+			}
+		})
+	}
+}
+
+func TestURIEncode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"/bucket/products/1/a.jpg", "/bucket/products/1/a.jpg"},
+		{"/bucket/a b+c", "/bucket/a%20b%2Bc"},
+		{"/bucket/~x_y-z.", "/bucket/~x_y-z."},
+		{"/bucket/é", "/bucket/%C3%A9"},
+	}
+	for _, tt := range tests {
+		if got := uriEncode(tt.in); got != tt.want {
+			t.Errorf("uriEncode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeS3 answers every request with status and body, recording the last
+// request it got.
+type fakeS3 struct {
+	status int
+	body   string
+	last   *http.Request
+	data   []byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.last = r
+	f.data, _ = io.ReadAll(r.Body)
+	w.WriteHeader(f.status)
+	io.WriteString(w, f.body)
+}
+
+func newFakeS3(t *testing.T, f *fakeS3) *S3Store {
+	t.Helper()
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	s, err := NewS3Store(S3Config{Endpoint: srv.URL, Bucket: "bucket", AccessKey: "key", SecretKey: "secret", PublicURL: "/media"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestS3StorePut(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusOK, false},
+		{http.StatusForbidden, true},
+		{http.StatusNotFound, true},
+		{http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		f := &fakeS3{status: tt.status, body: "<Error/>"}
+		s := newFakeS3(t, f)
+
+		err := s.Put(context.Background(), "products/1/a b.txt", "text/plain", []byte("hello"))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Put with status %d: err = %v, want error %v", tt.status, err, tt.wantErr)
+		}
+		if f.last.Method != http.MethodPut || f.last.URL.EscapedPath() != "/bucket/products/1/a%20b.txt" {
+			t.Errorf("Put sent %s %s", f.last.Method, f.last.URL.EscapedPath())
+		}
+		if string(f.data) != "hello" || f.last.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("Put sent body %q with content type %q", f.data, f.last.Header.Get("Content-Type"))
+		}
+		if auth := f.last.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=key/") ||
+			!strings.Contains(auth, "SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date,") {
+			t.Errorf("Put sent Authorization %q", auth)
+		}
+		if f.last.Header.Get("X-Amz-Content-Sha256") != sha256Hex([]byte("hello")) {
+			t.Errorf("Put sent payload hash %q", f.last.Header.Get("X-Amz-Content-Sha256"))
+		}
+	}
+}
+
+func TestS3StoreOpen(t *testing.T) {
+	tests := []struct {
+		status   int
+		want     string
+		wantErr  error
+		anyError bool
+	}{
+		{status: http.StatusOK, want: "content"},
+		{status: http.StatusNotFound, wantErr: ErrNotExist},
+		{status: http.StatusForbidden, anyError: true},
+		{status: http.StatusInternalServerError, anyError: true},
+	}
+	for _, tt := range tests {
+		s := newFakeS3(t, &fakeS3{status: tt.status, body: "content"})
+
+		rc, err := s.Open(context.Background(), "products/1/a.txt")
+		switch {
+		case tt.wantErr != nil:
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Open with status %d: err = %v, want %v", tt.status, err, tt.wantErr)
+			}
+		case tt.anyError:
+			if err == nil || errors.Is(err, ErrNotExist) {
+				t.Errorf("Open with status %d: err = %v, want a failure", tt.status, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("Open with status %d: %v", tt.status, err)
+			}
+			data, _ := io.ReadAll(rc)
+			rc.Close()
+			if string(data) != tt.want {
+				t.Errorf("Open read %q, want %q", data, tt.want)
+			}
+		}
+	}
+}
+
+func TestS3StoreDelete(t *testing.T) {
+	tests := []struct {
+		status  int
+		wantErr bool
+	}{
+		{http.StatusNoContent, false},
+		{http.StatusOK, false},
+		{http.StatusForbidden, true},
+		{http.StatusInternalServerError, true},
+	}
+	for _, tt := range tests {
+		f := &fakeS3{status: tt.status}
+		s := newFakeS3(t, f)
+
+		err := s.Delete(context.Background(), "products/1/a.txt")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Delete with status %d: err = %v, want error %v", tt.status, err, tt.wantErr)
+		}
+		if f.last.Method != http.MethodDelete {
+			t.Errorf("Delete sent %s", f.last.Method)
+		}
+	}
+}
+
+func TestS3StoreRejectsInvalidKeys(t *testing.T) {
+	f := &fakeS3{status: http.StatusOK}
+	s := newFakeS3(t, f)
+
+	for _, key := range []string{"", "/abs", "a/../b", "a//b"} {
+		if _, err := s.Open(context.Background(), key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Open(%q) err = %v, want ErrInvalidKey", key, err)
+		}
+	}
+	if f.last != nil {
+		t.Errorf("invalid keys reached the store: %s", f.last.URL)
+	}
+}
+
+func TestS3StoreURL(t *testing.T) {
+	s := newFakeS3(t, &fakeS3{})
+	if got := s.URL("products/1/a.jpg"); got != "/media/products/1/a.jpg" {
+		t.Errorf("URL = %q", got)
+	}
+}
@@ -0,0 +1,45 @@
+package utils
+
+import "fmt"
+
+// ValidGTIN reports whether code is an EAN-8, UPC-A or EAN-13 number with a
+// correct check digit.
+func ValidGTIN(code string) bool {
+	switch len(code) {
+	case 8, 12, 13:
+	default:
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return gtinCheckDigit(code[:len(code)-1]) == int(code[len(code)-1]-'0')
+}
+
+// NormalizeGTIN pads a UPC-A code to its EAN-13 form so that the same item
+// is found whichever symbology the scanner reports.
+func NormalizeGTIN(code string) (string, error) {
+	if !ValidGTIN(code) {
+		return "", fmt.Errorf("invalid barcode %q", code)
+	}
+	if len(code) == 12 {
+		return "0" + code, nil
+	}
+	return code, nil
+}
+
+// gtinCheckDigit computes the mod-10 check digit for the payload digits,
+// weighting by 3 from the rightmost digit.
+func gtinCheckDigit(payload string) int {
+	sum := 0
+	weight := 3
+	for i := len(payload) - 1; i >= 0; i-- {
+		sum += int(payload[i]-'0') * weight
+		weight = 4 - weight
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"image"
+	"image/color"
+)
+
+// Thumbnail scales img down to fit within maxSize x maxSize, averaging the
+// source pixels covered by each output pixel. Smaller images are returned
+// unchanged.
+func Thumbnail(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return img
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = max(1, srcH*maxSize/srcW)
+	} else {
+		dstW = max(1, srcW*maxSize/srcH)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := b.Min.Y + y*srcH/dstH
+		y1 := max(y0+1, b.Min.Y+(y+1)*srcH/dstH)
+		for x := 0; x < dstW; x++ {
+			x0 := b.Min.X + x*srcW/dstW
+			x1 := max(x0+1, b.Min.X+(x+1)*srcW/dstW)
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.SetRGBA64(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -7,9 +7,12 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userID int64, secretKey string) (string, error) {
+// GenerateJWT issues a token for a user. The role is embedded, so a role
+// change takes effect at the next login.
+func GenerateJWT(userID int64, role, secretKey string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
+		"role":    role,
 		"exp":     time.Now().Add(24 * time.Hour).Unix(),
 	}
 
@@ -17,7 +20,9 @@ func GenerateJWT(userID int64, secretKey string) (string, error) {
 	return token.SignedString([]byte(secretKey))
 }
 
-func ParseJWT(tokenStr string, secretKey string) (int64, error) {
+// ParseJWT returns the user ID and role of a valid token. Tokens issued
+// before roles existed have an empty role.
+func ParseJWT(tokenStr string, secretKey string) (int64, string, error) {
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -27,18 +32,20 @@ func ParseJWT(tokenStr string, secretKey string) (int64, error) {
 	})
 
 	if err != nil || !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+		return 0, "", fmt.Errorf("invalid token")
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok || !token.Valid {
-		return 0, fmt.Errorf("invalid claims")
+		return 0, "", fmt.Errorf("invalid claims")
 	}
 
 	idFloat, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, fmt.Errorf("invalid user_id in token")
+		return 0, "", fmt.Errorf("invalid user_id in token")
 	}
 
-	return int64(idFloat), nil
+	role, _ := claims["role"].(string)
+
+	return int64(idFloat), role, nil
 }
@@ -0,0 +1,157 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrPatchNotObject is returned when a merge patch is not a JSON object.
+var ErrPatchNotObject = errors.New("merge patch must be a JSON object")
+
+// ApplyMergePatch applies an RFC 7396 JSON merge patch to the JSON form of
+// the struct dst points to and decodes the result back into it, so a null
+// resets a field to its zero value. It returns the names of the struct
+// fields the patch touched, ready for validator.StructPartial, and rejects
+// keys dst does not have.
+func ApplyMergePatch(dst any, patch []byte) ([]string, error) {
+	var patchDoc map[string]any
+	if err := decodeNumbers(patch, &patchDoc); err != nil || patchDoc == nil {
+		if err == nil || isNotObject(err) {
+			return nil, ErrPatchNotObject
+		}
+		return nil, err
+	}
+
+	fieldNames := jsonFieldNames(reflect.TypeOf(dst).Elem())
+	touched := make([]string, 0, len(patchDoc))
+	for key := range patchDoc {
+		name, ok := fieldNames[key]
+		if !ok {
+			return nil, fmt.Errorf("json: unknown field %q", key)
+		}
+		touched = append(touched, name)
+	}
+
+	current, err := json.Marshal(dst)
+	if err != nil {
+		return nil, err
+	}
+	var doc any
+	if err := decodeNumbers(current, &doc); err != nil {
+		return nil, err
+	}
+	merged, err := json.Marshal(mergePatch(doc, patchDoc))
+	if err != nil {
+		return nil, err
+	}
+
+	target := reflect.ValueOf(dst).Elem()
+	target.Set(reflect.Zero(target.Type()))
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return nil, err
+	}
+	return touched, nil
+}
+
+// TextMergePatch builds a merge patch for the struct dst points to from
+// values given as text, such as spreadsheet cells keyed by JSON field name.
+// String fields are quoted; other fields are passed through as numbers when
+// they look like one, so type errors surface when the patch is applied.
+func TextMergePatch(dst any, values map[string]string) ([]byte, error) {
+	t := reflect.TypeOf(dst).Elem()
+	fieldNames := jsonFieldNames(t)
+
+	patch := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		name, ok := fieldNames[key]
+		if !ok {
+			return nil, fmt.Errorf("json: unknown field %q", key)
+		}
+		field, _ := t.FieldByName(name)
+		if field.Type.Kind() != reflect.String && isJSONNumber(value) {
+			patch[key] = json.RawMessage(value)
+			continue
+		}
+		quoted, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		patch[key] = quoted
+	}
+	return json.Marshal(patch)
+}
+
+// JSONFieldNames lists, sorted, the JSON names of the fields of the struct
+// dst points to.
+func JSONFieldNames(dst any) []string {
+	fieldNames := jsonFieldNames(reflect.TypeOf(dst).Elem())
+	names := make([]string, 0, len(fieldNames))
+	for name := range fieldNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func isJSONNumber(s string) bool {
+	var n json.Number
+	return s != "" && json.Unmarshal([]byte(s), &n) == nil && strings.IndexAny(s, " \t\r\n\"") < 0
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396 section 2.
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// jsonFieldNames maps the JSON names of a struct's fields to their Go names.
+func jsonFieldNames(t reflect.Type) map[string]string {
+	names := make(map[string]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch tag {
+		case "-":
+			continue
+		case "":
+			tag = field.Name
+		}
+		names[tag] = field.Name
+	}
+	return names
+}
+
+// decodeNumbers keeps numbers as json.Number so large integers such as
+// amounts in minor units survive the round trip exactly.
+func decodeNumbers(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+func isNotObject(err error) bool {
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &typeErr)
+}
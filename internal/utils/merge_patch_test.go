@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestMergePatchRFC7396 runs the examples of RFC 7396 Appendix A.
+func TestMergePatchRFC7396(t *testing.T) {
+	tests := []struct{ target, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `"bar"`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch, want any
+		for _, doc := range []struct {
+			src string
+			dst *any
+		}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+			if err := decodeNumbers([]byte(doc.src), doc.dst); err != nil {
+				t.Fatalf("decode %s: %v", doc.src, err)
+			}
+		}
+
+		got := mergePatch(target, patch)
+		if !reflect.DeepEqual(got, want) {
+			gotJSON, _ := json.Marshal(got)
+			t.Errorf("merge %s into %s = %s, want %s", tt.patch, tt.target, gotJSON, tt.want)
+		}
+	}
+}
+
+type patchTarget struct {
+	Name     string  `json:"name"`
+	Note     *string `json:"note,omitempty"`
+	Amount   int64   `json:"amount"`
+	Internal string  `json:"-"`
+	Plain    bool
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	note := "keep"
+	tests := []struct {
+		name        string
+		patch       string
+		want        patchTarget
+		wantTouched []string
+		wantErr     error
+		anyError    bool
+	}{
+		{
+			name:        "changes only the given fields",
+			patch:       `{"name":"new"}`,
+			want:        patchTarget{Name: "new", Note: &note, Amount: 9007199254740993},
+			wantTouched: []string{"Name"},
+		},
+		{
+			name:        "null resets a field",
+			patch:       `{"note":null,"amount":5}`,
+			want:        patchTarget{Name: "old", Amount: 5},
+			wantTouched: []string{"Amount", "Note"},
+		},
+		{
+			name:        "fields without a tag go by their Go name",
+			patch:       `{"Plain":true}`,
+			want:        patchTarget{Name: "old", Note: &note, Amount: 9007199254740993, Plain: true},
+			wantTouched: []string{"Plain"},
+		},
+		{
+			name:        "an empty patch touches nothing",
+			patch:       `{}`,
+			want:        patchTarget{Name: "old", Note: &note, Amount: 9007199254740993},
+			wantTouched: []string{},
+		},
+		{name: "unknown keys are rejected", patch: `{"name":"x","colour":"red"}`, anyError: true},
+		{name: "fields hidden from JSON are unknown", patch: `{"Internal":"x"}`, anyError: true},
+		{name: "arrays are not objects", patch: `["name"]`, wantErr: ErrPatchNotObject},
+		{name: "null is not an object", patch: `null`, wantErr: ErrPatchNotObject},
+		{name: "strings are not objects", patch: `"name"`, wantErr: ErrPatchNotObject},
+		{name: "type errors surface", patch: `{"amount":"lots"}`, anyError: true},
+		{name: "malformed JSON", patch: `{"name":`, anyError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dst := patchTarget{Name: "old", Note: &note, Amount: 9007199254740993, Internal: "secret"}
+			touched, err := ApplyMergePatch(&dst, []byte(tt.patch))
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			case tt.anyError:
+				if err == nil {
+					t.Fatalf("got %+v, want an error", dst)
+				}
+				return
+			case err != nil:
+				t.Fatal(err)
+			}
+
+			sort.Strings(touched)
+			if !reflect.DeepEqual(touched, tt.wantTouched) {
+				t.Errorf("touched = %v, want %v", touched, tt.wantTouched)
+			}
+			if !reflect.DeepEqual(dst, tt.want) {
+				t.Errorf("result = %+v, want %+v", dst, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextMergePatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "strings are quoted even when numeric",
+			values: map[string]string{"name": "123"},
+			want:   `{"name":"123"}`,
+		},
+		{
+			name:   "numbers pass through for other fields",
+			values: map[string]string{"amount": "42"},
+			want:   `{"amount":42}`,
+		},
+		{
+			name:   "non-numbers are quoted so type errors surface",
+			values: map[string]string{"amount": "4 2"},
+			want:   `{"amount":"4 2"}`,
+		},
+		{
+			name:    "unknown keys are rejected",
+			values:  map[string]string{"colour": "red"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := TextMergePatch(&patchTarget{}, tt.values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("got %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("patch = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFieldNames(t *testing.T) {
+	want := []string{"Plain", "amount", "name", "note"}
+	if got := JSONFieldNames(&patchTarget{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("JSONFieldNames = %v, want %v", got, want)
+	}
+}
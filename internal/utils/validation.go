@@ -1,37 +1,132 @@
 package utils
 
 import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/decimal"
 )
 
+func NewValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name := strings.SplitN(f.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	// Decimals are validated by their value in thousandths, so gt=0 and
+	// gte=0 compare against zero as expected.
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(decimal.Decimal).Milli()
+	}, decimal.Decimal{})
+	_ = v.RegisterValidation("gtin", func(fl validator.FieldLevel) bool {
+		return ValidGTIN(fl.Field().String())
+	})
+	return v
+}
+
 func FormatValidationErrors(err error) map[string]string {
 	errors := make(map[string]string)
 
 	if validationErrors, ok := err.(validator.ValidationErrors); ok {
 		for _, fieldErr := range validationErrors {
-			fieldName := fieldErr.Field()
-			var message string
-
-			switch fieldErr.Tag() {
-			case "required":
-				message = fieldName + " is required"
-			case "min":
-				message = fieldName + " must be at least " + fieldErr.Param() + " characters"
-			case "max":
-				message = fieldName + " must be at most " + fieldErr.Param() + " characters"
-			case "email":
-				message = fieldName + " must be a valid email address"
-			case "len":
-				message = fieldName + " must be exactly " + fieldErr.Param() + " characters"
-			case "numeric":
-				message = fieldName + " must be a numeric value"
-			default:
-				message = fieldName + " is invalid"
-			}
+			errors["reason"] = validationMessage(fieldErr)
+		}
+	}
+
+	return errors
+}
 
-			errors["reason"] = message
+// FormatFieldErrors keys each validation failure by the offending field so
+// clients can point users at the exact input that was rejected.
+func FormatFieldErrors(err error) map[string]string {
+	errors := make(map[string]string)
+
+	if validationErrors, ok := err.(validator.ValidationErrors); ok {
+		for _, fieldErr := range validationErrors {
+			key := fieldErr.Namespace()
+			if idx := strings.Index(key, "."); idx >= 0 {
+				key = key[idx+1:]
+			}
+			errors[key] = validationMessage(fieldErr)
 		}
 	}
 
 	return errors
 }
+
+// FormatDecodeError turns a JSON decoding failure into field-level errors
+// when the body is well-formed but a value has the wrong type.
+func FormatDecodeError(err error) map[string]string {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return nil
+	}
+
+	// encoding/json does not attach the field name to errors returned by
+	// custom unmarshalers, so those are reported against the value.
+	field := typeErr.Field
+	if field == "" {
+		field = "reason"
+		typeErr.Field = strings.TrimPrefix(typeErr.Value, "number ")
+	}
+
+	expected := "of type " + typeErr.Type.String()
+	switch {
+	case typeErr.Type == reflect.TypeOf(decimal.Decimal{}):
+		expected = "a number with at most " + strconv.Itoa(decimal.Scale) + " decimal places"
+	case typeErr.Type.Kind() >= reflect.Int && typeErr.Type.Kind() <= reflect.Float64:
+		expected = "a number"
+	case typeErr.Type.Kind() == reflect.String:
+		expected = "a string"
+	}
+	return map[string]string{field: typeErr.Field + " must be " + expected}
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	fieldName := fieldErr.Field()
+
+	switch fieldErr.Tag() {
+	case "required":
+		return fieldName + " is required"
+	case "min":
+		return fieldName + " must be at least " + fieldErr.Param() + " characters"
+	case "max":
+		return fieldName + " must be at most " + fieldErr.Param() + " characters"
+	case "email":
+		return fieldName + " must be a valid email address"
+	case "len":
+		return fieldName + " must be exactly " + fieldErr.Param() + " characters"
+	case "numeric":
+		return fieldName + " must be a numeric value"
+	case "gt":
+		return fieldName + " must be greater than " + fieldErr.Param()
+	case "lte":
+		return fieldName + " must be less than or equal to " + fieldErr.Param()
+	case "gte":
+		return fieldName + " must be greater than or equal to " + fieldErr.Param()
+	case "url":
+		return fieldName + " must be a valid URL"
+	case "gtin":
+		return fieldName + " must be a valid EAN-8, UPC-A or EAN-13 code"
+	case "required_without":
+		return fieldName + " is required when " + strings.ToLower(fieldErr.Param()) + " is not given"
+	case "required_without_all":
+		return fieldName + " is required when none of " + strings.ToLower(fieldErr.Param()) + " are given"
+	case "oneof":
+		return fieldName + " must be one of: " + fieldErr.Param()
+	case "iso4217":
+		return fieldName + " must be an upper-case ISO-4217 currency code"
+	default:
+		return fieldName + " is invalid"
+	}
+}
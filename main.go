@@ -10,8 +10,10 @@ import (
 	"github.com/yudistirarivaldi/technical-test-deeptech/config"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/handler"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/middleware"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/model"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/repository"
 	"github.com/yudistirarivaldi/technical-test-deeptech/internal/service"
+	"github.com/yudistirarivaldi/technical-test-deeptech/internal/storage"
 )
 
 type databaseConnections struct {
@@ -19,11 +21,22 @@ type databaseConnections struct {
 }
 
 type appServices struct {
-	authService       *service.AuthService
-	userService       *service.UserService
-	categoriesService *service.CategoriesService
-	productService    *service.ProductService
-	transactionSerice *service.TransactionService
+	blobStore             storage.BlobStore
+	authService           *service.AuthService
+	userService           *service.UserService
+	categoriesService     *service.CategoriesService
+	productService        *service.ProductService
+	transactionSerice     *service.TransactionService
+	labelService          *service.LabelService
+	productImageService   *service.ProductImageService
+	productVariantService *service.ProductVariantService
+	productUnitService    *service.ProductUnitService
+	productPriceService   *service.ProductPriceService
+	valuationService      *service.ValuationService
+	productImportService  *service.ProductImportService
+	attachmentService     *service.TransactionAttachmentService
+	warehouseService      *service.WarehouseService
+	binService            *service.BinService
 }
 
 func main() {
@@ -38,7 +51,12 @@ func main() {
 	}
 	defer closeDatabases(dbs)
 
-	services := initServices(dbs, cfg)
+	blobStore, err := initBlobStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize storage: %v", err)
+	}
+
+	services := initServices(dbs, cfg, blobStore)
 	startHTTPServer(cfg, services)
 }
 
@@ -61,25 +79,81 @@ func closeDatabases(dbs *databaseConnections) {
 	}
 }
 
-func initServices(dbs *databaseConnections, cfg *config.Config) *appServices {
+func initBlobStore(cfg *config.Config) (storage.BlobStore, error) {
+	// Blobs are served by the app at /media whatever the driver, so access
+	// to them can be checked.
+	publicURL := cfg.Storage.PublicURL
+	if publicURL == "" {
+		publicURL = "/media"
+	}
+
+	switch cfg.Storage.Driver {
+	case "s3":
+		return storage.NewS3Store(storage.S3Config{
+			Endpoint:  cfg.Storage.S3.Endpoint,
+			Region:    cfg.Storage.S3.Region,
+			Bucket:    cfg.Storage.S3.Bucket,
+			AccessKey: cfg.Storage.S3.AccessKey,
+			SecretKey: cfg.Storage.S3.SecretKey,
+			PublicURL: publicURL,
+		})
+	case "local":
+		return storage.NewLocalStore(cfg.Storage.LocalDir, publicURL)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", cfg.Storage.Driver)
+	}
+}
+
+func initServices(dbs *databaseConnections, cfg *config.Config, blobStore storage.BlobStore) *appServices {
 	authRepo := repository.NewAuthRepository(dbs.mysql)
 	userRepo := repository.NewUserRepository(dbs.mysql)
 	categoriesRepo := repository.NewCategoriesRepository(dbs.mysql)
 	productRepo := repository.NewProductRepository(dbs.mysql)
 	transactionRepo := repository.NewTransactionRepository(dbs.mysql)
+	productImageRepo := repository.NewProductImageRepository(dbs.mysql)
+	productVariantRepo := repository.NewProductVariantRepository(dbs.mysql)
+	productUnitRepo := repository.NewProductUnitRepository(dbs.mysql)
+	productPriceRepo := repository.NewProductPriceRepository(dbs.mysql)
+	valuationRepo := repository.NewValuationRepository(dbs.mysql)
+	productHistoryRepo := repository.NewProductHistoryRepository(dbs.mysql)
+	idempotencyRepo := repository.NewIdempotencyRepository(dbs.mysql)
+	attachmentRepo := repository.NewTransactionAttachmentRepository(dbs.mysql)
+	warehouseRepo := repository.NewWarehouseRepository(dbs.mysql)
+	binRepo := repository.NewBinRepository(dbs.mysql)
 
 	authService := service.NewAuthService(authRepo, cfg.JWT.Secret)
 	userService := service.NewUserService(userRepo)
 	categoriesService := service.NewCategoriesService(categoriesRepo)
-	productService := service.NewProductService(productRepo)
-	transactionService := service.NewTransactionService(transactionRepo)
+	productService := service.NewProductService(productRepo, categoriesRepo, productVariantRepo, productHistoryRepo)
+	valuationService := service.NewValuationService(valuationRepo, cfg.Inventory.ValuationMethod)
+	transactionService := service.NewTransactionService(transactionRepo, valuationService, idempotencyRepo, cfg.Inventory.AdjustApprovalThreshold)
+	labelService := service.NewLabelService(productRepo)
+	productImageService := service.NewProductImageService(productRepo, productImageRepo, blobStore)
+	productVariantService := service.NewProductVariantService(productRepo, productVariantRepo)
+	productUnitService := service.NewProductUnitService(productRepo, productUnitRepo)
+	productPriceService := service.NewProductPriceService(productRepo, productPriceRepo)
+	productImportService := service.NewProductImportService(blobStore)
+	attachmentService := service.NewTransactionAttachmentService(transactionRepo, attachmentRepo, blobStore)
+	warehouseService := service.NewWarehouseService(warehouseRepo)
+	binService := service.NewBinService(binRepo, warehouseRepo)
 
 	return &appServices{
-		authService:       authService,
-		categoriesService: categoriesService,
-		productService:    productService,
-		transactionSerice: transactionService,
-		userService:       userService,
+		authService:           authService,
+		categoriesService:     categoriesService,
+		productService:        productService,
+		transactionSerice:     transactionService,
+		userService:           userService,
+		labelService:          labelService,
+		productImageService:   productImageService,
+		productVariantService: productVariantService,
+		productUnitService:    productUnitService,
+		productPriceService:   productPriceService,
+		productImportService:  productImportService,
+		attachmentService:     attachmentService,
+		warehouseService:      warehouseService,
+		binService:            binService,
+		valuationService:      valuationService,
+		blobStore:             blobStore,
 	}
 }
 
@@ -89,8 +163,17 @@ func startHTTPServer(cfg *config.Config, services *appServices) {
 	authHandler := handler.NewAuthHandler(services.authService)
 	categoriesHandler := handler.NewCategoriesHandler(services.categoriesService)
 	productHandler := handler.NewProductHandler(services.productService)
-	transactionHandler := handler.NewTransactionHandler(services.transactionSerice)
+	transactionHandler := handler.NewTransactionHandler(services.transactionSerice, services.attachmentService)
 	userHandler := handler.NewUserHandler(services.userService)
+	labelHandler := handler.NewLabelHandler(services.labelService)
+	productImageHandler := handler.NewProductImageHandler(services.productImageService)
+	productVariantHandler := handler.NewProductVariantHandler(services.productVariantService)
+	productUnitHandler := handler.NewProductUnitHandler(services.productUnitService)
+	productPriceHandler := handler.NewProductPriceHandler(services.productPriceService)
+	reportHandler := handler.NewReportHandler(services.valuationService)
+	productImportHandler := handler.NewProductImportHandler(services.productService, services.productImportService)
+	warehouseHandler := handler.NewWarehouseHandler(services.warehouseService)
+	binHandler := handler.NewBinHandler(services.binService)
 
 	r.HandleFunc("/api/auth/register", authHandler.HandleRegister).Methods("POST")
 	r.HandleFunc("/api/auth/login", authHandler.HandleLogin).Methods("POST")
@@ -99,19 +182,70 @@ func startHTTPServer(cfg *config.Config, services *appServices) {
 	r.HandleFunc("/api/categories", middleware.JWTMiddleware(cfg.JWT.Secret, categoriesHandler.HandleGetAll)).Methods("GET")
 	r.HandleFunc("/api/categories/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, categoriesHandler.HandleGetByID)).Methods("GET")
 	r.HandleFunc("/api/categories/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, categoriesHandler.HandleUpdate)).Methods("PUT")
+	r.HandleFunc("/api/categories/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, categoriesHandler.HandlePatch)).Methods("PATCH")
 	r.HandleFunc("/api/categories/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, categoriesHandler.HandleDelete)).Methods("DELETE")
 
 	r.HandleFunc("/api/products", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleInsert)).Methods("POST")
 	r.HandleFunc("/api/products", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleGetAll)).Methods("GET")
+	r.HandleFunc("/api/products/export", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleExport)).Methods("GET")
+	r.HandleFunc("/api/products/import", middleware.JWTMiddleware(cfg.JWT.Secret, productImportHandler.HandleImport)).Methods("POST")
+	r.HandleFunc("/api/products/labels", middleware.JWTMiddleware(cfg.JWT.Secret, labelHandler.HandleLabelSheet)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/label", middleware.JWTMiddleware(cfg.JWT.Secret, labelHandler.HandleProductLabel)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/images", middleware.JWTMiddleware(cfg.JWT.Secret, productImageHandler.HandleUpload)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/images", middleware.JWTMiddleware(cfg.JWT.Secret, productImageHandler.HandleList)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/images/order", middleware.JWTMiddleware(cfg.JWT.Secret, productImageHandler.HandleReorder)).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/images/{imageId}", middleware.JWTMiddleware(cfg.JWT.Secret, productImageHandler.HandleDelete)).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/variants", middleware.JWTMiddleware(cfg.JWT.Secret, productVariantHandler.HandleCreate)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/variants", middleware.JWTMiddleware(cfg.JWT.Secret, productVariantHandler.HandleList)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/variants/{variantId}", middleware.JWTMiddleware(cfg.JWT.Secret, productVariantHandler.HandleUpdate)).Methods("PUT")
+	r.HandleFunc("/api/products/{id}/variants/{variantId}", middleware.JWTMiddleware(cfg.JWT.Secret, productVariantHandler.HandleDelete)).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/units", middleware.JWTMiddleware(cfg.JWT.Secret, productUnitHandler.HandleCreate)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/units", middleware.JWTMiddleware(cfg.JWT.Secret, productUnitHandler.HandleList)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/units/{unitId}", middleware.JWTMiddleware(cfg.JWT.Secret, productUnitHandler.HandleDelete)).Methods("DELETE")
+	r.HandleFunc("/api/products/{id}/prices", middleware.JWTMiddleware(cfg.JWT.Secret, productPriceHandler.HandleCreate)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/prices", middleware.JWTMiddleware(cfg.JWT.Secret, productPriceHandler.HandleList)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/history", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleHistory)).Methods("GET")
+	r.HandleFunc("/api/products/{id}/unarchive", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleUnarchive)).Methods("POST")
+	r.HandleFunc("/api/products/{id}/stock", middleware.JWTMiddleware(cfg.JWT.Secret, productUnitHandler.HandleStock)).Methods("GET")
+	r.HandleFunc("/api/products/by-barcode/{code}", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleGetByBarcode)).Methods("GET")
 	r.HandleFunc("/api/products/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleGetByID)).Methods("GET")
 	r.HandleFunc("/api/products/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleUpdate)).Methods("PUT")
+	r.HandleFunc("/api/products/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandlePatch)).Methods("PATCH")
 	r.HandleFunc("/api/products/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, productHandler.HandleDelete)).Methods("DELETE")
 
+	r.Handle("/api/warehouses", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(warehouseHandler.HandleCreate, model.RoleManager, model.RoleAdmin))).Methods("POST")
+	r.Handle("/api/warehouses", middleware.JWTMiddleware(cfg.JWT.Secret, warehouseHandler.HandleGetAll)).Methods("GET")
+	r.Handle("/api/warehouses/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, warehouseHandler.HandleGetByID)).Methods("GET")
+	r.Handle("/api/warehouses/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(warehouseHandler.HandleUpdate, model.RoleManager, model.RoleAdmin))).Methods("PUT")
+	r.Handle("/api/warehouses/{id}/bins", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(binHandler.HandleCreate, model.RoleManager, model.RoleAdmin))).Methods("POST")
+	r.Handle("/api/warehouses/{id}/bins", middleware.JWTMiddleware(cfg.JWT.Secret, binHandler.HandleGetAll)).Methods("GET")
+	r.Handle("/api/warehouses/{id}/bins/{binId}", middleware.JWTMiddleware(cfg.JWT.Secret, binHandler.HandleGetByID)).Methods("GET")
+	r.Handle("/api/warehouses/{id}/pick-list", middleware.JWTMiddleware(cfg.JWT.Secret, binHandler.HandlePickList)).Methods("GET")
+
 	r.Handle("/api/transactions", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleCreate)).Methods("POST")
+	r.Handle("/api/transactions", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(transactionHandler.HandleList, model.RoleManager, model.RoleAdmin))).Methods("GET")
 	r.Handle("/api/transactions/history", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleGetUserTransactions)).Methods("GET")
+	r.Handle("/api/transactions/{id}", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleGetByID)).Methods("GET")
+	r.Handle("/api/transactions/{id}/reverse", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleReverse)).Methods("POST")
+	r.Handle("/api/transactions/{id}/receive", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleReceive)).Methods("POST")
+	r.Handle("/api/transactions/{id}/attachments", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleUploadAttachments)).Methods("POST")
+	r.Handle("/api/transactions/{id}/attachments", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleListAttachments)).Methods("GET")
+	r.Handle("/api/transactions/{id}/attachments/{attachmentId}", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.HandleDeleteAttachment)).Methods("DELETE")
+	r.Handle("/api/transactions/{id}/approve", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(transactionHandler.HandleApprove, model.RoleManager, model.RoleAdmin))).Methods("POST")
+	r.Handle("/api/transactions/{id}/reject", middleware.JWTMiddleware(cfg.JWT.Secret, middleware.RequireRole(transactionHandler.HandleReject, model.RoleManager, model.RoleAdmin))).Methods("POST")
+
+	r.HandleFunc("/api/reports/valuation", middleware.JWTMiddleware(cfg.JWT.Secret, reportHandler.HandleValuation)).Methods("GET")
 
 	r.Handle("/api/users", middleware.JWTMiddleware(cfg.JWT.Secret, userHandler.HandleGetProfile)).Methods("GET")
 	r.Handle("/api/users", middleware.JWTMiddleware(cfg.JWT.Secret, userHandler.HandleUpdateUser)).Methods("PUT")
+	r.Handle("/api/users", middleware.JWTMiddleware(cfg.JWT.Secret, userHandler.HandlePatchUser)).Methods("PATCH")
+
+	// Product images are public; transaction attachments and import error
+	// reports need a signed-in caller.
+	files := handler.NewMediaHandler(services.blobStore)
+	r.PathPrefix("/media/products/").Handler(files).Methods("GET")
+	r.Handle("/media/transactions/{id}/{file}", middleware.JWTMiddleware(cfg.JWT.Secret, transactionHandler.ServeAttachmentFile(files))).Methods("GET")
+	r.Handle("/media/imports/{name}/{file}", middleware.JWTMiddleware(cfg.JWT.Secret, files.ServeHTTP)).Methods("GET")
 
 	log.Printf("Server starting on port %s...", cfg.Server.Port)
 	err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.Server.Port), r)